@@ -0,0 +1,43 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/cschleiden/go-workflows/converter"
+)
+
+// ErrNotFound is returned by readers if there is no archive for the requested instance
+var ErrNotFound = errors.New("archive not found")
+
+// Archive is the full history of a completed workflow instance
+type Archive struct {
+	InstanceID       string    `json:"instance_id"`
+	ExecutionID      string    `json:"execution_id"`
+	ParentInstanceID string    `json:"parent_instance_id,omitempty"`
+	CompletedAt      time.Time `json:"completed_at"`
+
+	// Result is the serialized result of the workflow, if it completed successfully
+	Result converter.EncodedPayload `json:"result,omitempty"`
+
+	// Error is the error message if the workflow failed
+	Error string `json:"error,omitempty"`
+
+	// History contains the events of the instance in the same JSON form as the events of an
+	// exported history. Use export.FromArchive to read them.
+	History json.RawMessage `json:"history"`
+}
+
+// Sink stores archives of completed workflow instances. Backends call Store before the history
+// of an instance is removed, implementations need to handle the same instance being stored more
+// than once.
+type Sink interface {
+	Store(ctx context.Context, archive *Archive) error
+}
+
+// Reader loads archived workflow instances
+type Reader interface {
+	Load(ctx context.Context, instanceID, executionID string) (*Archive, error)
+}
@@ -0,0 +1,87 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// FileSystem stores archives as JSON files in a local directory, one file per execution in
+// <dir>/<instance id>/<execution id>.json
+type FileSystem struct {
+	dir string
+}
+
+var _ Sink = (*FileSystem)(nil)
+var _ Reader = (*FileSystem)(nil)
+
+// NewFileSystem creates a sink and reader for archives in dir. The directory is created if it
+// doesn't exist.
+func NewFileSystem(dir string) (*FileSystem, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "could not create archive directory")
+	}
+
+	return &FileSystem{dir: dir}, nil
+}
+
+func (fs *FileSystem) Store(ctx context.Context, archive *Archive) error {
+	data, err := json.Marshal(archive)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal archive")
+	}
+
+	dir := filepath.Join(fs.dir, url.PathEscape(archive.InstanceID))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return errors.Wrap(err, "could not create archive directory")
+	}
+
+	// Write to a temporary file first, so that readers never see partial archives
+	f, err := ioutil.TempFile(dir, ".archive-*")
+	if err != nil {
+		return errors.Wrap(err, "could not create archive file")
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return errors.Wrap(err, "could not write archive")
+	}
+
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "could not write archive")
+	}
+
+	if err := os.Rename(f.Name(), fs.path(archive.InstanceID, archive.ExecutionID)); err != nil {
+		return errors.Wrap(err, "could not write archive")
+	}
+
+	return nil
+}
+
+func (fs *FileSystem) Load(ctx context.Context, instanceID, executionID string) (*Archive, error) {
+	data, err := ioutil.ReadFile(fs.path(instanceID, executionID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+
+		return nil, errors.Wrap(err, "could not read archive")
+	}
+
+	var a Archive
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal archive")
+	}
+
+	return &a, nil
+}
+
+func (fs *FileSystem) path(instanceID, executionID string) string {
+	return filepath.Join(fs.dir, url.PathEscape(instanceID), url.PathEscape(executionID)+".json")
+}
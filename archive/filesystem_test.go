@@ -0,0 +1,44 @@
+package archive
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/converter"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FileSystem_StoreAndLoad(t *testing.T) {
+	ctx := context.Background()
+
+	fs, err := NewFileSystem(t.TempDir())
+	require.NoError(t, err)
+
+	a := &Archive{
+		InstanceID:  "orders/1",
+		ExecutionID: "exec",
+		CompletedAt: time.Now(),
+		Result:      converter.EncodedPayload{Data: []byte(`"done"`)},
+		History:     []byte(`[{"id":"1"}]`),
+	}
+
+	require.NoError(t, fs.Store(ctx, a))
+
+	// Storing the same instance again overwrites the archive
+	require.NoError(t, fs.Store(ctx, a))
+
+	loaded, err := fs.Load(ctx, "orders/1", "exec")
+	require.NoError(t, err)
+	require.Equal(t, "orders/1", loaded.InstanceID)
+	require.Equal(t, `"done"`, string(loaded.Result.Data))
+	require.JSONEq(t, `[{"id":"1"}]`, string(loaded.History))
+}
+
+func Test_FileSystem_Load_NotFound(t *testing.T) {
+	fs, err := NewFileSystem(t.TempDir())
+	require.NoError(t, err)
+
+	_, err = fs.Load(context.Background(), "missing", "exec")
+	require.ErrorIs(t, err, ErrNotFound)
+}
@@ -2,8 +2,10 @@ package backend
 
 import (
 	"context"
+	"time"
 
 	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/schedule"
 	"github.com/cschleiden/go-workflows/internal/task"
 	"github.com/cschleiden/go-workflows/workflow"
 )
@@ -19,6 +21,23 @@ type Backend interface {
 	// SignalWorkflow signals a running workflow instance
 	SignalWorkflow(ctx context.Context, instanceID string, event history.Event) error
 
+	// RemoveWorkflowInstance removes a completed workflow instance including its history. Returns
+	// ErrInstanceNotFound if the instance does not exist, and ErrInstanceNotCompleted if it's still running.
+	RemoveWorkflowInstance(ctx context.Context, instance workflow.Instance) error
+
+	// GetWorkflowInstanceHistory returns the history of the given workflow instance. Events that
+	// haven't been processed by a workflow task yet are not included. Returns ErrInstanceNotFound
+	// if the instance does not exist.
+	GetWorkflowInstanceHistory(ctx context.Context, instance workflow.Instance) ([]history.Event, error)
+
+	// DescribeWorkflowInstance returns the state of the given workflow instance. Returns
+	// ErrInstanceNotFound if the instance does not exist.
+	DescribeWorkflowInstance(ctx context.Context, instance workflow.Instance) (*WorkflowInstanceInfo, error)
+
+	// ListWorkflowInstances returns up to limit workflow instances ordered by instance ID, starting
+	// after the given instance ID. Pass an empty afterInstanceID to start with the first instance.
+	ListWorkflowInstances(ctx context.Context, afterInstanceID string, limit int) ([]*WorkflowInstanceInfo, error)
+
 	// GetWorkflowInstance returns a pending workflow task or nil if there are no pending worflow executions
 	GetWorkflowTask(ctx context.Context) (*task.Workflow, error)
 
@@ -40,4 +59,28 @@ type Backend interface {
 
 	// ExtendActivityTask extends the lock of an activity task
 	ExtendActivityTask(ctx context.Context, activityID string) error
+
+	// CreateSchedule stores a new schedule and sets its version. Returns ErrScheduleAlreadyExists
+	// if a schedule with the same ID exists.
+	CreateSchedule(ctx context.Context, s *schedule.Schedule) error
+
+	// GetSchedule returns the schedule with the given ID. Returns ErrScheduleNotFound if the
+	// schedule does not exist.
+	GetSchedule(ctx context.Context, scheduleID string) (*schedule.Schedule, error)
+
+	// ListSchedules returns all schedules ordered by ID
+	ListSchedules(ctx context.Context) ([]*schedule.Schedule, error)
+
+	// GetDueSchedules returns up to limit schedules that are due at the given time
+	GetDueSchedules(ctx context.Context, now time.Time, limit int) ([]*schedule.Schedule, error)
+
+	// UpdateSchedule stores a schedule read before and increments its version. Returns
+	// ErrScheduleNotFound if the schedule does not exist, and ErrScheduleModified if it was
+	// updated since it was read.
+	UpdateSchedule(ctx context.Context, s *schedule.Schedule) error
+
+	// DeleteSchedule removes a schedule. Workflow instances already started are not affected.
+	// Backends deleting the blobs of removed instances also delete the blobs of the schedule's
+	// inputs. Returns ErrScheduleNotFound if the schedule does not exist.
+	DeleteSchedule(ctx context.Context, scheduleID string) error
 }
@@ -0,0 +1,18 @@
+package backend
+
+import "github.com/pkg/errors"
+
+// ErrInstanceNotFound is returned when a workflow instance does not exist
+var ErrInstanceNotFound = errors.New("workflow instance not found")
+
+// ErrInstanceNotCompleted is returned when trying to remove a workflow instance that is still running
+var ErrInstanceNotCompleted = errors.New("workflow instance is not completed")
+
+// ErrScheduleNotFound is returned when a schedule does not exist
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+// ErrScheduleAlreadyExists is returned when creating a schedule with the ID of an existing schedule
+var ErrScheduleAlreadyExists = errors.New("schedule already exists")
+
+// ErrScheduleModified is returned when updating a schedule that was modified concurrently since it was read
+var ErrScheduleModified = errors.New("schedule was modified concurrently")
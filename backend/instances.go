@@ -0,0 +1,87 @@
+package backend
+
+import (
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+// WorkflowInstanceState is the state of a workflow instance
+type WorkflowInstanceState int
+
+const (
+	// WorkflowInstanceStateActive instances have started, or are about to start, and haven't finished yet
+	WorkflowInstanceStateActive WorkflowInstanceState = iota
+
+	// WorkflowInstanceStateScheduled instances were created with a start time in the future
+	WorkflowInstanceStateScheduled
+
+	// WorkflowInstanceStateFinished instances have completed, failed, or were canceled
+	WorkflowInstanceStateFinished
+)
+
+func (s WorkflowInstanceState) String() string {
+	switch s {
+	case WorkflowInstanceStateActive:
+		return "active"
+	case WorkflowInstanceStateScheduled:
+		return "scheduled"
+	case WorkflowInstanceStateFinished:
+		return "finished"
+	}
+
+	return "unknown"
+}
+
+// WorkflowInstanceInfo describes a workflow instance
+type WorkflowInstanceInfo struct {
+	Instance workflow.Instance
+
+	State WorkflowInstanceState
+
+	CreatedAt time.Time
+
+	// StartAt is the time a scheduled instance starts. It's only set while the instance is scheduled.
+	StartAt *time.Time
+
+	CompletedAt *time.Time
+}
+
+// NewWorkflowInstanceInfo returns the description of an instance. startAt is when the instance's
+// WorkflowExecutionStarted event becomes visible, if it's still pending.
+func NewWorkflowInstanceInfo(instance workflow.Instance, createdAt time.Time, startAt, completedAt *time.Time, now time.Time) *WorkflowInstanceInfo {
+	info := &WorkflowInstanceInfo{
+		Instance:    instance,
+		State:       WorkflowInstanceStateActive,
+		CreatedAt:   createdAt,
+		CompletedAt: completedAt,
+	}
+
+	switch {
+	case completedAt != nil:
+		info.State = WorkflowInstanceStateFinished
+
+	case startAt != nil && startAt.After(now):
+		info.State = WorkflowInstanceStateScheduled
+		info.StartAt = startAt
+	}
+
+	return info
+}
+
+// DelayUntilStart delays the given events until startAt, the time the instance they are for is
+// scheduled to start. This makes sure events like signals are not processed before the instance
+// started.
+func DelayUntilStart(events []history.Event, startAt *time.Time) {
+	if startAt == nil {
+		return
+	}
+
+	for i := range events {
+		if events[i].VisibleAt == nil || events[i].VisibleAt.Before(*startAt) {
+			t := *startAt
+			events[i].VisibleAt = &t
+		}
+	}
+}
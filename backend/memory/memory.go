@@ -0,0 +1,634 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/pkg/errors"
+)
+
+// NewMemoryBackend returns a backend keeping all state in memory. It doesn't require cgo and is
+// intended for tests and samples. Workers have to run in the same process as the backend.
+//
+// GetWorkflowTask and GetActivityTask block until a task is available or the given context
+// is canceled.
+func NewMemoryBackend(opts ...backend.BackendOption) backend.Backend {
+	options := backend.ApplyOptions(opts...)
+
+	return &memoryBackend{
+		options:          options,
+		instances:        map[string]*workflowInstance{},
+		activities:       map[string]*activity{},
+		schedules:        map[string]*storedSchedule{},
+		workflowFairness: backend.NewPriorityFairness(options.PriorityFairness),
+		activityFairness: backend.NewPriorityFairness(options.PriorityFairness),
+		Notifications:    backend.NewNotifications(),
+	}
+}
+
+type workflowInstance struct {
+	instance core.WorkflowInstance
+
+	history       []history.Event
+	pendingEvents []history.Event
+
+	priority int
+
+	createdAt   time.Time
+	completedAt *time.Time
+	lockedUntil time.Time
+	stickyUntil time.Time
+	completed   bool
+}
+
+type activity struct {
+	id          string
+	instance    core.WorkflowInstance
+	event       history.Event
+	priority    int
+	lockedUntil time.Time
+}
+
+type memoryBackend struct {
+	options backend.Options
+
+	mu sync.Mutex
+
+	// instances are all workflow instances by instance ID, instanceIDs keeps them in the order
+	// they were created, so that tasks are returned in a fair order.
+	instances   map[string]*workflowInstance
+	instanceIDs []string
+
+	activities  map[string]*activity
+	activityIDs []string
+
+	schedules map[string]*storedSchedule
+
+	workflowFairness *backend.PriorityFairness
+	activityFairness *backend.PriorityFairness
+
+	*backend.Notifications
+}
+
+func (mb *memoryBackend) CreateWorkflowInstance(ctx context.Context, m history.WorkflowEvent) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if _, ok := mb.instances[m.WorkflowInstance.GetInstanceID()]; ok {
+		return errors.New("workflow instance already exists")
+	}
+
+	wfi := mb.createInstance(m.WorkflowInstance, backend.InstancePriority(m.HistoryEvent))
+	wfi.pendingEvents = append(wfi.pendingEvents, m.HistoryEvent)
+
+	mb.NotifyWorkflowTasks()
+
+	return nil
+}
+
+func (mb *memoryBackend) createInstance(instance core.WorkflowInstance, priority int) *workflowInstance {
+	if wfi, ok := mb.instances[instance.GetInstanceID()]; ok {
+		return wfi
+	}
+
+	wfi := &workflowInstance{
+		instance:  instance,
+		priority:  priority,
+		createdAt: time.Now(),
+	}
+
+	mb.instances[instance.GetInstanceID()] = wfi
+	mb.instanceIDs = append(mb.instanceIDs, instance.GetInstanceID())
+
+	return wfi
+}
+
+func (mb *memoryBackend) CancelWorkflowInstance(ctx context.Context, instance workflow.Instance) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	wfi, ok := mb.instances[instance.GetInstanceID()]
+	if !ok {
+		return errors.New("workflow instance does not exist")
+	}
+
+	mb.cancelInstance(wfi)
+
+	mb.NotifyWorkflowTasks()
+
+	return nil
+}
+
+// cancelInstance cancels the given instance and, recursively, all its running sub-workflow instances
+func (mb *memoryBackend) cancelInstance(wfi *workflowInstance) {
+	events := []history.Event{history.NewWorkflowCancellationEvent(time.Now())}
+	backend.DelayUntilStart(events, wfi.startAt(time.Now()))
+	wfi.pendingEvents = append(wfi.pendingEvents, events...)
+
+	for _, id := range mb.instanceIDs {
+		sub := mb.instances[id]
+		if !sub.completed && sub.instance.SubWorkflow() &&
+			sub.instance.ParentInstance().GetInstanceID() == wfi.instance.GetInstanceID() {
+			mb.cancelInstance(sub)
+		}
+	}
+}
+
+func (mb *memoryBackend) RemoveWorkflowInstance(ctx context.Context, instance workflow.Instance) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	instanceID := instance.GetInstanceID()
+
+	wfi, ok := mb.instances[instanceID]
+	if !ok || wfi.instance.GetExecutionID() != instance.GetExecutionID() {
+		return backend.ErrInstanceNotFound
+	}
+
+	if !wfi.completed {
+		return backend.ErrInstanceNotCompleted
+	}
+
+	delete(mb.instances, instanceID)
+
+	for i, id := range mb.instanceIDs {
+		if id == instanceID {
+			mb.instanceIDs = append(mb.instanceIDs[:i], mb.instanceIDs[i+1:]...)
+			break
+		}
+	}
+
+	for _, id := range append([]string{}, mb.activityIDs...) {
+		if mb.activities[id].instance.GetInstanceID() == instanceID {
+			mb.removeActivity(id)
+		}
+	}
+
+	return nil
+}
+
+func (mb *memoryBackend) GetWorkflowInstanceHistory(ctx context.Context, instance workflow.Instance) ([]history.Event, error) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	wfi, ok := mb.instances[instance.GetInstanceID()]
+	if !ok || wfi.instance.GetExecutionID() != instance.GetExecutionID() {
+		return nil, backend.ErrInstanceNotFound
+	}
+
+	return append([]history.Event{}, wfi.history...), nil
+}
+
+func (mb *memoryBackend) DescribeWorkflowInstance(ctx context.Context, instance workflow.Instance) (*backend.WorkflowInstanceInfo, error) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	wfi, ok := mb.instances[instance.GetInstanceID()]
+	if !ok || wfi.instance.GetExecutionID() != instance.GetExecutionID() {
+		return nil, backend.ErrInstanceNotFound
+	}
+
+	return wfi.info(time.Now()), nil
+}
+
+func (mb *memoryBackend) ListWorkflowInstances(ctx context.Context, afterInstanceID string, limit int) ([]*backend.WorkflowInstanceInfo, error) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	ids := make([]string, 0, len(mb.instanceIDs))
+	for _, id := range mb.instanceIDs {
+		if id > afterInstanceID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	now := time.Now()
+	infos := make([]*backend.WorkflowInstanceInfo, 0, len(ids))
+	for _, id := range ids {
+		infos = append(infos, mb.instances[id].info(now))
+	}
+
+	return infos, nil
+}
+
+func (wfi *workflowInstance) info(now time.Time) *backend.WorkflowInstanceInfo {
+	return backend.NewWorkflowInstanceInfo(wfi.instance, wfi.createdAt, wfi.startAt(now), wfi.completedAt, now)
+}
+
+// startAt returns when the instance is scheduled to start, or nil if it already started
+func (wfi *workflowInstance) startAt(now time.Time) *time.Time {
+	for _, e := range wfi.pendingEvents {
+		if e.Type == history.EventType_WorkflowExecutionStarted && e.VisibleAt != nil && e.VisibleAt.After(now) {
+			return e.VisibleAt
+		}
+	}
+
+	return nil
+}
+
+func (mb *memoryBackend) SignalWorkflow(ctx context.Context, instanceID string, event history.Event) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	wfi, ok := mb.instances[instanceID]
+	if !ok {
+		return errors.New("workflow instance does not exist")
+	}
+
+	events := []history.Event{event}
+	backend.DelayUntilStart(events, wfi.startAt(time.Now()))
+	wfi.pendingEvents = append(wfi.pendingEvents, events...)
+
+	mb.NotifyWorkflowTasks()
+
+	return nil
+}
+
+func (mb *memoryBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, error) {
+	for {
+		// Get the notification channel before looking for a task, so that no notification is missed
+		notification := mb.WorkflowTasksNotification()
+
+		t, next := mb.lockWorkflowTask()
+		if t != nil {
+			return t, nil
+		}
+
+		if !mb.wait(ctx, notification, next) {
+			return nil, nil
+		}
+	}
+}
+
+// lockWorkflowTask locks and returns the next workflow task. If there is no task, it returns the
+// time at which a task might become available, like when a timer fires or a lock expires.
+func (mb *memoryBackend) lockWorkflowTask() (*task.Workflow, time.Time) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	now := time.Now()
+	var next time.Time
+
+	// Find the available instance with the highest priority, or the one waiting the longest if
+	// this poll ignores priorities
+	honorPriority := mb.workflowFairness.HonorPriority()
+
+	var wfi *workflowInstance
+	var pendingEvents []history.Event
+	var wfiReadyAt time.Time
+
+	for _, id := range mb.instanceIDs {
+		candidate := mb.instances[id]
+		if candidate.completed {
+			continue
+		}
+
+		events, visibleAt := visibleEvents(candidate.pendingEvents, now)
+		if len(events) == 0 {
+			next = earliest(next, visibleAt)
+			continue
+		}
+
+		if candidate.lockedUntil.After(now) {
+			next = earliest(next, candidate.lockedUntil)
+			continue
+		}
+
+		readyAt := readyAt(events)
+		if wfi == nil ||
+			(honorPriority && candidate.priority > wfi.priority) ||
+			(!honorPriority && readyAt.Before(wfiReadyAt)) {
+			wfi, pendingEvents, wfiReadyAt = candidate, events, readyAt
+		}
+	}
+
+	if wfi == nil {
+		return nil, next
+	}
+
+	wfi.lockedUntil = now.Add(mb.options.WorkflowLockTimeout)
+
+	t := &task.Workflow{
+		WorkflowInstance: wfi.instance,
+		NewEvents:        pendingEvents,
+		History:          []history.Event{},
+	}
+
+	// Return a continuation task if the instance is still sticky to this worker
+	if wfi.stickyUntil.After(now) {
+		t.Kind = task.Continuation
+
+		if len(wfi.history) > 0 {
+			t.History = []history.Event{wfi.history[len(wfi.history)-1]}
+		}
+	} else {
+		t.History = append(t.History, wfi.history...)
+	}
+
+	mb.options.Logger.Debug("Locked workflow task",
+		log.InstanceIDKey, wfi.instance.GetInstanceID(),
+		log.ExecutionIDKey, wfi.instance.GetExecutionID(),
+		"new_events", len(t.NewEvents),
+		"continuation", t.Kind == task.Continuation,
+	)
+
+	return t, time.Time{}
+}
+
+func (mb *memoryBackend) CompleteWorkflowTask(
+	ctx context.Context,
+	instance workflow.Instance,
+	executedEvents []history.Event,
+	workflowEvents []history.WorkflowEvent,
+) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	wfi, ok := mb.instances[instance.GetInstanceID()]
+	if !ok || wfi.instance.GetExecutionID() != instance.GetExecutionID() {
+		return errors.New("could not find workflow instance")
+	}
+
+	now := time.Now()
+	if !wfi.lockedUntil.After(now) {
+		return errors.New("workflow instance is not locked")
+	}
+
+	// Unlock instance, but keep it sticky to the current worker
+	wfi.lockedUntil = time.Time{}
+	wfi.stickyUntil = now.Add(mb.options.StickyTimeout)
+
+	// Remove handled events
+	executed := make(map[string]bool, len(executedEvents))
+	for _, e := range executedEvents {
+		executed[e.ID] = true
+	}
+
+	pendingEvents := make([]history.Event, 0, len(wfi.pendingEvents))
+	for _, e := range wfi.pendingEvents {
+		if !executed[e.ID] {
+			pendingEvents = append(pendingEvents, e)
+		}
+	}
+	wfi.pendingEvents = pendingEvents
+
+	// Add events from last execution to history
+	wfi.history = append(wfi.history, executedEvents...)
+
+	for _, event := range executedEvents {
+		switch event.Type {
+		case history.EventType_ActivityScheduled:
+			mb.activities[event.ID] = &activity{
+				id:       event.ID,
+				instance: instance,
+				event:    event,
+				priority: backend.ActivityPriority(event),
+			}
+			mb.activityIDs = append(mb.activityIDs, event.ID)
+
+		case history.EventType_WorkflowExecutionFinished:
+			wfi.completed = true
+			wfi.completedAt = &now
+		}
+	}
+
+	// Insert new workflow events
+	for _, m := range workflowEvents {
+		target := mb.createInstance(m.WorkflowInstance, backend.InstancePriority(m.HistoryEvent))
+		target.pendingEvents = append(target.pendingEvents, m.HistoryEvent)
+	}
+
+	mb.options.Logger.Debug("Completed workflow task",
+		log.InstanceIDKey, instance.GetInstanceID(),
+		log.ExecutionIDKey, instance.GetExecutionID(),
+		"executed_events", len(executedEvents),
+		"workflow_events", len(workflowEvents),
+	)
+
+	mb.NotifyCompletedWorkflowTask(executedEvents, workflowEvents)
+	if len(workflowEvents) > 0 {
+		// Wake up waiting pollers even for events that only become visible in the future, like
+		// fired timers, so that they wait until the event becomes visible
+		mb.NotifyWorkflowTasks()
+	}
+
+	return nil
+}
+
+func (mb *memoryBackend) ExtendWorkflowTask(ctx context.Context, instance workflow.Instance) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	wfi, ok := mb.instances[instance.GetInstanceID()]
+	if !ok || wfi.instance.GetExecutionID() != instance.GetExecutionID() {
+		return errors.New("could not find workflow instance")
+	}
+
+	now := time.Now()
+	if !wfi.lockedUntil.After(now) {
+		return errors.New("could not extend workflow task")
+	}
+
+	wfi.lockedUntil = now.Add(mb.options.WorkflowLockTimeout)
+
+	return nil
+}
+
+func (mb *memoryBackend) GetActivityTask(ctx context.Context) (*task.Activity, error) {
+	for {
+		notification := mb.ActivityTasksNotification()
+
+		t, next := mb.lockActivityTask()
+		if t != nil {
+			return t, nil
+		}
+
+		if !mb.wait(ctx, notification, next) {
+			return nil, nil
+		}
+	}
+}
+
+func (mb *memoryBackend) lockActivityTask() (*task.Activity, time.Time) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	now := time.Now()
+	var next time.Time
+
+	honorPriority := mb.activityFairness.HonorPriority()
+
+	var a *activity
+	for _, id := range mb.activityIDs {
+		candidate := mb.activities[id]
+		if candidate.lockedUntil.After(now) {
+			next = earliest(next, candidate.lockedUntil)
+			continue
+		}
+
+		if a == nil || candidate.priority > a.priority {
+			a = candidate
+		}
+
+		if !honorPriority {
+			break
+		}
+	}
+
+	if a == nil {
+		return nil, next
+	}
+
+	a.lockedUntil = now.Add(mb.options.ActivityLockTimeout)
+
+	mb.options.Logger.Debug("Locked activity task",
+		log.InstanceIDKey, a.instance.GetInstanceID(),
+		log.ExecutionIDKey, a.instance.GetExecutionID(),
+		log.ActivityIDKey, a.id,
+		log.ScheduleEventIDKey, a.event.ScheduleEventID,
+	)
+
+	return &task.Activity{
+		ID:               a.id,
+		WorkflowInstance: a.instance,
+		Event:            a.event,
+	}, time.Time{}
+}
+
+func (mb *memoryBackend) CompleteActivityTask(ctx context.Context, instance workflow.Instance, activityID string, event history.Event) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	a, ok := mb.activities[activityID]
+	if !ok || a.instance.GetInstanceID() != instance.GetInstanceID() {
+		return errors.New("could not find activity")
+	}
+
+	if !a.lockedUntil.After(time.Now()) {
+		return errors.New("activity is not locked")
+	}
+
+	mb.removeActivity(activityID)
+
+	wfi, ok := mb.instances[instance.GetInstanceID()]
+	if !ok {
+		return errors.New("could not find workflow instance")
+	}
+
+	wfi.pendingEvents = append(wfi.pendingEvents, event)
+
+	mb.options.Logger.Debug("Completed activity task",
+		log.InstanceIDKey, instance.GetInstanceID(),
+		log.ExecutionIDKey, instance.GetExecutionID(),
+		log.ActivityIDKey, activityID,
+		log.EventIDKey, event.ID,
+	)
+
+	mb.NotifyWorkflowTasks()
+
+	return nil
+}
+
+func (mb *memoryBackend) removeActivity(activityID string) {
+	delete(mb.activities, activityID)
+
+	for i, id := range mb.activityIDs {
+		if id == activityID {
+			mb.activityIDs = append(mb.activityIDs[:i], mb.activityIDs[i+1:]...)
+			break
+		}
+	}
+}
+
+func (mb *memoryBackend) ExtendActivityTask(ctx context.Context, activityID string) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	a, ok := mb.activities[activityID]
+	if !ok {
+		return errors.New("could not find activity")
+	}
+
+	now := time.Now()
+	if !a.lockedUntil.After(now) {
+		return errors.New("could not extend activity")
+	}
+
+	a.lockedUntil = now.Add(mb.options.ActivityLockTimeout)
+
+	return nil
+}
+
+// wait blocks until a notification is received, the given time is reached, or ctx is canceled.
+// It returns false if ctx has been canceled.
+func (mb *memoryBackend) wait(ctx context.Context, notification <-chan struct{}, next time.Time) bool {
+	var timeout <-chan time.Time
+	if !next.IsZero() {
+		t := time.NewTimer(time.Until(next))
+		defer t.Stop()
+
+		timeout = t.C
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-notification:
+		return true
+	case <-timeout:
+		return true
+	}
+}
+
+// visibleEvents returns the events that are visible at the given time and the time the next
+// event becomes visible
+func visibleEvents(events []history.Event, now time.Time) ([]history.Event, time.Time) {
+	var visible []history.Event
+	var next time.Time
+
+	for _, e := range events {
+		if e.VisibleAt == nil || !e.VisibleAt.After(now) {
+			visible = append(visible, e)
+		} else {
+			next = earliest(next, *e.VisibleAt)
+		}
+	}
+
+	return visible, next
+}
+
+// readyAt returns when the earliest of the given visible events became visible
+func readyAt(events []history.Event) time.Time {
+	var t time.Time
+	for _, e := range events {
+		visibleAt := e.Timestamp
+		if e.VisibleAt != nil {
+			visibleAt = *e.VisibleAt
+		}
+
+		t = earliest(t, visibleAt)
+	}
+
+	return t
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+
+	return a
+}
@@ -0,0 +1,208 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/test"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MemoryBackend(t *testing.T) {
+	test.TestBackend(t, test.Tester{
+		New: func(options ...backend.BackendOption) backend.Backend {
+			// Disable sticky workflow behavior for the test execution
+			return NewMemoryBackend(append([]backend.BackendOption{backend.WithStickyTimeout(0)}, options...)...)
+		},
+	})
+}
+
+func Test_MemoryBackend_EndToEnd(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := NewMemoryBackend()
+
+	w := worker.New(b, nil)
+	require.NoError(t, w.RegisterWorkflow(workflowWithTimerAndActivity))
+	require.NoError(t, w.RegisterActivity(double))
+	require.NoError(t, w.Start(ctx))
+
+	c := client.New(b)
+
+	instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+	}, workflowWithTimerAndActivity, 21)
+	require.NoError(t, err)
+
+	mb := b.(*memoryBackend)
+	require.Eventually(t, func() bool {
+		mb.mu.Lock()
+		defer mb.mu.Unlock()
+
+		return mb.instances[instance.GetInstanceID()].completed
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, w.Stop())
+}
+
+func workflowWithTimerAndActivity(ctx workflow.Context, a int) (int, error) {
+	if err := workflow.ScheduleTimer(ctx, 50*time.Millisecond).Get(ctx, nil); err != nil {
+		return 0, err
+	}
+
+	var r int
+	if err := workflow.ExecuteActivity(ctx, workflow.DefaultActivityOptions, double, a).Get(ctx, &r); err != nil {
+		return 0, err
+	}
+
+	return r, nil
+}
+
+func double(ctx context.Context, a int) (int, error) {
+	return a * 2, nil
+}
+
+func Test_MemoryBackend_Schedules(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := NewMemoryBackend()
+
+	w := worker.New(b, &worker.Options{
+		WorkflowPollers:      1,
+		ActivityPollers:      1,
+		ProcessSchedules:     true,
+		SchedulePollInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, w.RegisterWorkflow(workflowWithTimerAndActivity))
+	require.NoError(t, w.RegisterActivity(double))
+	require.NoError(t, w.Start(ctx))
+
+	c := client.New(b)
+
+	scheduleID := uuid.NewString()
+	_, err := c.CreateSchedule(ctx, scheduleID, client.ScheduleSpec{
+		Interval: 100 * time.Millisecond,
+		Priority: 5,
+	}, workflowWithTimerAndActivity, 21)
+	require.NoError(t, err)
+
+	mb := b.(*memoryBackend)
+	runs := func() (started, completed int) {
+		mb.mu.Lock()
+		defer mb.mu.Unlock()
+
+		for id, wfi := range mb.instances {
+			if strings.HasPrefix(id, scheduleID) {
+				started++
+				if wfi.completed {
+					completed++
+				}
+			}
+		}
+
+		return started, completed
+	}
+	completedRuns := func() int {
+		_, completed := runs()
+		return completed
+	}
+
+	require.Eventually(t, func() bool {
+		return completedRuns() >= 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Runs are started with the priority of the schedule
+	mb.mu.Lock()
+	for id, wfi := range mb.instances {
+		if strings.HasPrefix(id, scheduleID) {
+			require.Equal(t, 5, wfi.priority)
+		}
+	}
+	mb.mu.Unlock()
+
+	// No more runs are started while the schedule is paused
+	require.NoError(t, c.PauseSchedule(ctx, scheduleID))
+	s, err := c.GetSchedule(ctx, scheduleID)
+	require.NoError(t, err)
+	require.True(t, s.Paused)
+
+	require.Eventually(t, func() bool {
+		started, completed := runs()
+		return started == completed
+	}, 5*time.Second, 10*time.Millisecond)
+	started, _ := runs()
+
+	time.Sleep(300 * time.Millisecond)
+	startedWhilePaused, _ := runs()
+	require.Equal(t, started, startedWhilePaused)
+
+	// Backfills are started even though the schedule is paused, both ends of the range are included
+	require.NoError(t, c.BackfillSchedule(ctx, scheduleID, s.CreatedAt.Add(-time.Second), s.CreatedAt.Add(-time.Second/2)))
+	require.Eventually(t, func() bool {
+		return completedRuns() == started+6
+	}, 5*time.Second, 10*time.Millisecond)
+
+	schedules, err := c.ListSchedules(ctx)
+	require.NoError(t, err)
+	require.Len(t, schedules, 1)
+
+	require.NoError(t, c.DeleteSchedule(ctx, scheduleID))
+
+	cancel()
+	require.NoError(t, w.Stop())
+}
+
+func Test_MemoryBackend_DelayedStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := NewMemoryBackend()
+
+	w := worker.New(b, nil)
+	require.NoError(t, w.RegisterWorkflow(workflowWithSignal))
+	require.NoError(t, w.Start(ctx))
+
+	c := client.New(b)
+
+	instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+		StartDelay: 200 * time.Millisecond,
+	}, workflowWithSignal)
+	require.NoError(t, err)
+
+	info, err := c.DescribeWorkflowInstance(ctx, instance)
+	require.NoError(t, err)
+	require.Equal(t, backend.WorkflowInstanceStateScheduled, info.State)
+
+	// The signal is delivered once the instance started
+	require.NoError(t, c.SignalWorkflow(ctx, instance.GetInstanceID(), "signal", 42))
+
+	require.Eventually(t, func() bool {
+		info, err := c.DescribeWorkflowInstance(ctx, instance)
+		return err == nil && info.State == backend.WorkflowInstanceStateFinished
+	}, 5*time.Second, 10*time.Millisecond)
+
+	info, err = c.DescribeWorkflowInstance(ctx, instance)
+	require.NoError(t, err)
+	require.True(t, info.CompletedAt.Sub(info.CreatedAt) >= 200*time.Millisecond)
+
+	cancel()
+	require.NoError(t, w.Stop())
+}
+
+func workflowWithSignal(ctx workflow.Context) (int, error) {
+	var v int
+	workflow.NewSignalChannel(ctx, "signal").Receive(ctx, &v)
+
+	return v, nil
+}
@@ -0,0 +1,166 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/schedule"
+	"github.com/pkg/errors"
+)
+
+// storedSchedule keeps schedules serialized, so that callers cannot modify the stored state
+type storedSchedule struct {
+	data    []byte
+	version int64
+	dueAt   *time.Time
+}
+
+func (mb *memoryBackend) CreateSchedule(ctx context.Context, s *schedule.Schedule) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if _, ok := mb.schedules[s.ID]; ok {
+		return backend.ErrScheduleAlreadyExists
+	}
+
+	if err := mb.storeSchedule(s, 1); err != nil {
+		return err
+	}
+
+	mb.NotifySchedules()
+
+	return nil
+}
+
+func (mb *memoryBackend) GetSchedule(ctx context.Context, scheduleID string) (*schedule.Schedule, error) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	ss, ok := mb.schedules[scheduleID]
+	if !ok {
+		return nil, backend.ErrScheduleNotFound
+	}
+
+	return ss.schedule()
+}
+
+func (mb *memoryBackend) ListSchedules(ctx context.Context) ([]*schedule.Schedule, error) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	ids := make([]string, 0, len(mb.schedules))
+	for id := range mb.schedules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	schedules := make([]*schedule.Schedule, 0, len(ids))
+	for _, id := range ids {
+		s, err := mb.schedules[id].schedule()
+		if err != nil {
+			return nil, err
+		}
+
+		schedules = append(schedules, s)
+	}
+
+	return schedules, nil
+}
+
+func (mb *memoryBackend) GetDueSchedules(ctx context.Context, now time.Time, limit int) ([]*schedule.Schedule, error) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	due := make([]*storedSchedule, 0)
+	for _, ss := range mb.schedules {
+		if ss.dueAt != nil && !ss.dueAt.After(now) {
+			due = append(due, ss)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].dueAt.Before(*due[j].dueAt)
+	})
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	schedules := make([]*schedule.Schedule, 0, len(due))
+	for _, ss := range due {
+		s, err := ss.schedule()
+		if err != nil {
+			return nil, err
+		}
+
+		schedules = append(schedules, s)
+	}
+
+	return schedules, nil
+}
+
+func (mb *memoryBackend) UpdateSchedule(ctx context.Context, s *schedule.Schedule) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	ss, ok := mb.schedules[s.ID]
+	if !ok {
+		return backend.ErrScheduleNotFound
+	}
+
+	if ss.version != s.Version {
+		return backend.ErrScheduleModified
+	}
+
+	if err := mb.storeSchedule(s, s.Version+1); err != nil {
+		return err
+	}
+
+	mb.NotifySchedules()
+
+	return nil
+}
+
+func (mb *memoryBackend) DeleteSchedule(ctx context.Context, scheduleID string) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if _, ok := mb.schedules[scheduleID]; !ok {
+		return backend.ErrScheduleNotFound
+	}
+
+	delete(mb.schedules, scheduleID)
+
+	return nil
+}
+
+func (mb *memoryBackend) storeSchedule(s *schedule.Schedule, version int64) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal schedule")
+	}
+
+	mb.schedules[s.ID] = &storedSchedule{
+		data:    data,
+		version: version,
+		dueAt:   s.DueAt,
+	}
+
+	s.Version = version
+
+	return nil
+}
+
+func (ss *storedSchedule) schedule() (*schedule.Schedule, error) {
+	var s schedule.Schedule
+	if err := json.Unmarshal(ss.data, &s); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal schedule")
+	}
+
+	s.Version = ss.version
+
+	return &s, nil
+}
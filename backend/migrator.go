@@ -0,0 +1,11 @@
+package backend
+
+import "context"
+
+// Migrator is implemented by backends with a versioned database schema. By default, backends
+// apply pending migrations when they are created. With WithApplyMigrations(false), Migrate has
+// to be called explicitly, for example as part of a deployment.
+type Migrator interface {
+	// Migrate applies all migrations that haven't been applied to the database yet
+	Migrate(ctx context.Context) error
+}
@@ -10,7 +10,11 @@ import (
 
 	mock "github.com/stretchr/testify/mock"
 
+	schedule "github.com/cschleiden/go-workflows/internal/schedule"
+
 	task "github.com/cschleiden/go-workflows/internal/task"
+
+	time "time"
 )
 
 // MockBackend is an autogenerated mock type for the Backend type
@@ -60,6 +64,20 @@ func (_m *MockBackend) CompleteWorkflowTask(ctx context.Context, instance core.W
 	return r0
 }
 
+// CreateSchedule provides a mock function with given fields: ctx, s
+func (_m *MockBackend) CreateSchedule(ctx context.Context, s *schedule.Schedule) error {
+	ret := _m.Called(ctx, s)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *schedule.Schedule) error); ok {
+		r0 = rf(ctx, s)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // CreateWorkflowInstance provides a mock function with given fields: ctx, event
 func (_m *MockBackend) CreateWorkflowInstance(ctx context.Context, event history.WorkflowEvent) error {
 	ret := _m.Called(ctx, event)
@@ -74,6 +92,43 @@ func (_m *MockBackend) CreateWorkflowInstance(ctx context.Context, event history
 	return r0
 }
 
+// DeleteSchedule provides a mock function with given fields: ctx, scheduleID
+func (_m *MockBackend) DeleteSchedule(ctx context.Context, scheduleID string) error {
+	ret := _m.Called(ctx, scheduleID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, scheduleID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DescribeWorkflowInstance provides a mock function with given fields: ctx, instance
+func (_m *MockBackend) DescribeWorkflowInstance(ctx context.Context, instance core.WorkflowInstance) (*WorkflowInstanceInfo, error) {
+	ret := _m.Called(ctx, instance)
+
+	var r0 *WorkflowInstanceInfo
+	if rf, ok := ret.Get(0).(func(context.Context, core.WorkflowInstance) *WorkflowInstanceInfo); ok {
+		r0 = rf(ctx, instance)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*WorkflowInstanceInfo)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, core.WorkflowInstance) error); ok {
+		r1 = rf(ctx, instance)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ExtendActivityTask provides a mock function with given fields: ctx, activityID
 func (_m *MockBackend) ExtendActivityTask(ctx context.Context, activityID string) error {
 	ret := _m.Called(ctx, activityID)
@@ -125,6 +180,75 @@ func (_m *MockBackend) GetActivityTask(ctx context.Context) (*task.Activity, err
 	return r0, r1
 }
 
+// GetDueSchedules provides a mock function with given fields: ctx, now, limit
+func (_m *MockBackend) GetDueSchedules(ctx context.Context, now time.Time, limit int) ([]*schedule.Schedule, error) {
+	ret := _m.Called(ctx, now, limit)
+
+	var r0 []*schedule.Schedule
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) []*schedule.Schedule); ok {
+		r0 = rf(ctx, now, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*schedule.Schedule)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int) error); ok {
+		r1 = rf(ctx, now, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSchedule provides a mock function with given fields: ctx, scheduleID
+func (_m *MockBackend) GetSchedule(ctx context.Context, scheduleID string) (*schedule.Schedule, error) {
+	ret := _m.Called(ctx, scheduleID)
+
+	var r0 *schedule.Schedule
+	if rf, ok := ret.Get(0).(func(context.Context, string) *schedule.Schedule); ok {
+		r0 = rf(ctx, scheduleID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*schedule.Schedule)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, scheduleID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWorkflowInstanceHistory provides a mock function with given fields: ctx, instance
+func (_m *MockBackend) GetWorkflowInstanceHistory(ctx context.Context, instance core.WorkflowInstance) ([]history.Event, error) {
+	ret := _m.Called(ctx, instance)
+
+	var r0 []history.Event
+	if rf, ok := ret.Get(0).(func(context.Context, core.WorkflowInstance) []history.Event); ok {
+		r0 = rf(ctx, instance)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]history.Event)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, core.WorkflowInstance) error); ok {
+		r1 = rf(ctx, instance)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetWorkflowTask provides a mock function with given fields: ctx
 func (_m *MockBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, error) {
 	ret := _m.Called(ctx)
@@ -148,6 +272,66 @@ func (_m *MockBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, err
 	return r0, r1
 }
 
+// ListSchedules provides a mock function with given fields: ctx
+func (_m *MockBackend) ListSchedules(ctx context.Context) ([]*schedule.Schedule, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []*schedule.Schedule
+	if rf, ok := ret.Get(0).(func(context.Context) []*schedule.Schedule); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*schedule.Schedule)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListWorkflowInstances provides a mock function with given fields: ctx, afterInstanceID, limit
+func (_m *MockBackend) ListWorkflowInstances(ctx context.Context, afterInstanceID string, limit int) ([]*WorkflowInstanceInfo, error) {
+	ret := _m.Called(ctx, afterInstanceID, limit)
+
+	var r0 []*WorkflowInstanceInfo
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []*WorkflowInstanceInfo); ok {
+		r0 = rf(ctx, afterInstanceID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*WorkflowInstanceInfo)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, afterInstanceID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RemoveWorkflowInstance provides a mock function with given fields: ctx, instance
+func (_m *MockBackend) RemoveWorkflowInstance(ctx context.Context, instance core.WorkflowInstance) error {
+	ret := _m.Called(ctx, instance)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, core.WorkflowInstance) error); ok {
+		r0 = rf(ctx, instance)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SignalWorkflow provides a mock function with given fields: ctx, instanceID, event
 func (_m *MockBackend) SignalWorkflow(ctx context.Context, instanceID string, event history.Event) error {
 	ret := _m.Called(ctx, instanceID, event)
@@ -161,3 +345,17 @@ func (_m *MockBackend) SignalWorkflow(ctx context.Context, instanceID string, ev
 
 	return r0
 }
+
+// UpdateSchedule provides a mock function with given fields: ctx, s
+func (_m *MockBackend) UpdateSchedule(ctx context.Context, s *schedule.Schedule) error {
+	ret := _m.Called(ctx, s)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *schedule.Schedule) error); ok {
+		r0 = rf(ctx, s)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
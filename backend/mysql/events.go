@@ -6,17 +6,22 @@ import (
 	"strings"
 
 	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/pkg/errors"
 )
 
-func insertNewEvents(ctx context.Context, tx *sql.Tx, instanceID string, newEvents []history.Event) error {
-	return insertEvents(ctx, tx, "pending_events", instanceID, newEvents)
+func (b *mysqlBackend) serializeAttributes(attributes interface{}) ([]byte, error) {
+	return history.SerializeAttributes(attributes, history.WithCompression(b.options.Compression, b.options.CompressionThreshold))
 }
 
-func insertHistoryEvents(ctx context.Context, tx *sql.Tx, instanceID string, historyEvents []history.Event) error {
-	return insertEvents(ctx, tx, "history", instanceID, historyEvents)
+func (b *mysqlBackend) insertNewEvents(ctx context.Context, tx *sql.Tx, instanceID string, newEvents []history.Event) error {
+	return b.insertEvents(ctx, tx, "pending_events", instanceID, newEvents)
 }
 
-func insertEvents(ctx context.Context, tx *sql.Tx, tableName string, instanceID string, events []history.Event) error {
+func (b *mysqlBackend) insertHistoryEvents(ctx context.Context, tx *sql.Tx, instanceID string, historyEvents []history.Event) error {
+	return b.insertEvents(ctx, tx, "history", instanceID, historyEvents)
+}
+
+func (b *mysqlBackend) insertEvents(ctx context.Context, tx *sql.Tx, tableName string, instanceID string, events []history.Event) error {
 	const batchSize = 20
 	for batchStart := 0; batchStart < len(events); batchStart += batchSize {
 		batchEnd := batchStart + batchSize
@@ -31,7 +36,7 @@ func insertEvents(ctx context.Context, tx *sql.Tx, tableName string, instanceID
 		args := make([]interface{}, 0, len(batchEvents)*7)
 
 		for _, newEvent := range batchEvents {
-			a, err := history.SerializeAttributes(newEvent.Attributes)
+			a, err := b.serializeAttributes(newEvent.Attributes)
 			if err != nil {
 				return err
 			}
@@ -51,3 +56,47 @@ func insertEvents(ctx context.Context, tx *sql.Tx, tableName string, instanceID
 
 	return nil
 }
+
+func getHistory(ctx context.Context, tx *sql.Tx, instanceID string) ([]history.Event, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT event_id, instance_id, event_type, timestamp, schedule_event_id, attributes, visible_at FROM `history` WHERE instance_id = ? ORDER BY id",
+		instanceID,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get history")
+	}
+	defer rows.Close()
+
+	events := make([]history.Event, 0)
+
+	for rows.Next() {
+		var instanceID string
+		var attributes []byte
+
+		historyEvent := history.Event{}
+
+		if err := rows.Scan(
+			&historyEvent.ID,
+			&instanceID,
+			&historyEvent.Type,
+			&historyEvent.Timestamp,
+			&historyEvent.ScheduleEventID,
+			&attributes,
+			&historyEvent.VisibleAt,
+		); err != nil {
+			return nil, errors.Wrap(err, "could not scan event")
+		}
+
+		a, err := history.DeserializeAttributes(historyEvent.Type, attributes)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not deserialize attributes")
+		}
+
+		historyEvent.Attributes = a
+
+		events = append(events, historyEvent)
+	}
+
+	return events, nil
+}
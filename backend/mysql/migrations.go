@@ -0,0 +1,56 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+
+	"github.com/cschleiden/go-workflows/internal/migrations"
+	"github.com/pkg/errors"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migrationLockTimeout is the number of seconds to wait for another process to finish migrating
+const migrationLockTimeout = 300
+
+// Migrate applies all pending schema migrations
+func (b *mysqlBackend) Migrate(ctx context.Context) error {
+	m, err := migrations.Load(migrationsFS, "migrations")
+	if err != nil {
+		return err
+	}
+
+	return migrations.Migrate(ctx, b.db, dialect{}, m)
+}
+
+// dialect uses a named lock to prevent concurrent migrations. MySQL commits schema changes
+// implicitly, so failed migrations can't be rolled back.
+type dialect struct{}
+
+func (dialect) Lock(ctx context.Context, conn *sql.Conn) error {
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK('workflows_migrations', ?)", migrationLockTimeout).Scan(&locked); err != nil {
+		return err
+	}
+
+	if !locked.Valid || locked.Int64 != 1 {
+		return errors.New("timed out waiting for migration lock")
+	}
+
+	return nil
+}
+
+func (dialect) Unlock(ctx context.Context, conn *sql.Conn, failed bool) error {
+	_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK('workflows_migrations')")
+	return err
+}
+
+func (dialect) CreateVersionTable() string {
+	return "CREATE TABLE IF NOT EXISTS `schema_version` (`version` INT NOT NULL PRIMARY KEY, `applied_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP)"
+}
+
+func (dialect) InsertVersion() string {
+	return "INSERT INTO `schema_version` (`version`) VALUES (?)"
+}
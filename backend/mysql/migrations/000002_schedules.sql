@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS `schedules` (
+  `id` NVARCHAR(128) NOT NULL PRIMARY KEY,
+  `version` BIGINT NOT NULL,
+  `due_at` DATETIME NULL,
+  `data` BLOB NOT NULL,
+
+  INDEX `idx_schedules_due_at` (`due_at`)
+);
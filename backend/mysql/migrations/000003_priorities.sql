@@ -0,0 +1,7 @@
+ALTER TABLE `instances`
+  ADD COLUMN `priority` INT NOT NULL DEFAULT 0,
+  ADD INDEX `idx_instances_completed_at_priority_id` (`completed_at`, `priority` DESC, `id`);
+
+ALTER TABLE `activities`
+  ADD COLUMN `priority` INT NOT NULL DEFAULT 0,
+  ADD INDEX `idx_activities_priority_id` (`priority` DESC, `id`);
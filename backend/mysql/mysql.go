@@ -3,57 +3,133 @@ package mysql
 import (
 	"context"
 	"database/sql"
-	_ "embed"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/internal/metrickeys"
+	"github.com/cschleiden/go-workflows/internal/retention"
+	"github.com/cschleiden/go-workflows/internal/sqlbackend"
 	"github.com/cschleiden/go-workflows/internal/task"
 	"github.com/cschleiden/go-workflows/workflow"
-	_ "github.com/go-sql-driver/mysql"
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 )
 
-//go:embed schema.sql
-var schema string
-
 func NewMysqlBackend(host string, port int, user, password, database string, opts ...backend.BackendOption) backend.Backend {
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&interpolateParams=true", user, password, host, port, database)
 
-	schemaDsn := dsn + "&multiStatements=true"
-	db, err := sql.Open("mysql", schemaDsn)
+	b, err := NewMysqlBackendFromDSN(dsn, opts...)
 	if err != nil {
 		panic(err)
 	}
 
-	if _, err := db.Exec(schema); err != nil {
-		panic(errors.Wrap(err, "could not initialize database"))
+	return b
+}
+
+// NewMysqlBackendFromDSN creates a backend using the MySQL database identified by dsn. The DSN
+// follows the format of github.com/go-sql-driver/mysql, which allows to configure TLS and
+// timeouts. parseTime is always enabled, since the backend relies on it. The connection pool
+// can be configured using backend.WithConnectionPool.
+func NewMysqlBackendFromDSN(dsn string, opts ...backend.BackendOption) (backend.Backend, error) {
+	options := backend.ApplyOptions(opts...)
+
+	cfg, err := mysqldriver.ParseDSN(dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse DSN")
 	}
 
-	if err := db.Close(); err != nil {
-		panic(err)
+	cfg.ParseTime = true
+
+	connector, err := mysqldriver.NewConnector(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create connector")
 	}
 
-	db, err = sql.Open("mysql", dsn)
+	db := sql.OpenDB(connector)
+	options.ConnectionPool.Apply(db)
+
+	b, err := newMysqlBackend(db, options)
 	if err != nil {
-		panic(err)
+		db.Close()
+		return nil, err
 	}
 
-	return &mysqlBackend{
+	b.ownsDB = true
+
+	return b, nil
+}
+
+// NewMysqlBackendFromDB creates a backend using an existing database, which has to be opened
+// with parseTime=true. The caller remains responsible for configuring and closing db.
+func NewMysqlBackendFromDB(db *sql.DB, opts ...backend.BackendOption) (backend.Backend, error) {
+	b, err := newMysqlBackend(db, backend.ApplyOptions(opts...))
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func newMysqlBackend(db *sql.DB, options backend.Options) (*mysqlBackend, error) {
+	b := &mysqlBackend{
 		db:         db,
 		workerName: fmt.Sprintf("worker-%v", uuid.NewString()),
-		options:    backend.ApplyOptions(opts...),
+		options:    options,
+
+		workflowFairness: backend.NewPriorityFairness(options.PriorityFairness),
+		activityFairness: backend.NewPriorityFairness(options.PriorityFairness),
+
+		Notifications: backend.NewNotifications(),
+	}
+
+	b.Store = sqlbackend.New(db, queries, options, b.Notifications)
+	b.queueDepth = backend.NewQueueDepth(options.Metrics, b.measureQueueDepth)
+
+	if options.ApplyMigrations {
+		if err := b.Migrate(context.Background()); err != nil {
+			return nil, errors.Wrap(err, "could not migrate database")
+		}
 	}
+
+	b.stopRetention = retention.Start(clock.New(), options.RetentionPeriod, retention.Interval, options.Logger, b.PurgeCompletedInstances)
+
+	return b, nil
 }
 
 type mysqlBackend struct {
 	db         *sql.DB
+	ownsDB     bool
 	workerName string
 	options    backend.Options
+
+	stopRetention func()
+
+	workflowFairness *backend.PriorityFairness
+	activityFairness *backend.PriorityFairness
+
+	*backend.Notifications
+	*sqlbackend.Store
+
+	queueDepth *backend.QueueDepth
+}
+
+// Close stops the background cleanup of completed instances and closes the database, if it was
+// opened by the backend
+func (b *mysqlBackend) Close() error {
+	b.stopRetention()
+
+	if !b.ownsDB {
+		return nil
+	}
+
+	return b.db.Close()
 }
 
 // CreateWorkflowInstance creates a new workflow instance
@@ -65,12 +141,12 @@ func (b *mysqlBackend) CreateWorkflowInstance(ctx context.Context, m history.Wor
 	defer tx.Rollback()
 
 	// Create workflow instance
-	if err := createInstance(ctx, tx, m.WorkflowInstance); err != nil {
+	if err := createInstance(ctx, tx, m.WorkflowInstance, backend.InstancePriority(m.HistoryEvent)); err != nil {
 		return err
 	}
 
 	// Initial history is empty, store only new events
-	if err := insertNewEvents(ctx, tx, m.WorkflowInstance.GetInstanceID(), []history.Event{m.HistoryEvent}); err != nil {
+	if err := b.insertNewEvents(ctx, tx, m.WorkflowInstance.GetInstanceID(), []history.Event{m.HistoryEvent}); err != nil {
 		return errors.Wrap(err, "could not insert new event")
 	}
 
@@ -78,6 +154,8 @@ func (b *mysqlBackend) CreateWorkflowInstance(ctx context.Context, m history.Wor
 		return errors.Wrap(err, "could not create workflow instance")
 	}
 
+	b.NotifyWorkflowTasks()
+
 	return nil
 }
 
@@ -90,8 +168,15 @@ func (b *mysqlBackend) CancelWorkflowInstance(ctx context.Context, instance work
 
 	instanceID := instance.GetInstanceID()
 
+	startAt, err := b.ScheduledStart(ctx, tx, instanceID)
+	if err != nil {
+		return err
+	}
+
 	// Cancel workflow instance
-	if err := insertNewEvents(ctx, tx, instanceID, []history.Event{history.NewWorkflowCancellationEvent(time.Now())}); err != nil {
+	events := []history.Event{history.NewWorkflowCancellationEvent(time.Now())}
+	backend.DelayUntilStart(events, startAt)
+	if err := b.insertNewEvents(ctx, tx, instanceID, events); err != nil {
 		return errors.Wrap(err, "could not insert cancellation event")
 	}
 
@@ -110,17 +195,23 @@ func (b *mysqlBackend) CancelWorkflowInstance(ctx context.Context, instance work
 		}
 
 		// Cancel sub-workflow instance
-		if err := insertNewEvents(ctx, tx, subWorkflowInstanceID, []history.Event{history.NewWorkflowCancellationEvent(time.Now())}); err != nil {
+		if err := b.insertNewEvents(ctx, tx, subWorkflowInstanceID, []history.Event{history.NewWorkflowCancellationEvent(time.Now())}); err != nil {
 			return errors.Wrap(err, "could not insert cancellation event")
 		}
 
 		instanceID = subWorkflowInstanceID
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	b.NotifyWorkflowTasks()
+
+	return nil
 }
 
-func createInstance(ctx context.Context, tx *sql.Tx, wfi workflow.Instance) error {
+func createInstance(ctx context.Context, tx *sql.Tx, wfi workflow.Instance, priority int) error {
 	var parentInstanceID *string
 	var parentEventID *int
 	if wfi.SubWorkflow() {
@@ -133,11 +224,12 @@ func createInstance(ctx context.Context, tx *sql.Tx, wfi workflow.Instance) erro
 
 	if _, err := tx.ExecContext(
 		ctx,
-		"INSERT IGNORE INTO `instances` (instance_id, execution_id, parent_instance_id, parent_schedule_event_id) VALUES (?, ?, ?, ?)",
+		"INSERT IGNORE INTO `instances` (instance_id, execution_id, parent_instance_id, parent_schedule_event_id, priority) VALUES (?, ?, ?, ?, ?)",
 		wfi.GetInstanceID(),
 		wfi.GetExecutionID(),
 		parentInstanceID,
 		parentEventID,
+		priority,
 	); err != nil {
 		return errors.Wrap(err, "could not insert workflow instance")
 	}
@@ -146,6 +238,25 @@ func createInstance(ctx context.Context, tx *sql.Tx, wfi workflow.Instance) erro
 }
 
 // SignalWorkflow signals a running workflow instance
+func (b *mysqlBackend) GetWorkflowInstanceHistory(ctx context.Context, instance workflow.Instance) ([]history.Event, error) {
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRowContext(ctx, "SELECT 1 FROM `instances` WHERE instance_id = ? AND execution_id = ?", instance.GetInstanceID(), instance.GetExecutionID()).Scan(&exists); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, backend.ErrInstanceNotFound
+		}
+
+		return nil, errors.Wrap(err, "could not get workflow instance")
+	}
+
+	return getHistory(ctx, tx, instance.GetInstanceID())
+}
+
 func (b *mysqlBackend) SignalWorkflow(ctx context.Context, instanceID string, event history.Event) error {
 	tx, err := b.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -153,15 +264,30 @@ func (b *mysqlBackend) SignalWorkflow(ctx context.Context, instanceID string, ev
 	}
 	defer tx.Rollback()
 
-	if err := insertNewEvents(ctx, tx, instanceID, []history.Event{event}); err != nil {
+	startAt, err := b.ScheduledStart(ctx, tx, instanceID)
+	if err != nil {
+		return err
+	}
+
+	events := []history.Event{event}
+	backend.DelayUntilStart(events, startAt)
+	if err := b.insertNewEvents(ctx, tx, instanceID, events); err != nil {
 		return errors.Wrap(err, "could not insert signal event")
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	b.NotifyWorkflowTasks()
+
+	return nil
 }
 
 // GetWorkflowInstance returns a pending workflow task or nil if there are no pending worflow executions
 func (b *mysqlBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, error) {
+	b.queueDepth.Report(ctx)
+
 	tx, err := b.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -169,6 +295,12 @@ func (b *mysqlBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, err
 	defer tx.Rollback()
 
 	// Lock next workflow task by finding an unlocked instance with new events to process.
+	// Polls ignoring priorities pick the instance whose earliest pending event became visible first
+	orderBy := "COALESCE(pe.visible_at, pe.timestamp), i.id"
+	if b.workflowFairness.HonorPriority() {
+		orderBy = "i.priority DESC, i.id"
+	}
+
 	now := time.Now()
 	row := tx.QueryRowContext(
 		ctx,
@@ -179,6 +311,7 @@ func (b *mysqlBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, err
 				AND (i.sticky_until IS NULL OR i.sticky_until < ? OR i.worker = ?)
 				AND i.completed_at IS NULL
 				AND (pe.visible_at IS NULL OR pe.visible_at <= ?)
+			ORDER BY `+orderBy+`
 			LIMIT 1
 			FOR UPDATE SKIP LOCKED`,
 		now,          // locked_until
@@ -278,42 +411,12 @@ func (b *mysqlBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, err
 
 	// Get historyEvents
 	if kind != task.Continuation {
-		historyEvents, err := tx.QueryContext(
-			ctx,
-			"SELECT event_id, instance_id, event_type, timestamp, schedule_event_id, attributes, visible_at FROM `history` WHERE instance_id = ? ORDER BY id",
-			instanceID,
-		)
+		historyEvents, err := getHistory(ctx, tx, instanceID)
 		if err != nil {
-			return nil, errors.Wrap(err, "could not get history")
+			return nil, err
 		}
 
-		for historyEvents.Next() {
-			var instanceID string
-			var attributes []byte
-
-			historyEvent := history.Event{}
-
-			if err := historyEvents.Scan(
-				&historyEvent.ID,
-				&instanceID,
-				&historyEvent.Type,
-				&historyEvent.Timestamp,
-				&historyEvent.ScheduleEventID,
-				&attributes,
-				&historyEvent.VisibleAt,
-			); err != nil {
-				return nil, errors.Wrap(err, "could not scan event")
-			}
-
-			a, err := history.DeserializeAttributes(historyEvent.Type, attributes)
-			if err != nil {
-				return nil, errors.Wrap(err, "could not deserialize attributes")
-			}
-
-			historyEvent.Attributes = a
-
-			t.History = append(t.History, historyEvent)
-		}
+		t.History = historyEvents
 	} else {
 		// Get only most recent history event
 		row := tx.QueryRowContext(ctx, "SELECT event_id, instance_id, event_type, timestamp, schedule_event_id, attributes, visible_at FROM `history` WHERE instance_id = ? ORDER BY id DESC LIMIT 1", instanceID)
@@ -349,6 +452,13 @@ func (b *mysqlBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, err
 		return nil, err
 	}
 
+	b.options.Logger.Debug("Locked workflow task",
+		log.InstanceIDKey, instanceID,
+		log.ExecutionIDKey, executionID,
+		"new_events", len(t.NewEvents),
+		"continuation", kind == task.Continuation,
+	)
+
 	return t, nil
 }
 
@@ -409,7 +519,7 @@ func (b *mysqlBackend) CompleteWorkflowTask(
 	}
 
 	// Insert new events generated during this workflow execution to the history
-	if err := insertHistoryEvents(ctx, tx, instance.GetInstanceID(), executedEvents); err != nil {
+	if err := b.insertHistoryEvents(ctx, tx, instance.GetInstanceID(), executedEvents); err != nil {
 		return errors.Wrap(err, "could not insert new history events")
 	}
 
@@ -419,7 +529,7 @@ func (b *mysqlBackend) CompleteWorkflowTask(
 	for _, e := range executedEvents {
 		switch e.Type {
 		case history.EventType_ActivityScheduled:
-			if err := scheduleActivity(ctx, tx, instance.GetInstanceID(), instance.GetExecutionID(), e); err != nil {
+			if err := b.scheduleActivity(ctx, tx, instance.GetInstanceID(), instance.GetExecutionID(), e); err != nil {
 				return errors.Wrap(err, "could not schedule activity")
 			}
 
@@ -441,12 +551,12 @@ func (b *mysqlBackend) CompleteWorkflowTask(
 	for targetInstance, events := range groupedEvents {
 		if targetInstance.GetInstanceID() != instance.GetInstanceID() {
 			// Create new instance
-			if err := createInstance(ctx, tx, targetInstance); err != nil {
+			if err := createInstance(ctx, tx, targetInstance, backend.InstancePriority(events...)); err != nil {
 				return err
 			}
 		}
 
-		if err := insertNewEvents(ctx, tx, targetInstance.GetInstanceID(), events); err != nil {
+		if err := b.insertNewEvents(ctx, tx, targetInstance.GetInstanceID(), events); err != nil {
 			return errors.Wrap(err, "could not insert messages")
 		}
 	}
@@ -467,10 +577,25 @@ func (b *mysqlBackend) CompleteWorkflowTask(
 		return err
 	}
 
+	if workflowCompleted && b.options.ArchiveOnCompletion {
+		b.ArchiveCompletedInstance(ctx, instance.GetInstanceID())
+	}
+
+	b.options.Logger.Debug("Completed workflow task",
+		log.InstanceIDKey, instance.GetInstanceID(),
+		log.ExecutionIDKey, instance.GetExecutionID(),
+		"executed_events", len(executedEvents),
+		"workflow_events", len(workflowEvents),
+	)
+
+	b.NotifyCompletedWorkflowTask(executedEvents, workflowEvents)
+
 	return nil
 }
 
-func (b *mysqlBackend) ExtendWorkflowTask(ctx context.Context, instance workflow.Instance) error {
+func (b *mysqlBackend) ExtendWorkflowTask(ctx context.Context, instance workflow.Instance) (err error) {
+	defer func() { b.recordLockExtension(metrickeys.TypeWorkflow, err) }()
+
 	tx, err := b.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
@@ -501,6 +626,8 @@ func (b *mysqlBackend) ExtendWorkflowTask(ctx context.Context, instance workflow
 
 // GetActivityTask returns a pending activity task or nil if there are no pending activities
 func (b *mysqlBackend) GetActivityTask(ctx context.Context) (*task.Activity, error) {
+	b.queueDepth.Report(ctx)
+
 	tx, err := b.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -508,12 +635,18 @@ func (b *mysqlBackend) GetActivityTask(ctx context.Context) (*task.Activity, err
 	defer tx.Rollback()
 
 	// Lock next activity
+	orderBy := "id"
+	if b.activityFairness.HonorPriority() {
+		orderBy = "priority DESC, id"
+	}
+
 	now := time.Now()
 	res := tx.QueryRowContext(
 		ctx,
 		`SELECT id, activity_id, instance_id, execution_id, event_type, timestamp, schedule_event_id, attributes, visible_at
 			FROM activities
 			WHERE locked_until IS NULL OR locked_until < ?
+			ORDER BY `+orderBy+`
 			LIMIT 1
 			FOR UPDATE SKIP LOCKED`,
 		now,
@@ -559,6 +692,13 @@ func (b *mysqlBackend) GetActivityTask(ctx context.Context) (*task.Activity, err
 		return nil, err
 	}
 
+	b.options.Logger.Debug("Locked activity task",
+		log.InstanceIDKey, instanceID,
+		log.ExecutionIDKey, executionID,
+		log.ActivityIDKey, event.ID,
+		log.ScheduleEventIDKey, event.ScheduleEventID,
+	)
+
 	return t, nil
 }
 
@@ -592,7 +732,7 @@ func (b *mysqlBackend) CompleteActivityTask(ctx context.Context, instance workfl
 	}
 
 	// Insert new event generated during this workflow execution
-	if err := insertNewEvents(ctx, tx, instance.GetInstanceID(), []history.Event{event}); err != nil {
+	if err := b.insertNewEvents(ctx, tx, instance.GetInstanceID(), []history.Event{event}); err != nil {
 		return errors.Wrap(err, "could not insert new events for completed activity")
 	}
 
@@ -600,10 +740,21 @@ func (b *mysqlBackend) CompleteActivityTask(ctx context.Context, instance workfl
 		return err
 	}
 
+	b.options.Logger.Debug("Completed activity task",
+		log.InstanceIDKey, instance.GetInstanceID(),
+		log.ExecutionIDKey, instance.GetExecutionID(),
+		log.ActivityIDKey, id,
+		log.EventIDKey, event.ID,
+	)
+
+	b.NotifyWorkflowTasks()
+
 	return nil
 }
 
-func (b *mysqlBackend) ExtendActivityTask(ctx context.Context, activityID string) error {
+func (b *mysqlBackend) ExtendActivityTask(ctx context.Context, activityID string) (err error) {
+	defer func() { b.recordLockExtension(metrickeys.TypeActivity, err) }()
+
 	tx, err := b.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
@@ -631,8 +782,8 @@ func (b *mysqlBackend) ExtendActivityTask(ctx context.Context, activityID string
 	return tx.Commit()
 }
 
-func scheduleActivity(ctx context.Context, tx *sql.Tx, instanceID, executionID string, event history.Event) error {
-	a, err := history.SerializeAttributes(event.Attributes)
+func (b *mysqlBackend) scheduleActivity(ctx context.Context, tx *sql.Tx, instanceID, executionID string, event history.Event) error {
+	a, err := b.serializeAttributes(event.Attributes)
 	if err != nil {
 		return err
 	}
@@ -640,7 +791,7 @@ func scheduleActivity(ctx context.Context, tx *sql.Tx, instanceID, executionID s
 	_, err = tx.ExecContext(
 		ctx,
 		`INSERT INTO activities
-			(activity_id, instance_id, execution_id, event_type, timestamp, schedule_event_id, attributes, visible_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			(activity_id, instance_id, execution_id, event_type, timestamp, schedule_event_id, attributes, visible_at, priority) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		event.ID,
 		instanceID,
 		executionID,
@@ -649,6 +800,7 @@ func scheduleActivity(ctx context.Context, tx *sql.Tx, instanceID, executionID s
 		event.ScheduleEventID,
 		a,
 		event.VisibleAt,
+		backend.ActivityPriority(event),
 	)
 
 	return err
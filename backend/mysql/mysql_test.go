@@ -10,6 +10,7 @@ import (
 	"github.com/cschleiden/go-workflows/backend/test"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
 )
 
 const testUser = "root"
@@ -26,7 +27,7 @@ func Test_MysqlBackend(t *testing.T) {
 	dbName := "test_" + strings.Replace(uuid.NewString(), "-", "", -1)
 
 	test.TestBackend(t, test.Tester{
-		New: func() backend.Backend {
+		New: func(options ...backend.BackendOption) backend.Backend {
 			db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@/?parseTime=true&interpolateParams=true", testUser, testPassword))
 			if err != nil {
 				panic(err)
@@ -40,7 +41,7 @@ func Test_MysqlBackend(t *testing.T) {
 				panic(err)
 			}
 
-			return NewMysqlBackend("localhost", 3306, testUser, testPassword, dbName, backend.WithStickyTimeout(0))
+			return NewMysqlBackend("localhost", 3306, testUser, testPassword, dbName, append([]backend.BackendOption{backend.WithStickyTimeout(0)}, options...)...)
 		},
 
 		Teardown: func() {
@@ -59,3 +60,9 @@ func Test_MysqlBackend(t *testing.T) {
 		},
 	})
 }
+
+func Test_NewMysqlBackendFromDSN_ReturnsErrorForInvalidDSN(t *testing.T) {
+	b, err := NewMysqlBackendFromDSN("invalid")
+	require.Error(t, err)
+	require.Nil(t, b)
+}
@@ -0,0 +1,36 @@
+package mysql
+
+import "github.com/cschleiden/go-workflows/internal/sqlbackend"
+
+// The start time of an instance is when its pending WorkflowExecutionStarted event becomes visible
+const instanceInfoQuery = "SELECT i.instance_id, i.execution_id, i.parent_instance_id, p.execution_id, i.parent_schedule_event_id, i.created_at, i.completed_at, pe.visible_at " +
+	"FROM `instances` i LEFT JOIN `instances` p ON p.instance_id = i.parent_instance_id LEFT JOIN `pending_events` pe ON pe.instance_id = i.instance_id AND pe.event_type = ?"
+
+var queries = sqlbackend.Queries{
+	In:         sqlbackend.InList,
+	GetHistory: getHistory,
+
+	DescribeInstance: instanceInfoQuery + " WHERE i.instance_id = ? AND i.execution_id = ?",
+	ListInstances:    instanceInfoQuery + " WHERE i.instance_id > ? ORDER BY i.instance_id LIMIT ?",
+	ScheduledStart:   "SELECT visible_at FROM `pending_events` WHERE instance_id = ? AND event_type = ? AND visible_at > ?",
+
+	CompletedAt:        "SELECT completed_at FROM `instances` WHERE instance_id = ? AND execution_id = ?",
+	ExpiredInstances:   "SELECT instance_id FROM `instances` WHERE completed_at < ? LIMIT ?",
+	CompletedInstances: "SELECT instance_id FROM `instances` WHERE instance_id %s AND completed_at IS NOT NULL FOR UPDATE SKIP LOCKED",
+	RemoveInstances: []string{
+		"DELETE FROM `history` WHERE instance_id %s",
+		"DELETE FROM `pending_events` WHERE instance_id %s",
+		"DELETE FROM `activities` WHERE instance_id %s",
+		"DELETE FROM `instances` WHERE instance_id %s",
+	},
+	ArchivedInstance: "SELECT execution_id, parent_instance_id, completed_at FROM `instances` WHERE instance_id = ?",
+	ParentInstance:   "SELECT parent_instance_id FROM `instances` WHERE instance_id = ?",
+
+	InsertSchedule: "INSERT IGNORE INTO `schedules` (id, version, due_at, data) VALUES (?, ?, ?, ?)",
+	GetSchedule:    "SELECT version, data FROM `schedules` WHERE id = ?",
+	ListSchedules:  "SELECT version, data FROM `schedules` ORDER BY id",
+	DueSchedules:   "SELECT version, data FROM `schedules` WHERE due_at IS NOT NULL AND due_at <= ? ORDER BY due_at LIMIT ?",
+	UpdateSchedule: "UPDATE `schedules` SET version = version + 1, due_at = ?, data = ? WHERE id = ? AND version = ?",
+	ScheduleExists: "SELECT 1 FROM `schedules` WHERE id = ?",
+	DeleteSchedule: "DELETE FROM `schedules` WHERE id = ?",
+}
@@ -0,0 +1,100 @@
+package backend
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+// Notifications notifies pollers in the same process when new tasks might be available.
+// Backends can embed it to implement Notifier and ScheduleNotifier, and call NotifyWorkflowTasks
+// or NotifyActivityTasks after inserting pending events or activities, and NotifySchedules after
+// storing a schedule.
+type Notifications struct {
+	mu       sync.Mutex
+	workflow chan struct{}
+	activity chan struct{}
+	schedule chan struct{}
+}
+
+var (
+	_ Notifier         = (*Notifications)(nil)
+	_ ScheduleNotifier = (*Notifications)(nil)
+)
+
+func NewNotifications() *Notifications {
+	return &Notifications{
+		workflow: make(chan struct{}),
+		activity: make(chan struct{}),
+		schedule: make(chan struct{}),
+	}
+}
+
+func (n *Notifications) WorkflowTasksNotification() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.workflow
+}
+
+func (n *Notifications) ActivityTasksNotification() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.activity
+}
+
+func (n *Notifications) SchedulesNotification() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.schedule
+}
+
+// NotifyWorkflowTasks wakes up all pollers waiting for workflow tasks
+func (n *Notifications) NotifyWorkflowTasks() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	close(n.workflow)
+	n.workflow = make(chan struct{})
+}
+
+// NotifyActivityTasks wakes up all pollers waiting for activity tasks
+func (n *Notifications) NotifyActivityTasks() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	close(n.activity)
+	n.activity = make(chan struct{})
+}
+
+// NotifySchedules wakes up all pollers waiting for due schedules
+func (n *Notifications) NotifySchedules() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	close(n.schedule)
+	n.schedule = make(chan struct{})
+}
+
+// NotifyCompletedWorkflowTask notifies pollers about the tasks created by completing a workflow
+// task with the given events. Events that only become visible in the future, like fired timers,
+// don't cause a notification.
+func (n *Notifications) NotifyCompletedWorkflowTask(executedEvents []history.Event, workflowEvents []history.WorkflowEvent) {
+	for _, e := range executedEvents {
+		if e.Type == history.EventType_ActivityScheduled {
+			n.NotifyActivityTasks()
+			break
+		}
+	}
+
+	now := time.Now()
+	for _, e := range workflowEvents {
+		if e.HistoryEvent.VisibleAt == nil || !e.HistoryEvent.VisibleAt.After(now) {
+			n.NotifyWorkflowTasks()
+			break
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Notifications_WakesAllWaiters(t *testing.T) {
+	n := NewNotifications()
+
+	c1 := n.WorkflowTasksNotification()
+	c2 := n.WorkflowTasksNotification()
+	a := n.ActivityTasksNotification()
+
+	n.NotifyWorkflowTasks()
+
+	require.True(t, closed(c1))
+	require.True(t, closed(c2))
+	require.False(t, closed(a))
+
+	// Subsequent waiters wait for the next notification
+	require.False(t, closed(n.WorkflowTasksNotification()))
+}
+
+func Test_Notifications_CompletedWorkflowTask_IgnoresFutureEvents(t *testing.T) {
+	n := NewNotifications()
+
+	c := n.WorkflowTasksNotification()
+
+	n.NotifyCompletedWorkflowTask(nil, []history.WorkflowEvent{
+		{
+			WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+			HistoryEvent: history.NewHistoryEvent(
+				time.Now(),
+				history.EventType_TimerFired,
+				&history.TimerFiredAttributes{},
+				history.VisibleAt(time.Now().Add(time.Hour)),
+			),
+		},
+	})
+
+	require.False(t, closed(c))
+}
+
+func closed(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
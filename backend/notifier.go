@@ -0,0 +1,22 @@
+package backend
+
+// Notifier is an optional interface for backends that can notify workers when new tasks might
+// be available. Between polls, workers wait for a notification instead of only waiting for the
+// poll interval to expire, so idle workers don't have to query the backend frequently.
+type Notifier interface {
+	// WorkflowTasksNotification returns a channel that is closed the next time new workflow tasks
+	// might be available
+	WorkflowTasksNotification() <-chan struct{}
+
+	// ActivityTasksNotification returns a channel that is closed the next time new activity tasks
+	// might be available
+	ActivityTasksNotification() <-chan struct{}
+}
+
+// ScheduleNotifier is an optional interface for backends that can notify workers when schedules
+// were created or changed, so that they are processed without waiting for the poll interval.
+type ScheduleNotifier interface {
+	// SchedulesNotification returns a channel that is closed the next time a schedule is created
+	// or updated
+	SchedulesNotification() <-chan struct{}
+}
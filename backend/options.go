@@ -1,19 +1,104 @@
 package backend
 
-import "time"
+import (
+	"database/sql"
+	"time"
+
+	"github.com/cschleiden/go-workflows/archive"
+	"github.com/cschleiden/go-workflows/blob"
+	"github.com/cschleiden/go-workflows/compression"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/metrics"
+)
 
 type Options struct {
+	Logger log.Logger
+
+	Metrics metrics.Client
+
 	StickyTimeout time.Duration
 
 	WorkflowLockTimeout time.Duration
 
 	ActivityLockTimeout time.Duration
+
+	// ApplyMigrations determines if backends implementing Migrator apply pending schema migrations
+	// when they are created
+	ApplyMigrations bool
+
+	// RetentionPeriod is the time after which completed workflow instances and their history are
+	// removed. Removal is supported by the SQL backends and runs in the background. If zero,
+	// completed instances are kept forever.
+	RetentionPeriod time.Duration
+
+	// Archiver stores the history of completed workflow instances before SQL backends remove
+	// them, either through retention or RemoveWorkflowInstance. If storing fails, the instance is
+	// not removed.
+	Archiver archive.Sink
+
+	// ArchiveOnCompletion additionally archives instances as soon as they complete
+	ArchiveOnCompletion bool
+
+	// BlobStore is the store that payloads are offloaded to. If set, SQL backends delete the
+	// blobs referenced by a workflow instance when they remove it.
+	BlobStore blob.Store
+
+	// Compression is the algorithm used by SQL backends to compress serialized event attributes
+	// of at least CompressionThreshold bytes. Compressed and uncompressed attributes can be read
+	// regardless of this setting.
+	Compression compression.Algorithm
+
+	CompressionThreshold int
+
+	// PriorityFairness is the number of task polls after which a poll ignores priorities and picks
+	// the task that has been available the longest, so that tasks with a low priority are not
+	// starved. If zero, priorities are always honored.
+	PriorityFairness int
+
+	// ConnectionPool configures the connection pool of SQL backends that open the database
+	// themselves. It's ignored for backends created from an existing *sql.DB.
+	ConnectionPool ConnectionPoolOptions
+}
+
+// ConnectionPoolOptions configure a database/sql connection pool. Zero values keep the
+// database/sql defaults.
+type ConnectionPoolOptions struct {
+	MaxOpenConns int
+
+	MaxIdleConns int
+
+	ConnMaxLifetime time.Duration
+
+	ConnMaxIdleTime time.Duration
+}
+
+// Apply configures the connection pool of db
+func (o ConnectionPoolOptions) Apply(db *sql.DB) {
+	if o.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(o.MaxOpenConns)
+	}
+
+	if o.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(o.MaxIdleConns)
+	}
+
+	if o.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(o.ConnMaxLifetime)
+	}
+
+	if o.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(o.ConnMaxIdleTime)
+	}
 }
 
 var DefaultOptions Options = Options{
+	Logger:              log.NewDefaultLogger(),
+	Metrics:             metrics.NewNoopMetricsClient(),
 	StickyTimeout:       30 * time.Second,
 	WorkflowLockTimeout: time.Minute,
 	ActivityLockTimeout: time.Minute * 2,
+	ApplyMigrations:     true,
+	PriorityFairness:    10,
 }
 
 type BackendOption func(*Options)
@@ -24,6 +109,82 @@ func WithStickyTimeout(timeout time.Duration) BackendOption {
 	}
 }
 
+// WithLogger sets the logger used by the backend
+func WithLogger(logger log.Logger) BackendOption {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// WithMetrics sets the client used to record backend metrics like lock extensions and queue depth
+func WithMetrics(client metrics.Client) BackendOption {
+	return func(o *Options) {
+		o.Metrics = client
+	}
+}
+
+// WithApplyMigrations sets whether pending schema migrations are applied when the backend is
+// created. If disabled, migrations have to be applied using Migrator.
+func WithApplyMigrations(apply bool) BackendOption {
+	return func(o *Options) {
+		o.ApplyMigrations = apply
+	}
+}
+
+// WithRetentionPeriod sets the time after which completed workflow instances are removed
+func WithRetentionPeriod(period time.Duration) BackendOption {
+	return func(o *Options) {
+		o.RetentionPeriod = period
+	}
+}
+
+// WithArchiver sets the sink used to archive completed workflow instances before they are removed
+func WithArchiver(sink archive.Sink) BackendOption {
+	return func(o *Options) {
+		o.Archiver = sink
+	}
+}
+
+// WithArchiveOnCompletion sets whether workflow instances are archived as soon as they complete.
+// Requires an archiver to be configured.
+func WithArchiveOnCompletion(archiveOnCompletion bool) BackendOption {
+	return func(o *Options) {
+		o.ArchiveOnCompletion = archiveOnCompletion
+	}
+}
+
+// WithBlobStore sets the store payloads are offloaded to, so that blobs are removed together with
+// the workflow instances referencing them
+func WithBlobStore(store blob.Store) BackendOption {
+	return func(o *Options) {
+		o.BlobStore = store
+	}
+}
+
+// WithCompression sets the algorithm used to compress event attributes that are at least threshold
+// bytes long when serialized
+func WithCompression(algorithm compression.Algorithm, threshold int) BackendOption {
+	return func(o *Options) {
+		o.Compression = algorithm
+		o.CompressionThreshold = threshold
+	}
+}
+
+// WithConnectionPool configures the connection pool of SQL backends opening their own database
+func WithConnectionPool(pool ConnectionPoolOptions) BackendOption {
+	return func(o *Options) {
+		o.ConnectionPool = pool
+	}
+}
+
+// WithPriorityFairness sets the number of task polls after which a poll ignores priorities and
+// picks the task that has been available the longest instead
+func WithPriorityFairness(n int) BackendOption {
+	return func(o *Options) {
+		o.PriorityFairness = n
+	}
+}
+
 func ApplyOptions(opts ...BackendOption) Options {
 	options := DefaultOptions
 
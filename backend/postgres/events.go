@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/pkg/errors"
+)
+
+func (b *postgresBackend) serializeAttributes(attributes interface{}) ([]byte, error) {
+	return history.SerializeAttributes(attributes, history.WithCompression(b.options.Compression, b.options.CompressionThreshold))
+}
+
+func (b *postgresBackend) insertNewEvents(ctx context.Context, tx *sql.Tx, instanceID string, newEvents []history.Event) error {
+	return b.insertEvents(ctx, tx, "pending_events", instanceID, newEvents)
+}
+
+func (b *postgresBackend) insertHistoryEvents(ctx context.Context, tx *sql.Tx, instanceID string, historyEvents []history.Event) error {
+	return b.insertEvents(ctx, tx, "history", instanceID, historyEvents)
+}
+
+func (b *postgresBackend) insertEvents(ctx context.Context, tx *sql.Tx, tableName string, instanceID string, events []history.Event) error {
+	const batchSize = 20
+	const columns = 7
+	for batchStart := 0; batchStart < len(events); batchStart += batchSize {
+		batchEnd := batchStart + batchSize
+		if batchEnd > len(events) {
+			batchEnd = len(events)
+		}
+		batchEvents := events[batchStart:batchEnd]
+
+		values := make([]string, 0, len(batchEvents))
+		args := make([]interface{}, 0, len(batchEvents)*columns)
+
+		for i, newEvent := range batchEvents {
+			a, err := b.serializeAttributes(newEvent.Attributes)
+			if err != nil {
+				return err
+			}
+
+			n := i * columns
+			values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7))
+			args = append(args, newEvent.ID, instanceID, newEvent.Type, newEvent.Timestamp, newEvent.ScheduleEventID, a, newEvent.VisibleAt)
+		}
+
+		query := "INSERT INTO " + tableName + ` (event_id, instance_id, event_type, "timestamp", schedule_event_id, attributes, visible_at) VALUES ` +
+			strings.Join(values, ", ")
+
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			args...,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getHistory(ctx context.Context, tx *sql.Tx, instanceID string) ([]history.Event, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT event_id, instance_id, event_type, "timestamp", schedule_event_id, attributes, visible_at FROM history WHERE instance_id = $1 ORDER BY id`,
+		instanceID,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get history")
+	}
+	defer rows.Close()
+
+	events := make([]history.Event, 0)
+
+	for rows.Next() {
+		var instanceID string
+		var attributes []byte
+
+		historyEvent := history.Event{}
+
+		if err := rows.Scan(
+			&historyEvent.ID,
+			&instanceID,
+			&historyEvent.Type,
+			&historyEvent.Timestamp,
+			&historyEvent.ScheduleEventID,
+			&attributes,
+			&historyEvent.VisibleAt,
+		); err != nil {
+			return nil, errors.Wrap(err, "could not scan event")
+		}
+
+		a, err := history.DeserializeAttributes(historyEvent.Type, attributes)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not deserialize attributes")
+		}
+
+		historyEvent.Attributes = a
+
+		events = append(events, historyEvent)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+
+	"github.com/cschleiden/go-workflows/internal/migrations"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migrationLockKey identifies the advisory lock held while migrating
+const migrationLockKey = 7429103
+
+// Migrate applies all pending schema migrations
+func (b *postgresBackend) Migrate(ctx context.Context) error {
+	m, err := migrations.Load(migrationsFS, "migrations")
+	if err != nil {
+		return err
+	}
+
+	return migrations.Migrate(ctx, b.db, dialect{}, m)
+}
+
+// dialect runs all migrations in a single transaction, holding an advisory lock until it
+// completes. A failed migration is rolled back completely.
+type dialect struct{}
+
+func (dialect) Lock(ctx context.Context, conn *sql.Conn) error {
+	if _, err := conn.ExecContext(ctx, "BEGIN"); err != nil {
+		return err
+	}
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockKey); err != nil {
+		conn.ExecContext(ctx, "ROLLBACK")
+		return err
+	}
+
+	return nil
+}
+
+func (dialect) Unlock(ctx context.Context, conn *sql.Conn, failed bool) error {
+	if failed {
+		_, err := conn.ExecContext(ctx, "ROLLBACK")
+		return err
+	}
+
+	_, err := conn.ExecContext(ctx, "COMMIT")
+	return err
+}
+
+func (dialect) CreateVersionTable() string {
+	return "CREATE TABLE IF NOT EXISTS schema_version (version INT NOT NULL PRIMARY KEY, applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP)"
+}
+
+func (dialect) InsertVersion() string {
+	return "INSERT INTO schema_version (version) VALUES ($1)"
+}
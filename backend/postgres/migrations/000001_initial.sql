@@ -0,0 +1,62 @@
+CREATE TABLE IF NOT EXISTS instances (
+  id SERIAL PRIMARY KEY,
+  instance_id VARCHAR(128) NOT NULL,
+  execution_id VARCHAR(128) NOT NULL,
+  parent_instance_id VARCHAR(128) NULL,
+  parent_schedule_event_id INT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  completed_at TIMESTAMPTZ NULL,
+  locked_until TIMESTAMPTZ NULL,
+  sticky_until TIMESTAMPTZ NULL,
+  worker VARCHAR(64) NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_instances_instance_id ON instances (instance_id);
+CREATE INDEX IF NOT EXISTS idx_instances_locked_until_completed_at ON instances (locked_until, sticky_until, completed_at, worker);
+CREATE INDEX IF NOT EXISTS idx_instances_parent_instance_id ON instances (parent_instance_id);
+
+
+CREATE TABLE IF NOT EXISTS pending_events (
+  id SERIAL PRIMARY KEY,
+  event_id VARCHAR(128) NOT NULL,
+  instance_id VARCHAR(128) NOT NULL,
+  event_type INT NOT NULL,
+  "timestamp" TIMESTAMPTZ NOT NULL,
+  schedule_event_id INT NOT NULL,
+  attributes BYTEA NOT NULL,
+  visible_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_pending_events_instance_id ON pending_events (instance_id);
+
+
+CREATE TABLE IF NOT EXISTS history (
+  id SERIAL PRIMARY KEY,
+  event_id VARCHAR(64) NOT NULL,
+  instance_id VARCHAR(128) NOT NULL,
+  event_type INT NOT NULL,
+  "timestamp" TIMESTAMPTZ NOT NULL,
+  schedule_event_id INT NOT NULL,
+  attributes BYTEA NOT NULL,
+  visible_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_history_instance_id ON history (instance_id);
+
+
+CREATE TABLE IF NOT EXISTS activities (
+  id SERIAL PRIMARY KEY,
+  activity_id VARCHAR(64) NOT NULL,
+  instance_id VARCHAR(128) NOT NULL,
+  execution_id VARCHAR(128) NOT NULL,
+  event_type INT NOT NULL,
+  "timestamp" TIMESTAMPTZ NOT NULL,
+  schedule_event_id INT NOT NULL,
+  attributes BYTEA NOT NULL,
+  visible_at TIMESTAMPTZ NULL,
+  locked_until TIMESTAMPTZ NULL,
+  worker VARCHAR(64) NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_activities_instance_id ON activities (activity_id, instance_id, execution_id);
+CREATE INDEX IF NOT EXISTS idx_activities_locked_until ON activities (locked_until);
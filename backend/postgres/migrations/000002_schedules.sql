@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS schedules (
+  id VARCHAR(128) PRIMARY KEY,
+  version BIGINT NOT NULL,
+  due_at TIMESTAMPTZ NULL,
+  data BYTEA NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedules_due_at ON schedules (due_at);
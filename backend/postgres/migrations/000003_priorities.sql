@@ -0,0 +1,7 @@
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS priority INT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_instances_completed_at_priority_id ON instances (completed_at, priority DESC, id);
+
+ALTER TABLE activities ADD COLUMN IF NOT EXISTS priority INT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_activities_priority_id ON activities (priority DESC, id);
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// Channels used with LISTEN/NOTIFY to wake up pollers in other processes when new tasks are
+// available.
+const (
+	workflowTasksChannel = "workflows_workflow_tasks"
+	activityTasksChannel = "workflows_activity_tasks"
+)
+
+// listenerPingInterval is the interval in which the listener connection is checked if there
+// haven't been any notifications.
+const listenerPingInterval = 90 * time.Second
+
+func (b *postgresBackend) listen(dsn string) error {
+	b.listener = pq.NewListener(dsn, 10*time.Millisecond, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			b.options.Logger.Warning("Postgres listener error", "event", event, "error", err)
+		}
+	})
+
+	for _, channel := range []string{workflowTasksChannel, activityTasksChannel} {
+		if err := b.listener.Listen(channel); err != nil {
+			b.listener.Close()
+			return errors.Wrapf(err, "could not listen on channel %v", channel)
+		}
+	}
+
+	b.done = make(chan struct{})
+	go b.handleNotifications()
+
+	return nil
+}
+
+// handleNotifications forwards notifications received from the database to pollers in this process.
+func (b *postgresBackend) handleNotifications() {
+	defer close(b.done)
+
+	for {
+		select {
+		case n, ok := <-b.listener.Notify:
+			if !ok {
+				return
+			}
+
+			if n == nil {
+				// Connection was re-established, notifications might have been lost
+				b.NotifyWorkflowTasks()
+				b.NotifyActivityTasks()
+				continue
+			}
+
+			// Pollers in this process have already been notified by the sender
+			if n.Extra == b.workerName {
+				continue
+			}
+
+			switch n.Channel {
+			case workflowTasksChannel:
+				b.NotifyWorkflowTasks()
+			case activityTasksChannel:
+				b.NotifyActivityTasks()
+			}
+
+		case <-time.After(listenerPingInterval):
+			go b.listener.Ping()
+		}
+	}
+}
+
+// notify sends a notification on the given channel. Notifications sent in a transaction are
+// only delivered when the transaction commits.
+func (b *postgresBackend) notify(ctx context.Context, tx *sql.Tx, channel string) error {
+	if _, err := tx.ExecContext(ctx, "SELECT pg_notify($1, $2)", channel, b.workerName); err != nil {
+		return errors.Wrap(err, "could not send notification")
+	}
+
+	return nil
+}
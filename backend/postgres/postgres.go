@@ -0,0 +1,854 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/internal/metrickeys"
+	"github.com/cschleiden/go-workflows/internal/retention"
+	"github.com/cschleiden/go-workflows/internal/sqlbackend"
+	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// NewPostgresBackend creates a backend using the given PostgreSQL database. Pollers are woken up
+// using LISTEN/NOTIFY when other processes sharing the database create new tasks.
+func NewPostgresBackend(host string, port int, user, password, database string, opts ...backend.BackendOption) backend.Backend {
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, database)
+
+	b, err := NewPostgresBackendFromDSN(dsn, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
+
+// NewPostgresBackendFromDSN creates a backend using the PostgreSQL database identified by dsn,
+// either as a URL or as key/value pairs as supported by github.com/lib/pq. The connection pool
+// can be configured using backend.WithConnectionPool.
+func NewPostgresBackendFromDSN(dsn string, opts ...backend.BackendOption) (backend.Backend, error) {
+	options := backend.ApplyOptions(opts...)
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open database")
+	}
+
+	options.ConnectionPool.Apply(db)
+
+	b, err := newPostgresBackend(db, options)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	b.ownsDB = true
+
+	if err := b.listen(dsn); err != nil {
+		b.stopRetention()
+		db.Close()
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// NewPostgresBackendFromDB creates a backend using an existing database. The caller remains
+// responsible for configuring and closing db. LISTEN requires a dedicated connection, so
+// pollers are only notified about tasks created in this process.
+func NewPostgresBackendFromDB(db *sql.DB, opts ...backend.BackendOption) (backend.Backend, error) {
+	b, err := newPostgresBackend(db, backend.ApplyOptions(opts...))
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func newPostgresBackend(db *sql.DB, options backend.Options) (*postgresBackend, error) {
+	b := &postgresBackend{
+		db:         db,
+		workerName: fmt.Sprintf("worker-%v", uuid.NewString()),
+		options:    options,
+
+		workflowFairness: backend.NewPriorityFairness(options.PriorityFairness),
+		activityFairness: backend.NewPriorityFairness(options.PriorityFairness),
+
+		Notifications: backend.NewNotifications(),
+	}
+
+	b.Store = sqlbackend.New(db, queries, options, b.Notifications)
+	b.queueDepth = backend.NewQueueDepth(options.Metrics, b.measureQueueDepth)
+
+	if options.ApplyMigrations {
+		if err := b.Migrate(context.Background()); err != nil {
+			return nil, errors.Wrap(err, "could not migrate database")
+		}
+	}
+
+	b.stopRetention = retention.Start(clock.New(), options.RetentionPeriod, retention.Interval, options.Logger, b.PurgeCompletedInstances)
+
+	return b, nil
+}
+
+type postgresBackend struct {
+	db         *sql.DB
+	ownsDB     bool
+	workerName string
+	options    backend.Options
+
+	*backend.Notifications
+	*sqlbackend.Store
+	listener *pq.Listener
+	done     chan struct{}
+
+	stopRetention func()
+
+	workflowFairness *backend.PriorityFairness
+	activityFairness *backend.PriorityFairness
+
+	queueDepth *backend.QueueDepth
+}
+
+// Close stops listening for notifications and the background cleanup of completed instances,
+// and closes the database, if it was opened by the backend
+func (b *postgresBackend) Close() error {
+	b.stopRetention()
+
+	if b.listener != nil {
+		if err := b.listener.Close(); err != nil {
+			return errors.Wrap(err, "could not close listener")
+		}
+
+		<-b.done
+	}
+
+	if !b.ownsDB {
+		return nil
+	}
+
+	return b.db.Close()
+}
+
+// CreateWorkflowInstance creates a new workflow instance
+func (b *postgresBackend) CreateWorkflowInstance(ctx context.Context, m history.WorkflowEvent) error {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "could not start transaction")
+	}
+	defer tx.Rollback()
+
+	// Create workflow instance
+	if err := createInstance(ctx, tx, m.WorkflowInstance, backend.InstancePriority(m.HistoryEvent)); err != nil {
+		return err
+	}
+
+	// Initial history is empty, store only new events
+	if err := b.insertNewEvents(ctx, tx, m.WorkflowInstance.GetInstanceID(), []history.Event{m.HistoryEvent}); err != nil {
+		return errors.Wrap(err, "could not insert new event")
+	}
+
+	if err := b.notify(ctx, tx, workflowTasksChannel); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "could not create workflow instance")
+	}
+
+	b.NotifyWorkflowTasks()
+
+	return nil
+}
+
+func (b *postgresBackend) CancelWorkflowInstance(ctx context.Context, instance workflow.Instance) error {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	instanceID := instance.GetInstanceID()
+
+	startAt, err := b.ScheduledStart(ctx, tx, instanceID)
+	if err != nil {
+		return err
+	}
+
+	// Cancel workflow instance
+	events := []history.Event{history.NewWorkflowCancellationEvent(time.Now())}
+	backend.DelayUntilStart(events, startAt)
+	if err := b.insertNewEvents(ctx, tx, instanceID, events); err != nil {
+		return errors.Wrap(err, "could not insert cancellation event")
+	}
+
+	// Recursively, find any sub-workflow instance to cancel
+	for {
+		row := tx.QueryRowContext(ctx, "SELECT instance_id FROM instances WHERE parent_instance_id = $1 AND completed_at IS NULL LIMIT 1", instanceID)
+
+		var subWorkflowInstanceID string
+		if err := row.Scan(&subWorkflowInstanceID); err != nil {
+			if err == sql.ErrNoRows {
+				// No more sub-workflow instances to cancel
+				break
+			}
+
+			return errors.Wrap(err, "could not get workflow instance for cancelling")
+		}
+
+		// Cancel sub-workflow instance
+		if err := b.insertNewEvents(ctx, tx, subWorkflowInstanceID, []history.Event{history.NewWorkflowCancellationEvent(time.Now())}); err != nil {
+			return errors.Wrap(err, "could not insert cancellation event")
+		}
+
+		instanceID = subWorkflowInstanceID
+	}
+
+	if err := b.notify(ctx, tx, workflowTasksChannel); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	b.NotifyWorkflowTasks()
+
+	return nil
+}
+
+func createInstance(ctx context.Context, tx *sql.Tx, wfi workflow.Instance, priority int) error {
+	var parentInstanceID *string
+	var parentEventID *int
+	if wfi.SubWorkflow() {
+		i := wfi.ParentInstance().GetInstanceID()
+		parentInstanceID = &i
+
+		n := wfi.ParentEventID()
+		parentEventID = &n
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO instances (instance_id, execution_id, parent_instance_id, parent_schedule_event_id, priority) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (instance_id) DO NOTHING",
+		wfi.GetInstanceID(),
+		wfi.GetExecutionID(),
+		parentInstanceID,
+		parentEventID,
+		priority,
+	); err != nil {
+		return errors.Wrap(err, "could not insert workflow instance")
+	}
+
+	return nil
+}
+
+// SignalWorkflow signals a running workflow instance
+func (b *postgresBackend) GetWorkflowInstanceHistory(ctx context.Context, instance workflow.Instance) ([]history.Event, error) {
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRowContext(ctx, "SELECT 1 FROM instances WHERE instance_id = $1 AND execution_id = $2", instance.GetInstanceID(), instance.GetExecutionID()).Scan(&exists); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, backend.ErrInstanceNotFound
+		}
+
+		return nil, errors.Wrap(err, "could not get workflow instance")
+	}
+
+	return getHistory(ctx, tx, instance.GetInstanceID())
+}
+
+func (b *postgresBackend) SignalWorkflow(ctx context.Context, instanceID string, event history.Event) error {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	startAt, err := b.ScheduledStart(ctx, tx, instanceID)
+	if err != nil {
+		return err
+	}
+
+	events := []history.Event{event}
+	backend.DelayUntilStart(events, startAt)
+	if err := b.insertNewEvents(ctx, tx, instanceID, events); err != nil {
+		return errors.Wrap(err, "could not insert signal event")
+	}
+
+	if err := b.notify(ctx, tx, workflowTasksChannel); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	b.NotifyWorkflowTasks()
+
+	return nil
+}
+
+// GetWorkflowInstance returns a pending workflow task or nil if there are no pending worflow executions
+func (b *postgresBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, error) {
+	b.queueDepth.Report(ctx)
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock next workflow task by finding an unlocked instance with new events to process.
+	// Polls ignoring priorities pick the instance whose earliest pending event became visible first
+	orderBy := `COALESCE(pe.visible_at, pe."timestamp"), i.id`
+	if b.workflowFairness.HonorPriority() {
+		orderBy = "i.priority DESC, i.id"
+	}
+
+	now := time.Now()
+	row := tx.QueryRowContext(
+		ctx,
+		`SELECT i.id, i.instance_id, i.execution_id, i.parent_instance_id, i.parent_schedule_event_id, i.sticky_until FROM instances i
+			INNER JOIN pending_events pe ON i.instance_id = pe.instance_id
+			WHERE
+				(i.locked_until IS NULL OR i.locked_until < $1)
+				AND (i.sticky_until IS NULL OR i.sticky_until < $2 OR i.worker = $3)
+				AND i.completed_at IS NULL
+				AND (pe.visible_at IS NULL OR pe.visible_at <= $4)
+			ORDER BY `+orderBy+`
+			LIMIT 1
+			FOR UPDATE OF i SKIP LOCKED`,
+		now,          // locked_until
+		now,          // sticky_until
+		b.workerName, // worker
+		now,          // event.visible_at
+	)
+
+	var id int
+	var instanceID, executionID string
+	var parentInstanceID *string
+	var parentEventID *int
+	var stickyUntil *time.Time
+	if err := row.Scan(&id, &instanceID, &executionID, &parentInstanceID, &parentEventID, &stickyUntil); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, errors.Wrap(err, "could not scan workflow instance")
+	}
+
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE instances
+			SET locked_until = $1, worker = $2
+			WHERE id = $3`,
+		now.Add(b.options.WorkflowLockTimeout),
+		b.workerName,
+		id,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not lock workflow instance")
+	}
+
+	if affectedRows, err := res.RowsAffected(); err != nil {
+		return nil, errors.Wrap(err, "could not lock workflow instance")
+	} else if affectedRows == 0 {
+		// No instance locked?
+		return nil, nil
+	}
+
+	// Check if this task is using a dedicated queue and should be returned as a continuation
+	var kind task.Kind
+	if stickyUntil != nil && stickyUntil.After(now) {
+		kind = task.Continuation
+	}
+
+	var wfi workflow.Instance
+	if parentInstanceID != nil {
+		wfi = core.NewSubWorkflowInstance(instanceID, executionID, core.NewWorkflowInstance(*parentInstanceID, ""), *parentEventID)
+	} else {
+		wfi = core.NewWorkflowInstance(instanceID, executionID)
+	}
+
+	t := &task.Workflow{
+		WorkflowInstance: wfi,
+		NewEvents:        []history.Event{},
+		History:          []history.Event{},
+		Kind:             kind,
+	}
+
+	// Get new events
+	events, err := tx.QueryContext(
+		ctx,
+		`SELECT event_id, instance_id, event_type, "timestamp", schedule_event_id, attributes, visible_at FROM pending_events WHERE instance_id = $1 AND (visible_at IS NULL OR visible_at <= $2) ORDER BY id`,
+		instanceID,
+		now,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get new events")
+	}
+
+	for events.Next() {
+		var instanceID string
+		var attributes []byte
+
+		historyEvent := history.Event{}
+
+		if err := events.Scan(&historyEvent.ID, &instanceID, &historyEvent.Type, &historyEvent.Timestamp, &historyEvent.ScheduleEventID, &attributes, &historyEvent.VisibleAt); err != nil {
+			return nil, errors.Wrap(err, "could not scan event")
+		}
+
+		a, err := history.DeserializeAttributes(historyEvent.Type, attributes)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not deserialize attributes")
+		}
+
+		historyEvent.Attributes = a
+
+		t.NewEvents = append(t.NewEvents, historyEvent)
+	}
+
+	// Return if there aren't any new events
+	if len(t.NewEvents) == 0 {
+		return nil, nil
+	}
+
+	// Get historyEvents
+	if kind != task.Continuation {
+		historyEvents, err := getHistory(ctx, tx, instanceID)
+		if err != nil {
+			return nil, err
+		}
+
+		t.History = historyEvents
+	} else {
+		// Get only most recent history event
+		row := tx.QueryRowContext(ctx, `SELECT event_id, instance_id, event_type, "timestamp", schedule_event_id, attributes, visible_at FROM history WHERE instance_id = $1 ORDER BY id DESC LIMIT 1`, instanceID)
+
+		var instanceID string
+		var attributes []byte
+
+		lastHistoryEvent := history.Event{}
+
+		if err := row.Scan(
+			&lastHistoryEvent.ID,
+			&instanceID,
+			&lastHistoryEvent.Type,
+			&lastHistoryEvent.Timestamp,
+			&lastHistoryEvent.ScheduleEventID,
+			&attributes,
+			&lastHistoryEvent.VisibleAt,
+		); err != nil {
+			return nil, errors.Wrap(err, "could not scan event")
+		}
+
+		a, err := history.DeserializeAttributes(lastHistoryEvent.Type, attributes)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not deserialize attributes")
+		}
+
+		lastHistoryEvent.Attributes = a
+
+		t.History = []history.Event{lastHistoryEvent}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	b.options.Logger.Debug("Locked workflow task",
+		log.InstanceIDKey, instanceID,
+		log.ExecutionIDKey, executionID,
+		"new_events", len(t.NewEvents),
+		"continuation", kind == task.Continuation,
+	)
+
+	return t, nil
+}
+
+// CompleteWorkflowTask completes a workflow task retrieved using GetWorkflowTask
+//
+// This checkpoints the execution. events are new events from the last workflow execution
+// which will be added to the workflow instance history. workflowEvents are new events for the
+// completed or other workflow instances.
+func (b *postgresBackend) CompleteWorkflowTask(
+	ctx context.Context,
+	instance workflow.Instance,
+	executedEvents []history.Event,
+	workflowEvents []history.WorkflowEvent,
+) error {
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Unlock instance, but keep it sticky to the current worker
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE instances SET locked_until = NULL, sticky_until = $1 WHERE instance_id = $2 AND execution_id = $3 AND worker = $4`,
+		time.Now().Add(b.options.StickyTimeout),
+		instance.GetInstanceID(),
+		instance.GetExecutionID(),
+		b.workerName,
+	)
+	if err != nil {
+		return errors.Wrap(err, "could not unlock instance")
+	}
+
+	changedRows, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "could not check for unlocked workflow instances")
+	} else if changedRows != 1 {
+		return errors.New("could not find workflow instance to unlock")
+	}
+
+	// Remove handled events from task
+	if len(executedEvents) > 0 {
+		eventIDs := make([]string, 0, len(executedEvents))
+		for _, e := range executedEvents {
+			eventIDs = append(eventIDs, e.ID)
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			`DELETE FROM pending_events WHERE instance_id = $1 AND event_id = ANY($2)`,
+			instance.GetInstanceID(),
+			pq.Array(eventIDs),
+		); err != nil {
+			return errors.Wrap(err, "could not delete handled new events")
+		}
+	}
+
+	// Insert new events generated during this workflow execution to the history
+	if err := b.insertHistoryEvents(ctx, tx, instance.GetInstanceID(), executedEvents); err != nil {
+		return errors.Wrap(err, "could not insert new history events")
+	}
+
+	workflowCompleted := false
+	activityScheduled := false
+
+	// Schedule activities
+	for _, e := range executedEvents {
+		switch e.Type {
+		case history.EventType_ActivityScheduled:
+			if err := b.scheduleActivity(ctx, tx, instance.GetInstanceID(), instance.GetExecutionID(), e); err != nil {
+				return errors.Wrap(err, "could not schedule activity")
+			}
+
+			activityScheduled = true
+
+		case history.EventType_WorkflowExecutionFinished:
+			workflowCompleted = true
+		}
+	}
+
+	// Insert new workflow events
+	now := time.Now()
+	workflowTasks := false
+	groupedEvents := make(map[workflow.Instance][]history.Event)
+	for _, m := range workflowEvents {
+		if m.HistoryEvent.VisibleAt == nil || !m.HistoryEvent.VisibleAt.After(now) {
+			workflowTasks = true
+		}
+
+		if _, ok := groupedEvents[m.WorkflowInstance]; !ok {
+			groupedEvents[m.WorkflowInstance] = []history.Event{}
+		}
+
+		groupedEvents[m.WorkflowInstance] = append(groupedEvents[m.WorkflowInstance], m.HistoryEvent)
+	}
+
+	for targetInstance, events := range groupedEvents {
+		if targetInstance.GetInstanceID() != instance.GetInstanceID() {
+			// Create new instance
+			if err := createInstance(ctx, tx, targetInstance, backend.InstancePriority(events...)); err != nil {
+				return err
+			}
+		}
+
+		if err := b.insertNewEvents(ctx, tx, targetInstance.GetInstanceID(), events); err != nil {
+			return errors.Wrap(err, "could not insert messages")
+		}
+	}
+
+	if workflowCompleted {
+		if _, err := tx.ExecContext(
+			ctx,
+			"UPDATE instances SET completed_at = $1 WHERE instance_id = $2 AND execution_id = $3",
+			time.Now(),
+			instance.GetInstanceID(),
+			instance.GetExecutionID(),
+		); err != nil {
+			return errors.Wrap(err, "could not mark instance as completed")
+		}
+	}
+
+	// Wake up pollers in other processes, pollers in this process are notified after the commit
+	if activityScheduled {
+		if err := b.notify(ctx, tx, activityTasksChannel); err != nil {
+			return err
+		}
+	}
+
+	if workflowTasks {
+		if err := b.notify(ctx, tx, workflowTasksChannel); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if workflowCompleted && b.options.ArchiveOnCompletion {
+		b.ArchiveCompletedInstance(ctx, instance.GetInstanceID())
+	}
+
+	b.options.Logger.Debug("Completed workflow task",
+		log.InstanceIDKey, instance.GetInstanceID(),
+		log.ExecutionIDKey, instance.GetExecutionID(),
+		"executed_events", len(executedEvents),
+		"workflow_events", len(workflowEvents),
+	)
+
+	b.NotifyCompletedWorkflowTask(executedEvents, workflowEvents)
+
+	return nil
+}
+
+func (b *postgresBackend) ExtendWorkflowTask(ctx context.Context, instance workflow.Instance) (err error) {
+	defer func() { b.recordLockExtension(metrickeys.TypeWorkflow, err) }()
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	until := time.Now().Add(b.options.WorkflowLockTimeout)
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE instances SET locked_until = $1 WHERE instance_id = $2 AND execution_id = $3 AND worker = $4`,
+		until,
+		instance.GetInstanceID(),
+		instance.GetExecutionID(),
+		b.workerName,
+	)
+	if err != nil {
+		return errors.Wrap(err, "could not extend workflow task lock")
+	}
+
+	if rowsAffected, err := res.RowsAffected(); err != nil {
+		return errors.Wrap(err, "could not determine if workflow task was extended")
+	} else if rowsAffected == 0 {
+		return errors.New("could not extend workflow task")
+	}
+
+	return tx.Commit()
+}
+
+// GetActivityTask returns a pending activity task or nil if there are no pending activities
+func (b *postgresBackend) GetActivityTask(ctx context.Context) (*task.Activity, error) {
+	b.queueDepth.Report(ctx)
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock next activity
+	orderBy := "id"
+	if b.activityFairness.HonorPriority() {
+		orderBy = "priority DESC, id"
+	}
+
+	now := time.Now()
+	res := tx.QueryRowContext(
+		ctx,
+		`SELECT id, activity_id, instance_id, execution_id, event_type, "timestamp", schedule_event_id, attributes, visible_at
+			FROM activities
+			WHERE locked_until IS NULL OR locked_until < $1
+			ORDER BY `+orderBy+`
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED`,
+		now,
+	)
+
+	var id int
+	var instanceID, executionID string
+	var attributes []byte
+	event := history.Event{}
+
+	if err := res.Scan(&id, &event.ID, &instanceID, &executionID, &event.Type, &event.Timestamp, &event.ScheduleEventID, &attributes, &event.VisibleAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, errors.Wrap(err, "could not find activity task to lock")
+	}
+
+	a, err := history.DeserializeAttributes(event.Type, attributes)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not deserialize attributes")
+	}
+
+	event.Attributes = a
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`UPDATE activities SET locked_until = $1, worker = $2 WHERE id = $3`,
+		now.Add(b.options.ActivityLockTimeout),
+		b.workerName,
+		id,
+	); err != nil {
+		return nil, errors.Wrap(err, "could not lock activity")
+	}
+
+	t := &task.Activity{
+		ID:               event.ID,
+		WorkflowInstance: core.NewWorkflowInstance(instanceID, executionID),
+		Event:            event,
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	b.options.Logger.Debug("Locked activity task",
+		log.InstanceIDKey, instanceID,
+		log.ExecutionIDKey, executionID,
+		log.ActivityIDKey, event.ID,
+		log.ScheduleEventIDKey, event.ScheduleEventID,
+	)
+
+	return t, nil
+}
+
+// CompleteActivityTask completes a activity task retrieved using GetActivityTask
+func (b *postgresBackend) CompleteActivityTask(ctx context.Context, instance workflow.Instance, id string, event history.Event) error {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Remove activity
+	if res, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM activities WHERE activity_id = $1 AND instance_id = $2 AND execution_id = $3 AND worker = $4`,
+		id,
+		instance.GetInstanceID(),
+		instance.GetExecutionID(),
+		b.workerName,
+	); err != nil {
+		return errors.Wrap(err, "could not complete activity")
+	} else {
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return errors.Wrap(err, "could not check for completed activity")
+		}
+
+		if affected == 0 {
+			return errors.New("could not find locked activity")
+		}
+	}
+
+	// Insert new event generated during this workflow execution
+	if err := b.insertNewEvents(ctx, tx, instance.GetInstanceID(), []history.Event{event}); err != nil {
+		return errors.Wrap(err, "could not insert new events for completed activity")
+	}
+
+	if err := b.notify(ctx, tx, workflowTasksChannel); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	b.options.Logger.Debug("Completed activity task",
+		log.InstanceIDKey, instance.GetInstanceID(),
+		log.ExecutionIDKey, instance.GetExecutionID(),
+		log.ActivityIDKey, id,
+		log.EventIDKey, event.ID,
+	)
+
+	b.NotifyWorkflowTasks()
+
+	return nil
+}
+
+func (b *postgresBackend) ExtendActivityTask(ctx context.Context, activityID string) (err error) {
+	defer func() { b.recordLockExtension(metrickeys.TypeActivity, err) }()
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	until := time.Now().Add(b.options.ActivityLockTimeout)
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE activities SET locked_until = $1 WHERE activity_id = $2 AND worker = $3`,
+		until,
+		activityID,
+		b.workerName,
+	)
+	if err != nil {
+		return errors.Wrap(err, "could not extend activity lock")
+	}
+
+	if rowsAffected, err := res.RowsAffected(); err != nil {
+		return errors.Wrap(err, "could not determine if activity was extended")
+	} else if rowsAffected == 0 {
+		return errors.New("could not extend activity")
+	}
+
+	return tx.Commit()
+}
+
+func (b *postgresBackend) scheduleActivity(ctx context.Context, tx *sql.Tx, instanceID, executionID string, event history.Event) error {
+	a, err := b.serializeAttributes(event.Attributes)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO activities
+			(activity_id, instance_id, execution_id, event_type, "timestamp", schedule_event_id, attributes, visible_at, priority) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		event.ID,
+		instanceID,
+		executionID,
+		event.Type,
+		event.Timestamp,
+		event.ScheduleEventID,
+		a,
+		event.VisibleAt,
+		backend.ActivityPriority(event),
+	)
+
+	return err
+}
@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/test"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const testUser = "postgres"
+const testPassword = "root"
+
+// See the MySQL backend tests, every test uses its own database for complete isolation.
+
+func Test_PostgresBackend(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	dbName := "test_" + strings.Replace(uuid.NewString(), "-", "", -1)
+
+	var b backend.Backend
+
+	test.TestBackend(t, test.Tester{
+		New: func(options ...backend.BackendOption) backend.Backend {
+			db, err := sql.Open("postgres", fmt.Sprintf("host=localhost port=5432 user=%s password=%s sslmode=disable", testUser, testPassword))
+			if err != nil {
+				panic(err)
+			}
+
+			if _, err := db.Exec("CREATE DATABASE " + dbName); err != nil {
+				panic(errors.Wrap(err, "could not create database"))
+			}
+
+			if err := db.Close(); err != nil {
+				panic(err)
+			}
+
+			b = NewPostgresBackend("localhost", 5432, testUser, testPassword, dbName, append([]backend.BackendOption{backend.WithStickyTimeout(0)}, options...)...)
+
+			return b
+		},
+
+		Teardown: func() {
+			if err := b.(io.Closer).Close(); err != nil {
+				panic(err)
+			}
+
+			db, err := sql.Open("postgres", fmt.Sprintf("host=localhost port=5432 user=%s password=%s sslmode=disable", testUser, testPassword))
+			if err != nil {
+				panic(err)
+			}
+
+			if _, err := db.Exec("DROP DATABASE IF EXISTS " + dbName); err != nil {
+				panic(errors.Wrap(err, "could not drop database"))
+			}
+
+			if err := db.Close(); err != nil {
+				panic(err)
+			}
+		},
+	})
+}
@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"github.com/cschleiden/go-workflows/internal/sqlbackend"
+	"github.com/lib/pq"
+)
+
+// The start time of an instance is when its pending WorkflowExecutionStarted event becomes visible
+const instanceInfoQuery = "SELECT i.instance_id, i.execution_id, i.parent_instance_id, p.execution_id, i.parent_schedule_event_id, i.created_at, i.completed_at, pe.visible_at " +
+	"FROM instances i LEFT JOIN instances p ON p.instance_id = i.parent_instance_id LEFT JOIN pending_events pe ON pe.instance_id = i.instance_id AND pe.event_type = $1"
+
+var queries = sqlbackend.Queries{
+	In:         inArray,
+	GetHistory: getHistory,
+
+	DescribeInstance: instanceInfoQuery + " WHERE i.instance_id = $2 AND i.execution_id = $3",
+	ListInstances:    instanceInfoQuery + " WHERE i.instance_id > $2 ORDER BY i.instance_id LIMIT $3",
+	ScheduledStart:   "SELECT visible_at FROM pending_events WHERE instance_id = $1 AND event_type = $2 AND visible_at > $3",
+
+	CompletedAt:        "SELECT completed_at FROM instances WHERE instance_id = $1 AND execution_id = $2",
+	ExpiredInstances:   "SELECT instance_id FROM instances WHERE completed_at < $1 LIMIT $2",
+	CompletedInstances: "SELECT instance_id FROM instances WHERE instance_id %s AND completed_at IS NOT NULL FOR UPDATE SKIP LOCKED",
+	RemoveInstances: []string{
+		"DELETE FROM history WHERE instance_id %s",
+		"DELETE FROM pending_events WHERE instance_id %s",
+		"DELETE FROM activities WHERE instance_id %s",
+		"DELETE FROM instances WHERE instance_id %s",
+	},
+	ArchivedInstance: "SELECT execution_id, parent_instance_id, completed_at FROM instances WHERE instance_id = $1",
+	ParentInstance:   "SELECT parent_instance_id FROM instances WHERE instance_id = $1",
+
+	InsertSchedule: "INSERT INTO schedules (id, version, due_at, data) VALUES ($1, $2, $3, $4) ON CONFLICT (id) DO NOTHING",
+	GetSchedule:    "SELECT version, data FROM schedules WHERE id = $1",
+	ListSchedules:  "SELECT version, data FROM schedules ORDER BY id",
+	DueSchedules:   "SELECT version, data FROM schedules WHERE due_at IS NOT NULL AND due_at <= $1 ORDER BY due_at LIMIT $2",
+	UpdateSchedule: "UPDATE schedules SET version = version + 1, due_at = $1, data = $2 WHERE id = $3 AND version = $4",
+	ScheduleExists: "SELECT 1 FROM schedules WHERE id = $1",
+	DeleteSchedule: "DELETE FROM schedules WHERE id = $1",
+}
+
+// inArray matches a column against the given values passed as a single array argument
+func inArray(values []string) (string, []interface{}) {
+	return "= ANY($1)", []interface{}{pq.Array(values)}
+}
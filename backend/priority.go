@@ -0,0 +1,57 @@
+package backend
+
+import (
+	"sync/atomic"
+
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+// InstancePriority returns the priority of the workflow instance started by the given events, or 0
+// if none of them starts an instance
+func InstancePriority(events ...history.Event) int {
+	for _, event := range events {
+		if a, ok := event.Attributes.(*history.ExecutionStartedAttributes); ok {
+			return a.Priority
+		}
+	}
+
+	return 0
+}
+
+// ActivityPriority returns the priority of the activity scheduled by the given event
+func ActivityPriority(event history.Event) int {
+	if a, ok := event.Attributes.(*history.ActivityScheduledAttributes); ok {
+		return a.Priority
+	}
+
+	return 0
+}
+
+// PriorityFairness decides which task polls honor priorities. To prevent tasks with a low
+// priority from being starved when there is a constant backlog of tasks with a higher priority,
+// every n-th poll ignores priorities and picks the task that has been available the longest
+// instead.
+type PriorityFairness struct {
+	n     uint64
+	polls uint64
+}
+
+// NewPriorityFairness returns a PriorityFairness that ignores priorities for every n-th poll. If
+// n is zero or negative, priorities are always honored.
+func NewPriorityFairness(n int) *PriorityFairness {
+	f := &PriorityFairness{}
+	if n > 0 {
+		f.n = uint64(n)
+	}
+
+	return f
+}
+
+// HonorPriority returns whether the next poll should pick tasks by priority
+func (f *PriorityFairness) HonorPriority() bool {
+	if f.n == 0 {
+		return true
+	}
+
+	return atomic.AddUint64(&f.polls, 1)%f.n != 0
+}
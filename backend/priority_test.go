@@ -0,0 +1,36 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PriorityFairness_IgnoresPriorityForEveryNthPoll(t *testing.T) {
+	f := NewPriorityFairness(3)
+
+	honored := []bool{}
+	for i := 0; i < 6; i++ {
+		honored = append(honored, f.HonorPriority())
+	}
+
+	require.Equal(t, []bool{true, true, false, true, true, false}, honored)
+}
+
+func Test_PriorityFairness_Disabled(t *testing.T) {
+	f := NewPriorityFairness(0)
+
+	for i := 0; i < 10; i++ {
+		require.True(t, f.HonorPriority())
+	}
+}
+
+func Test_InstancePriority(t *testing.T) {
+	signal := history.NewHistoryEvent(time.Now(), history.EventType_SignalReceived, &history.SignalReceivedAttributes{})
+	started := history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Priority: 3})
+
+	require.Equal(t, 0, InstancePriority(signal))
+	require.Equal(t, 3, InstancePriority(signal, started))
+}
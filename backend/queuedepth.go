@@ -0,0 +1,51 @@
+package backend
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cschleiden/go-workflows/metrics"
+)
+
+// QueueDepthInterval is the minimum time between two measurements of the queue depth
+const QueueDepthInterval = 10 * time.Second
+
+// QueueDepth records gauges for the number of pending tasks of a backend. To keep the overhead
+// for pollers low, the queue depth is measured at most once per QueueDepthInterval.
+type QueueDepth struct {
+	metrics metrics.Client
+	measure func(ctx context.Context) (map[string]int64, error)
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// NewQueueDepth returns a QueueDepth using measure to get the value of every gauge by name
+func NewQueueDepth(metrics metrics.Client, measure func(ctx context.Context) (map[string]int64, error)) *QueueDepth {
+	return &QueueDepth{
+		metrics: metrics,
+		measure: measure,
+	}
+}
+
+// Report measures and records the queue depth, unless it was measured within the last
+// QueueDepthInterval. Errors are ignored, the queue depth is measured again after the interval.
+func (q *QueueDepth) Report(ctx context.Context) {
+	q.mu.Lock()
+	if time.Since(q.last) < QueueDepthInterval {
+		q.mu.Unlock()
+		return
+	}
+	q.last = time.Now()
+	q.mu.Unlock()
+
+	gauges, err := q.measure(ctx)
+	if err != nil {
+		return
+	}
+
+	for name, value := range gauges {
+		q.metrics.Gauge(name, nil, value)
+	}
+}
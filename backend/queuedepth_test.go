@@ -0,0 +1,35 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+type gaugeRecorder struct {
+	metrics.Client
+
+	gauges map[string]int64
+}
+
+func (r *gaugeRecorder) Gauge(name string, tags metrics.Tags, value int64) {
+	r.gauges[name] = value
+}
+
+func Test_QueueDepth_ReportsAtMostOncePerInterval(t *testing.T) {
+	r := &gaugeRecorder{gauges: map[string]int64{}}
+
+	measured := 0
+	q := NewQueueDepth(r, func(ctx context.Context) (map[string]int64, error) {
+		measured++
+		return map[string]int64{"pending": int64(measured)}, nil
+	})
+
+	q.Report(context.Background())
+	q.Report(context.Background())
+
+	require.Equal(t, 1, measured)
+	require.Equal(t, map[string]int64{"pending": 1}, r.gauges)
+}
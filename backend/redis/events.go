@@ -0,0 +1,89 @@
+package redis
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/pkg/errors"
+)
+
+func marshalEvent(e history.Event) (string, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", errors.Wrap(err, "could not marshal event")
+	}
+
+	return string(data), nil
+}
+
+func unmarshalEvent(data string) (history.Event, error) {
+	var e history.Event
+	if err := json.Unmarshal([]byte(data), &e); err != nil {
+		return history.Event{}, errors.Wrap(err, "could not unmarshal event")
+	}
+
+	return e, nil
+}
+
+// pendingEvent is a new event for a workflow instance, as expected by the scripts
+type pendingEvent struct {
+	ID        string `json:"id"`
+	VisibleAt string `json:"visible_at"`
+	Event     string `json:"event"`
+}
+
+func newPendingEvents(events []history.Event, now time.Time) ([]pendingEvent, error) {
+	pendingEvents := make([]pendingEvent, 0, len(events))
+	for _, e := range events {
+		data, err := marshalEvent(e)
+		if err != nil {
+			return nil, err
+		}
+
+		visibleAt := now
+		if e.VisibleAt != nil {
+			visibleAt = *e.VisibleAt
+		}
+
+		pendingEvents = append(pendingEvents, pendingEvent{
+			ID:        e.ID,
+			VisibleAt: score(visibleAt),
+			Event:     data,
+		})
+	}
+
+	return pendingEvents, nil
+}
+
+// score returns the representation of t used for sorted set scores and timestamps
+func score(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
+
+// entryValues returns the fields of a stream entry returned by a script
+func entryValues(res interface{}) (map[string]string, error) {
+	entry, ok := res.([]interface{})
+	if !ok || len(entry) != 2 {
+		return nil, errors.Errorf("unexpected stream entry %v", res)
+	}
+
+	fields, ok := entry[1].([]interface{})
+	if !ok {
+		return nil, errors.Errorf("unexpected stream entry %v", res)
+	}
+
+	values := make(map[string]string, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		name, ok := fields[i].(string)
+		value, ok2 := fields[i+1].(string)
+		if !ok || !ok2 {
+			return nil, errors.Errorf("unexpected stream entry %v", res)
+		}
+
+		values[name] = value
+	}
+
+	return values, nil
+}
@@ -0,0 +1,138 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+)
+
+var instanceInfoFields = []string{
+	"instance_id", "execution_id", "parent_instance_id", "parent_event_id", "created_at", "completed_at", "start_at",
+	"parent_execution_id",
+}
+
+func (b *redisBackend) DescribeWorkflowInstance(ctx context.Context, instance workflow.Instance) (*backend.WorkflowInstanceInfo, error) {
+	state, err := b.rdb.HMGet(ctx, instanceKey(instance.GetInstanceID()), instanceInfoFields...).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get workflow instance")
+	}
+
+	if state[0] == nil || state[1] != instance.GetExecutionID() {
+		return nil, backend.ErrInstanceNotFound
+	}
+
+	return parseInstanceInfo(state, time.Now())
+}
+
+// ListWorkflowInstances returns workflow instances ordered by instance ID. Instances created
+// before instances were indexed by the backend are not included.
+func (b *redisBackend) ListWorkflowInstances(ctx context.Context, afterInstanceID string, limit int) ([]*backend.WorkflowInstanceInfo, error) {
+	min := "-"
+	if afterInstanceID != "" {
+		min = "(" + afterInstanceID
+	}
+
+	ids, err := b.rdb.ZRangeByLex(ctx, instancesKey, &redis.ZRangeBy{
+		Min:   min,
+		Max:   "+",
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list workflow instances")
+	}
+
+	cmds := make([]*redis.SliceCmd, 0, len(ids))
+	if _, err := b.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, id := range ids {
+			cmds = append(cmds, p.HMGet(ctx, instanceKey(id), instanceInfoFields...))
+		}
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "could not get workflow instances")
+	}
+
+	now := time.Now()
+	infos := make([]*backend.WorkflowInstanceInfo, 0, len(ids))
+	for _, cmd := range cmds {
+		state := cmd.Val()
+		if state[0] == nil {
+			// Removed concurrently
+			continue
+		}
+
+		info, err := parseInstanceInfo(state, now)
+		if err != nil {
+			return nil, err
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// parseInstanceInfo parses the values of instanceInfoFields
+func parseInstanceInfo(state []interface{}, now time.Time) (*backend.WorkflowInstanceInfo, error) {
+	instanceID, _ := state[0].(string)
+	executionID, _ := state[1].(string)
+
+	var wfi workflow.Instance
+	if parentInstanceID, ok := state[2].(string); ok {
+		parentEventID, err := strconv.Atoi(state[3].(string))
+		if err != nil {
+			return nil, errors.Wrap(err, "could not parse parent event id")
+		}
+
+		// Instances created before the parent execution was recorded don't have it
+		parentExecutionID, _ := state[7].(string)
+
+		wfi = core.NewSubWorkflowInstance(instanceID, executionID, core.NewWorkflowInstance(parentInstanceID, parentExecutionID), parentEventID)
+	} else {
+		wfi = core.NewWorkflowInstance(instanceID, executionID)
+	}
+
+	createdAt, err := parseScore(state[4])
+	if err != nil {
+		return nil, err
+	}
+
+	completedAt, err := parseScore(state[5])
+	if err != nil {
+		return nil, err
+	}
+
+	startAt, err := parseScore(state[6])
+	if err != nil {
+		return nil, err
+	}
+
+	var created time.Time
+	if createdAt != nil {
+		created = *createdAt
+	}
+
+	return backend.NewWorkflowInstanceInfo(wfi, created, startAt, completedAt, now), nil
+}
+
+// parseScore parses a time stored using score, returns nil if the value is not set
+func parseScore(v interface{}) (*time.Time, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, nil
+	}
+
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse time")
+	}
+
+	t := time.UnixMilli(ms)
+	return &t, nil
+}
@@ -0,0 +1,66 @@
+package redis
+
+// Keys used by the backend, these have to match the ones in scripts/lib.lua.
+const (
+	// readyKey is a sorted set of instances with pending events, scored by the time the
+	// earliest pending event becomes visible. Ready instances are also tracked in a sorted set
+	// per priority, to find the ready instance with the highest priority.
+	readyKey = "instances:ready"
+
+	// lockedKey is a sorted set of locked instances, scored by the time the lock expires
+	lockedKey = "instances:locked"
+
+	// activitiesKey is the stream of scheduled activities without a priority. Activities with a
+	// priority are kept in a stream per priority, see activityStreamKey.
+	activitiesKey = "activities"
+
+	// activityPrioritiesKey is a sorted set of the priorities of all activity streams, scored by
+	// the priority
+	activityPrioritiesKey = "activities:priorities"
+
+	// activitiesLockedKey is a sorted set of locked activity stream entries, scored by the time
+	// the lock expires
+	activitiesLockedKey = "activities:locked"
+
+	// instancesKey is a sorted set of all instance IDs, all with the same score so that they are
+	// ordered by ID
+	instancesKey = "instances"
+
+	// schedulesKey is a sorted set of all schedule IDs, all with the same score so that they are
+	// ordered by ID
+	schedulesKey = "schedules"
+
+	// schedulesDueKey is a sorted set of schedules, scored by the time they are due
+	schedulesDueKey = "schedules:due"
+
+	// activitiesGroup is the consumer group used by all workers to read activities
+	activitiesGroup = "activity-workers"
+)
+
+func activityStreamKey(priority string) string {
+	if priority == "0" {
+		return activitiesKey
+	}
+
+	return activitiesKey + ":" + priority
+}
+
+func instanceKey(instanceID string) string {
+	return "instance:" + instanceID
+}
+
+func subInstancesKey(instanceID string) string {
+	return "sub-instances:" + instanceID
+}
+
+func pendingEventsKey(instanceID string) string {
+	return "pending-events:" + instanceID
+}
+
+func historyKey(instanceID string) string {
+	return "history:" + instanceID
+}
+
+func scheduleKey(scheduleID string) string {
+	return "schedule:" + scheduleID
+}
@@ -0,0 +1,43 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/cschleiden/go-workflows/internal/metrickeys"
+	"github.com/cschleiden/go-workflows/metrics"
+)
+
+// measureQueueDepth returns the number of pending activities of all priorities. Pending events
+// are spread over one stream per instance, so they are not counted.
+func (b *redisBackend) measureQueueDepth(ctx context.Context) (map[string]int64, error) {
+	priorities, err := b.rdb.ZRange(ctx, activityPrioritiesKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var pendingActivities int64
+	for _, priority := range priorities {
+		n, err := b.rdb.XLen(ctx, activityStreamKey(priority)).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		pendingActivities += n
+	}
+
+	return map[string]int64{
+		metrickeys.PendingActivities: pendingActivities,
+	}, nil
+}
+
+func (b *redisBackend) recordLockExtension(taskType string, err error) {
+	result := metrickeys.ResultSuccess
+	if err != nil {
+		result = metrickeys.ResultError
+	}
+
+	b.options.Metrics.Counter(metrickeys.LockExtensions, metrics.Tags{
+		metrickeys.Type:   taskType,
+		metrickeys.Result: result,
+	}, 1)
+}
@@ -0,0 +1,629 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/internal/metrickeys"
+	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// NewRedisBackend creates a backend storing workflow instances in Redis. Pending events and
+// activities are kept in streams, sorted sets track when instances become ready and when locks
+// expire. Requires Redis 5 or newer.
+//
+// Scripts access keys that aren't declared upfront, so the backend requires a single node or
+// Sentinel client. Redis Cluster is not supported.
+func NewRedisBackend(rdb *redis.Client, opts ...backend.BackendOption) backend.Backend {
+	// Create the consumer group for activities, if it doesn't exist yet
+	if err := rdb.XGroupCreateMkStream(context.Background(), activitiesKey, activitiesGroup, "0").Err(); err != nil &&
+		!strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		panic(errors.Wrap(err, "could not create activity consumer group"))
+	}
+
+	// Activities without a priority are always read from the activities stream
+	if err := rdb.ZAdd(context.Background(), activityPrioritiesKey, &redis.Z{Score: 0, Member: 0}).Err(); err != nil {
+		panic(errors.Wrap(err, "could not register activity stream"))
+	}
+
+	options := backend.ApplyOptions(opts...)
+
+	b := &redisBackend{
+		rdb:        rdb,
+		workerName: fmt.Sprintf("worker-%v", uuid.NewString()),
+		options:    options,
+
+		workflowFairness: backend.NewPriorityFairness(options.PriorityFairness),
+		activityFairness: backend.NewPriorityFairness(options.PriorityFairness),
+
+		Notifications: backend.NewNotifications(),
+	}
+
+	b.queueDepth = backend.NewQueueDepth(options.Metrics, b.measureQueueDepth)
+
+	return b
+}
+
+type redisBackend struct {
+	rdb        *redis.Client
+	workerName string
+	options    backend.Options
+
+	workflowFairness *backend.PriorityFairness
+	activityFairness *backend.PriorityFairness
+
+	*backend.Notifications
+
+	queueDepth *backend.QueueDepth
+}
+
+// instanceState is a workflow instance, as expected by the scripts
+type instanceState struct {
+	InstanceID        string `json:"instance_id"`
+	ExecutionID       string `json:"execution_id"`
+	ParentInstanceID  string `json:"parent_instance_id,omitempty"`
+	ParentExecutionID string `json:"parent_execution_id,omitempty"`
+	ParentEventID     string `json:"parent_event_id,omitempty"`
+	CreatedAt         string `json:"created_at"`
+	StartAt           string `json:"start_at,omitempty"`
+	Priority          int    `json:"priority,omitempty"`
+}
+
+func newInstanceState(wfi workflow.Instance, now time.Time) instanceState {
+	s := instanceState{
+		InstanceID:  wfi.GetInstanceID(),
+		ExecutionID: wfi.GetExecutionID(),
+		CreatedAt:   score(now),
+	}
+
+	if wfi.SubWorkflow() {
+		s.ParentInstanceID = wfi.ParentInstance().GetInstanceID()
+		s.ParentExecutionID = wfi.ParentInstance().GetExecutionID()
+		s.ParentEventID = strconv.Itoa(wfi.ParentEventID())
+	}
+
+	return s
+}
+
+// CreateWorkflowInstance creates a new workflow instance
+func (b *redisBackend) CreateWorkflowInstance(ctx context.Context, m history.WorkflowEvent) error {
+	now := time.Now()
+
+	events, err := newPendingEvents([]history.Event{m.HistoryEvent}, now)
+	if err != nil {
+		return err
+	}
+
+	instance := newInstanceState(m.WorkflowInstance, now)
+	if m.HistoryEvent.VisibleAt != nil {
+		instance.StartAt = score(*m.HistoryEvent.VisibleAt)
+	}
+
+	instance.Priority = backend.InstancePriority(m.HistoryEvent)
+
+	if _, err := runScript(ctx, b.rdb, createWorkflowInstanceCmd, struct {
+		Instance instanceState  `json:"instance"`
+		Events   []pendingEvent `json:"events"`
+	}{
+		Instance: instance,
+		Events:   events,
+	}); err != nil {
+		return errors.Wrap(err, "could not create workflow instance")
+	}
+
+	b.NotifyWorkflowTasks()
+
+	return nil
+}
+
+func (b *redisBackend) CancelWorkflowInstance(ctx context.Context, instance workflow.Instance) error {
+	if err := b.cancelInstance(ctx, instance.GetInstanceID()); err != nil {
+		return err
+	}
+
+	b.NotifyWorkflowTasks()
+
+	return nil
+}
+
+// cancelInstance cancels the given instance and, recursively, all of its running sub-workflow instances
+func (b *redisBackend) cancelInstance(ctx context.Context, instanceID string) error {
+	if err := b.addPendingEvents(ctx, instanceID, []history.Event{history.NewWorkflowCancellationEvent(time.Now())}); err != nil {
+		return errors.Wrap(err, "could not insert cancellation event")
+	}
+
+	subInstanceIDs, err := b.rdb.SMembers(ctx, subInstancesKey(instanceID)).Result()
+	if err != nil {
+		return errors.Wrap(err, "could not get sub-workflow instances")
+	}
+
+	for _, subInstanceID := range subInstanceIDs {
+		completed, err := b.rdb.HExists(ctx, instanceKey(subInstanceID), "completed_at").Result()
+		if err != nil {
+			return errors.Wrap(err, "could not get workflow instance for cancelling")
+		}
+
+		if completed {
+			continue
+		}
+
+		if err := b.cancelInstance(ctx, subInstanceID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SignalWorkflow signals a running workflow instance
+func (b *redisBackend) SignalWorkflow(ctx context.Context, instanceID string, event history.Event) error {
+	if err := b.addPendingEvents(ctx, instanceID, []history.Event{event}); err != nil {
+		return errors.Wrap(err, "could not insert signal event")
+	}
+
+	b.NotifyWorkflowTasks()
+
+	return nil
+}
+
+func (b *redisBackend) addPendingEvents(ctx context.Context, instanceID string, events []history.Event) error {
+	pendingEvents, err := newPendingEvents(events, time.Now())
+	if err != nil {
+		return err
+	}
+
+	_, err = runScript(ctx, b.rdb, addPendingEventsCmd, struct {
+		InstanceID string         `json:"instance_id"`
+		Events     []pendingEvent `json:"events"`
+	}{
+		InstanceID: instanceID,
+		Events:     pendingEvents,
+	})
+
+	return err
+}
+
+// RemoveWorkflowInstance removes a completed workflow instance including its history. Activities
+// are not removed, since they are expected to be completed before the workflow instance.
+func (b *redisBackend) RemoveWorkflowInstance(ctx context.Context, instance workflow.Instance) error {
+	res, err := runIntScript(ctx, b.rdb, removeWorkflowInstanceCmd, struct {
+		InstanceID  string `json:"instance_id"`
+		ExecutionID string `json:"execution_id"`
+	}{
+		InstanceID:  instance.GetInstanceID(),
+		ExecutionID: instance.GetExecutionID(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not remove workflow instance")
+	}
+
+	switch res {
+	case 0:
+		return backend.ErrInstanceNotFound
+	case -1:
+		return backend.ErrInstanceNotCompleted
+	}
+
+	return nil
+}
+
+func (b *redisBackend) GetWorkflowInstanceHistory(ctx context.Context, instance workflow.Instance) ([]history.Event, error) {
+	executionID, err := b.rdb.HGet(ctx, instanceKey(instance.GetInstanceID()), "execution_id").Result()
+	if err != nil && err != redis.Nil {
+		return nil, errors.Wrap(err, "could not get workflow instance")
+	}
+
+	if err == redis.Nil || executionID != instance.GetExecutionID() {
+		return nil, backend.ErrInstanceNotFound
+	}
+
+	msgs, err := b.rdb.XRange(ctx, historyKey(instance.GetInstanceID()), "-", "+").Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get history")
+	}
+
+	events := make([]history.Event, 0, len(msgs))
+	for _, msg := range msgs {
+		event, err := unmarshalEvent(msg.Values["event"].(string))
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// noTask reports whether err means that no task could be locked, either because there is none or
+// because the poll was cancelled or timed out.
+func noTask(ctx context.Context, err error) bool {
+	return err == redis.Nil ||
+		ctx.Err() != nil ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, context.Canceled)
+}
+
+// GetWorkflowTask returns a pending workflow task or nil if there are no pending worflow executions
+func (b *redisBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, error) {
+	b.queueDepth.Report(ctx)
+
+	now := time.Now()
+	res, err := runScript(ctx, b.rdb, lockWorkflowTaskCmd, struct {
+		Now           string `json:"now"`
+		Worker        string `json:"worker"`
+		LockedUntil   string `json:"locked_until"`
+		HonorPriority bool   `json:"honor_priority"`
+	}{
+		Now:           score(now),
+		Worker:        b.workerName,
+		LockedUntil:   score(now.Add(b.options.WorkflowLockTimeout)),
+		HonorPriority: b.workflowFairness.HonorPriority(),
+	})
+	if err != nil {
+		if noTask(ctx, err) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrap(err, "could not lock workflow instance")
+	}
+
+	// Locked instance as returned by the script: [instance id, sticky]
+	locked, ok := res.([]interface{})
+	if !ok || len(locked) != 2 {
+		return nil, errors.Errorf("unexpected script result %v", res)
+	}
+
+	instanceID, ok := locked[0].(string)
+	sticky, ok2 := locked[1].(int64)
+	if !ok || !ok2 {
+		return nil, errors.Errorf("unexpected script result %v", res)
+	}
+
+	var kind task.Kind
+	if sticky == 1 {
+		kind = task.Continuation
+	}
+
+	state, err := b.rdb.HMGet(ctx, instanceKey(instanceID), "execution_id", "parent_instance_id", "parent_event_id").Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get workflow instance")
+	}
+
+	executionID, _ := state[0].(string)
+
+	var wfi workflow.Instance
+	if parentInstanceID, ok := state[1].(string); ok {
+		parentEventID, err := strconv.Atoi(state[2].(string))
+		if err != nil {
+			return nil, errors.Wrap(err, "could not parse parent event id")
+		}
+
+		wfi = core.NewSubWorkflowInstance(instanceID, executionID, core.NewWorkflowInstance(parentInstanceID, ""), parentEventID)
+	} else {
+		wfi = core.NewWorkflowInstance(instanceID, executionID)
+	}
+
+	t := &task.Workflow{
+		WorkflowInstance: wfi,
+		NewEvents:        []history.Event{},
+		History:          []history.Event{},
+		Kind:             kind,
+	}
+
+	// Get new events
+	pendingEvents, err := b.rdb.XRange(ctx, pendingEventsKey(instanceID), "-", "+").Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get new events")
+	}
+
+	for _, msg := range pendingEvents {
+		visibleAt, err := strconv.ParseInt(msg.Values["visible_at"].(string), 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not parse event visibility")
+		}
+
+		if visibleAt > now.UnixMilli() {
+			continue
+		}
+
+		event, err := unmarshalEvent(msg.Values["event"].(string))
+		if err != nil {
+			return nil, err
+		}
+
+		t.NewEvents = append(t.NewEvents, event)
+	}
+
+	// Return if there aren't any new events
+	if len(t.NewEvents) == 0 {
+		if err := b.unlockWorkflowTask(ctx, instanceID); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+
+	// Get history
+	var historyEvents []redis.XMessage
+	if kind != task.Continuation {
+		historyEvents, err = b.rdb.XRange(ctx, historyKey(instanceID), "-", "+").Result()
+	} else {
+		// Get only most recent history event
+		historyEvents, err = b.rdb.XRevRangeN(ctx, historyKey(instanceID), "+", "-", 1).Result()
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get history")
+	}
+
+	for _, msg := range historyEvents {
+		event, err := unmarshalEvent(msg.Values["event"].(string))
+		if err != nil {
+			return nil, err
+		}
+
+		t.History = append(t.History, event)
+	}
+
+	b.options.Logger.Debug("Locked workflow task",
+		log.InstanceIDKey, instanceID,
+		log.ExecutionIDKey, executionID,
+		"new_events", len(t.NewEvents),
+		"continuation", kind == task.Continuation,
+	)
+
+	return t, nil
+}
+
+// unlockWorkflowTask releases the lock of an instance without visible pending events and
+// updates when the instance becomes ready again
+func (b *redisBackend) unlockWorkflowTask(ctx context.Context, instanceID string) error {
+	if _, err := runScript(ctx, b.rdb, unlockWorkflowTaskCmd, struct {
+		InstanceID string `json:"instance_id"`
+	}{
+		InstanceID: instanceID,
+	}); err != nil {
+		return errors.Wrap(err, "could not unlock workflow instance")
+	}
+
+	return nil
+}
+
+// CompleteWorkflowTask completes a workflow task retrieved using GetWorkflowTask
+//
+// This checkpoints the execution. events are new events from the last workflow execution
+// which will be added to the workflow instance history. workflowEvents are new events for the
+// completed or other workflow instances.
+func (b *redisBackend) CompleteWorkflowTask(
+	ctx context.Context,
+	instance workflow.Instance,
+	executedEvents []history.Event,
+	workflowEvents []history.WorkflowEvent,
+) error {
+	type scheduledActivity struct {
+		ID       string `json:"id"`
+		Event    string `json:"event"`
+		Priority int    `json:"priority,omitempty"`
+	}
+
+	type instanceEvents struct {
+		Instance instanceState  `json:"instance"`
+		Events   []pendingEvent `json:"events"`
+	}
+
+	now := time.Now()
+
+	args := struct {
+		InstanceID       string              `json:"instance_id"`
+		ExecutionID      string              `json:"execution_id"`
+		Worker           string              `json:"worker"`
+		Group            string              `json:"group"`
+		StickyUntil      string              `json:"sticky_until"`
+		ExecutedEventIDs []string            `json:"executed_event_ids,omitempty"`
+		HistoryEvents    []string            `json:"history_events,omitempty"`
+		Activities       []scheduledActivity `json:"activities,omitempty"`
+		CompletedAt      string              `json:"completed_at,omitempty"`
+		WorkflowEvents   []instanceEvents    `json:"workflow_events,omitempty"`
+	}{
+		InstanceID:  instance.GetInstanceID(),
+		ExecutionID: instance.GetExecutionID(),
+		Worker:      b.workerName,
+		Group:       activitiesGroup,
+		StickyUntil: score(now.Add(b.options.StickyTimeout)),
+	}
+
+	for _, e := range executedEvents {
+		data, err := marshalEvent(e)
+		if err != nil {
+			return err
+		}
+
+		args.ExecutedEventIDs = append(args.ExecutedEventIDs, e.ID)
+		args.HistoryEvents = append(args.HistoryEvents, data)
+
+		switch e.Type {
+		case history.EventType_ActivityScheduled:
+			args.Activities = append(args.Activities, scheduledActivity{ID: e.ID, Event: data, Priority: backend.ActivityPriority(e)})
+
+		case history.EventType_WorkflowExecutionFinished:
+			args.CompletedAt = score(now)
+		}
+	}
+
+	// Group new workflow events by instance, keeping the order in which instances appear
+	groups := make(map[string]int)
+	for _, m := range workflowEvents {
+		events, err := newPendingEvents([]history.Event{m.HistoryEvent}, now)
+		if err != nil {
+			return err
+		}
+
+		targetInstanceID := m.WorkflowInstance.GetInstanceID()
+		i, ok := groups[targetInstanceID]
+		if !ok {
+			i = len(args.WorkflowEvents)
+			groups[targetInstanceID] = i
+			args.WorkflowEvents = append(args.WorkflowEvents, instanceEvents{
+				Instance: newInstanceState(m.WorkflowInstance, now),
+			})
+		}
+
+		if priority := backend.InstancePriority(m.HistoryEvent); priority != 0 {
+			args.WorkflowEvents[i].Instance.Priority = priority
+		}
+
+		args.WorkflowEvents[i].Events = append(args.WorkflowEvents[i].Events, events...)
+	}
+
+	if _, err := runScript(ctx, b.rdb, completeWorkflowTaskCmd, args); err != nil {
+		return errors.Wrap(err, "could not complete workflow task")
+	}
+
+	b.options.Logger.Debug("Completed workflow task",
+		log.InstanceIDKey, instance.GetInstanceID(),
+		log.ExecutionIDKey, instance.GetExecutionID(),
+		"executed_events", len(executedEvents),
+		"workflow_events", len(workflowEvents),
+	)
+
+	b.NotifyCompletedWorkflowTask(executedEvents, workflowEvents)
+
+	return nil
+}
+
+func (b *redisBackend) ExtendWorkflowTask(ctx context.Context, instance workflow.Instance) (err error) {
+	defer func() { b.recordLockExtension(metrickeys.TypeWorkflow, err) }()
+
+	if _, err := runScript(ctx, b.rdb, extendWorkflowTaskCmd, struct {
+		InstanceID  string `json:"instance_id"`
+		ExecutionID string `json:"execution_id"`
+		Worker      string `json:"worker"`
+		LockedUntil string `json:"locked_until"`
+	}{
+		InstanceID:  instance.GetInstanceID(),
+		ExecutionID: instance.GetExecutionID(),
+		Worker:      b.workerName,
+		LockedUntil: score(time.Now().Add(b.options.WorkflowLockTimeout)),
+	}); err != nil {
+		return errors.Wrap(err, "could not extend workflow task lock")
+	}
+
+	return nil
+}
+
+// GetActivityTask returns a pending activity task or nil if there are no pending activities
+func (b *redisBackend) GetActivityTask(ctx context.Context) (*task.Activity, error) {
+	b.queueDepth.Report(ctx)
+
+	now := time.Now()
+	res, err := runScript(ctx, b.rdb, lockActivityTaskCmd, struct {
+		Group         string `json:"group"`
+		Worker        string `json:"worker"`
+		Now           string `json:"now"`
+		LockedUntil   string `json:"locked_until"`
+		HonorPriority bool   `json:"honor_priority"`
+	}{
+		Group:         activitiesGroup,
+		Worker:        b.workerName,
+		Now:           score(now),
+		LockedUntil:   score(now.Add(b.options.ActivityLockTimeout)),
+		HonorPriority: b.activityFairness.HonorPriority(),
+	})
+	if err != nil {
+		if noTask(ctx, err) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrap(err, "could not lock activity task")
+	}
+
+	// Stream entry as returned by the script: [id, [field, value, ...]]
+	values, err := entryValues(res)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := unmarshalEvent(values["event"])
+	if err != nil {
+		return nil, err
+	}
+
+	t := &task.Activity{
+		ID:               values["id"],
+		WorkflowInstance: core.NewWorkflowInstance(values["instance_id"], values["execution_id"]),
+		Event:            event,
+	}
+
+	b.options.Logger.Debug("Locked activity task",
+		log.InstanceIDKey, values["instance_id"],
+		log.ExecutionIDKey, values["execution_id"],
+		log.ActivityIDKey, event.ID,
+		log.ScheduleEventIDKey, event.ScheduleEventID,
+	)
+
+	return t, nil
+}
+
+// CompleteActivityTask completes a activity task retrieved using GetActivityTask
+func (b *redisBackend) CompleteActivityTask(ctx context.Context, instance workflow.Instance, id string, event history.Event) error {
+	events, err := newPendingEvents([]history.Event{event}, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if _, err := runScript(ctx, b.rdb, completeActivityTaskCmd, struct {
+		Group      string         `json:"group"`
+		Worker     string         `json:"worker"`
+		ActivityID string         `json:"activity_id"`
+		InstanceID string         `json:"instance_id"`
+		Events     []pendingEvent `json:"events"`
+	}{
+		Group:      activitiesGroup,
+		Worker:     b.workerName,
+		ActivityID: id,
+		InstanceID: instance.GetInstanceID(),
+		Events:     events,
+	}); err != nil {
+		return errors.Wrap(err, "could not complete activity")
+	}
+
+	b.options.Logger.Debug("Completed activity task",
+		log.InstanceIDKey, instance.GetInstanceID(),
+		log.ExecutionIDKey, instance.GetExecutionID(),
+		log.ActivityIDKey, id,
+		log.EventIDKey, event.ID,
+	)
+
+	b.NotifyWorkflowTasks()
+
+	return nil
+}
+
+func (b *redisBackend) ExtendActivityTask(ctx context.Context, activityID string) (err error) {
+	defer func() { b.recordLockExtension(metrickeys.TypeActivity, err) }()
+
+	if _, err := runScript(ctx, b.rdb, extendActivityTaskCmd, struct {
+		Worker      string `json:"worker"`
+		ActivityID  string `json:"activity_id"`
+		LockedUntil string `json:"locked_until"`
+	}{
+		Worker:      b.workerName,
+		ActivityID:  activityID,
+		LockedUntil: score(time.Now().Add(b.options.ActivityLockTimeout)),
+	}); err != nil {
+		return errors.Wrap(err, "could not extend activity lock")
+	}
+
+	return nil
+}
@@ -0,0 +1,206 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/test"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RedisBackend(t *testing.T) {
+	var mr *miniredis.Miniredis
+
+	test.TestBackend(t, test.Tester{
+		New: func(options ...backend.BackendOption) backend.Backend {
+			var err error
+			mr, err = miniredis.Run()
+			if err != nil {
+				panic(err)
+			}
+
+			rdb := redis.NewClient(&redis.Options{
+				Addr: mr.Addr(),
+			})
+
+			// Disable sticky workflow behavior for the test execution
+			return NewRedisBackend(rdb, append([]backend.BackendOption{backend.WithStickyTimeout(0)}, options...)...)
+		},
+
+		Teardown: func() {
+			mr.Close()
+		},
+	})
+}
+
+func Test_RedisBackend_EndToEnd(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	b := NewRedisBackend(rdb)
+
+	w := worker.New(b, nil)
+	require.NoError(t, w.RegisterWorkflow(workflowWithSubWorkflow))
+	require.NoError(t, w.RegisterWorkflow(workflowWithTimerAndActivity))
+	require.NoError(t, w.RegisterActivity(double))
+	require.NoError(t, w.Start(ctx))
+
+	c := client.New(b)
+
+	instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+	}, workflowWithSubWorkflow, 21)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		completed, err := rdb.HExists(ctx, instanceKey(instance.GetInstanceID()), "completed_at").Result()
+		require.NoError(t, err)
+
+		return completed
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, w.Stop())
+}
+
+func workflowWithSubWorkflow(ctx workflow.Context, a int) (int, error) {
+	var r int
+	if err := workflow.CreateSubWorkflowInstance(ctx, workflow.DefaultSubWorkflowOptions, workflowWithTimerAndActivity, a).Get(ctx, &r); err != nil {
+		return 0, err
+	}
+
+	return r, nil
+}
+
+func workflowWithTimerAndActivity(ctx workflow.Context, a int) (int, error) {
+	if err := workflow.ScheduleTimer(ctx, 50*time.Millisecond).Get(ctx, nil); err != nil {
+		return 0, err
+	}
+
+	var r int
+	if err := workflow.ExecuteActivity(ctx, workflow.DefaultActivityOptions, double, a).Get(ctx, &r); err != nil {
+		return 0, err
+	}
+
+	return r, nil
+}
+
+func double(ctx context.Context, a int) (int, error) {
+	return a * 2, nil
+}
+
+func Test_RunIntScript_UnexpectedResult(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	_, err = runIntScript(context.Background(), rdb, newScript(`return "unexpected"`), struct{}{})
+	require.Error(t, err)
+}
+
+func Test_RedisBackend_ActivityPriorityFairness(t *testing.T) {
+	ctx := context.Background()
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	// Every poll ignores priorities
+	b := NewRedisBackend(rdb, backend.WithStickyTimeout(0), backend.WithPriorityFairness(1))
+
+	startedEvent := history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{})
+	lowEvent := history.NewHistoryEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{}, history.ScheduleEventID(1))
+	highEvent := history.NewHistoryEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{Priority: 10}, history.ScheduleEventID(2))
+
+	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	require.NoError(t, b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: wfi,
+		HistoryEvent:     startedEvent,
+	}))
+
+	_, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, b.CompleteWorkflowTask(ctx, wfi, []history.Event{
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowTaskStarted, &history.WorkflowTaskStartedAttributes{}),
+		startedEvent,
+		lowEvent,
+		highEvent,
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowTaskFinished, &history.WorkflowTaskFinishedAttributes{}),
+	}, []history.WorkflowEvent{}))
+
+	// Activities are returned in the order they were scheduled, across the streams of all priorities
+	low, err := b.GetActivityTask(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, low)
+	require.Equal(t, lowEvent.ID, low.Event.ID)
+
+	high, err := b.GetActivityTask(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, high)
+	require.Equal(t, highEvent.ID, high.Event.ID)
+
+	none, err := b.GetActivityTask(ctx)
+	require.NoError(t, err)
+	require.Nil(t, none)
+
+	// Activities of both streams can be completed
+	result := history.NewHistoryEvent(time.Now(), history.EventType_ActivityCompleted, &history.ActivityCompletedAttributes{}, history.ScheduleEventID(1))
+	require.NoError(t, b.CompleteActivityTask(ctx, wfi, low.ID, result))
+	require.NoError(t, b.ExtendActivityTask(ctx, high.ID))
+
+	result = history.NewHistoryEvent(time.Now(), history.EventType_ActivityCompleted, &history.ActivityCompletedAttributes{}, history.ScheduleEventID(2))
+	require.NoError(t, b.CompleteActivityTask(ctx, wfi, high.ID, result))
+
+	n, err := rdb.XLen(ctx, activityStreamKey("10")).Result()
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func Test_RedisBackend_WorkflowPriority_ManyReadyInstances(t *testing.T) {
+	ctx := context.Background()
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	// Always honor priorities
+	b := NewRedisBackend(rdb, backend.WithStickyTimeout(0), backend.WithPriorityFairness(0))
+
+	createInstance := func(priority int) string {
+		wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+		require.NoError(t, b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+			WorkflowInstance: wfi,
+			HistoryEvent:     history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Priority: priority}),
+		}))
+
+		return wfi.GetInstanceID()
+	}
+
+	for i := 0; i < 150; i++ {
+		createInstance(0)
+	}
+
+	// The high priority instance is found, even if many instances have been ready longer
+	high := createInstance(10)
+
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, task)
+	require.Equal(t, high, task.WorkflowInstance.GetInstanceID())
+}
@@ -0,0 +1,173 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/schedule"
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+)
+
+// scheduleState is a schedule, as expected by the scripts
+type scheduleState struct {
+	ID      string `json:"id"`
+	Version int64  `json:"version,omitempty"`
+	Data    string `json:"data"`
+	DueAt   string `json:"due_at,omitempty"`
+}
+
+func newScheduleState(s *schedule.Schedule) (scheduleState, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return scheduleState{}, errors.Wrap(err, "could not marshal schedule")
+	}
+
+	state := scheduleState{
+		ID:      s.ID,
+		Version: s.Version,
+		Data:    string(data),
+	}
+
+	if s.DueAt != nil {
+		state.DueAt = score(*s.DueAt)
+	}
+
+	return state, nil
+}
+
+func (b *redisBackend) CreateSchedule(ctx context.Context, s *schedule.Schedule) error {
+	state, err := newScheduleState(s)
+	if err != nil {
+		return err
+	}
+
+	res, err := runIntScript(ctx, b.rdb, createScheduleCmd, state)
+	if err != nil {
+		return errors.Wrap(err, "could not create schedule")
+	}
+
+	if res == 0 {
+		return backend.ErrScheduleAlreadyExists
+	}
+
+	s.Version = 1
+
+	b.NotifySchedules()
+
+	return nil
+}
+
+func (b *redisBackend) GetSchedule(ctx context.Context, scheduleID string) (*schedule.Schedule, error) {
+	schedules, err := b.getSchedules(ctx, []string{scheduleID})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(schedules) == 0 {
+		return nil, backend.ErrScheduleNotFound
+	}
+
+	return schedules[0], nil
+}
+
+func (b *redisBackend) ListSchedules(ctx context.Context) ([]*schedule.Schedule, error) {
+	ids, err := b.rdb.ZRange(ctx, schedulesKey, 0, -1).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list schedules")
+	}
+
+	return b.getSchedules(ctx, ids)
+}
+
+func (b *redisBackend) GetDueSchedules(ctx context.Context, now time.Time, limit int) ([]*schedule.Schedule, error) {
+	ids, err := b.rdb.ZRangeByScore(ctx, schedulesDueKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   score(now),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get due schedules")
+	}
+
+	return b.getSchedules(ctx, ids)
+}
+
+func (b *redisBackend) UpdateSchedule(ctx context.Context, s *schedule.Schedule) error {
+	state, err := newScheduleState(s)
+	if err != nil {
+		return err
+	}
+
+	res, err := runIntScript(ctx, b.rdb, updateScheduleCmd, state)
+	if err != nil {
+		return errors.Wrap(err, "could not update schedule")
+	}
+
+	switch res {
+	case 0:
+		return backend.ErrScheduleNotFound
+	case -1:
+		return backend.ErrScheduleModified
+	}
+
+	s.Version++
+
+	b.NotifySchedules()
+
+	return nil
+}
+
+func (b *redisBackend) DeleteSchedule(ctx context.Context, scheduleID string) error {
+	res, err := runIntScript(ctx, b.rdb, deleteScheduleCmd, scheduleState{ID: scheduleID})
+	if err != nil {
+		return errors.Wrap(err, "could not delete schedule")
+	}
+
+	if res == 0 {
+		return backend.ErrScheduleNotFound
+	}
+
+	return nil
+}
+
+// getSchedules returns the given schedules, skipping schedules that were deleted concurrently
+func (b *redisBackend) getSchedules(ctx context.Context, ids []string) ([]*schedule.Schedule, error) {
+	cmds := make([]*redis.SliceCmd, 0, len(ids))
+	if _, err := b.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, id := range ids {
+			cmds = append(cmds, p.HMGet(ctx, scheduleKey(id), "version", "data"))
+		}
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "could not get schedules")
+	}
+
+	schedules := make([]*schedule.Schedule, 0, len(ids))
+	for _, cmd := range cmds {
+		fields := cmd.Val()
+		if fields[0] == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(fields[0].(string), 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not parse schedule version")
+		}
+
+		var s schedule.Schedule
+		if err := json.Unmarshal([]byte(fields[1].(string)), &s); err != nil {
+			return nil, errors.Wrap(err, "could not unmarshal schedule")
+		}
+
+		s.Version = version
+
+		schedules = append(schedules, &s)
+	}
+
+	return schedules, nil
+}
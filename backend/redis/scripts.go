@@ -0,0 +1,98 @@
+package redis
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+)
+
+//go:embed scripts/lib.lua
+var lib string
+
+//go:embed scripts/create_workflow_instance.lua
+var createWorkflowInstanceScript string
+
+//go:embed scripts/add_pending_events.lua
+var addPendingEventsScript string
+
+//go:embed scripts/remove_workflow_instance.lua
+var removeWorkflowInstanceScript string
+
+//go:embed scripts/lock_workflow_task.lua
+var lockWorkflowTaskScript string
+
+//go:embed scripts/extend_workflow_task.lua
+var extendWorkflowTaskScript string
+
+//go:embed scripts/unlock_workflow_task.lua
+var unlockWorkflowTaskScript string
+
+//go:embed scripts/complete_workflow_task.lua
+var completeWorkflowTaskScript string
+
+//go:embed scripts/lock_activity_task.lua
+var lockActivityTaskScript string
+
+//go:embed scripts/extend_activity_task.lua
+var extendActivityTaskScript string
+
+//go:embed scripts/complete_activity_task.lua
+var completeActivityTaskScript string
+
+//go:embed scripts/create_schedule.lua
+var createScheduleScript string
+
+//go:embed scripts/update_schedule.lua
+var updateScheduleScript string
+
+//go:embed scripts/delete_schedule.lua
+var deleteScheduleScript string
+
+// Scripts are executed atomically by Redis. Each one is prepended with the shared helpers and
+// receives its arguments as a single JSON encoded value.
+var (
+	createWorkflowInstanceCmd = newScript(createWorkflowInstanceScript)
+	addPendingEventsCmd       = newScript(addPendingEventsScript)
+	removeWorkflowInstanceCmd = newScript(removeWorkflowInstanceScript)
+	lockWorkflowTaskCmd       = newScript(lockWorkflowTaskScript)
+	extendWorkflowTaskCmd     = newScript(extendWorkflowTaskScript)
+	unlockWorkflowTaskCmd     = newScript(unlockWorkflowTaskScript)
+	completeWorkflowTaskCmd   = newScript(completeWorkflowTaskScript)
+	lockActivityTaskCmd       = newScript(lockActivityTaskScript)
+	extendActivityTaskCmd     = newScript(extendActivityTaskScript)
+	completeActivityTaskCmd   = newScript(completeActivityTaskScript)
+	createScheduleCmd         = newScript(createScheduleScript)
+	updateScheduleCmd         = newScript(updateScheduleScript)
+	deleteScheduleCmd         = newScript(deleteScheduleScript)
+)
+
+func newScript(script string) *redis.Script {
+	return redis.NewScript(lib + "\n" + script)
+}
+
+func runScript(ctx context.Context, rdb *redis.Client, script *redis.Script, args interface{}) (interface{}, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal script arguments")
+	}
+
+	return script.Run(ctx, rdb, nil, string(data)).Result()
+}
+
+// runIntScript runs a script returning an integer status code
+func runIntScript(ctx context.Context, rdb *redis.Client, script *redis.Script, args interface{}) (int64, error) {
+	res, err := runScript(ctx, rdb, script, args)
+	if err != nil {
+		return 0, err
+	}
+
+	n, ok := res.(int64)
+	if !ok {
+		return 0, errors.Errorf("unexpected script result %v", res)
+	}
+
+	return n, nil
+}
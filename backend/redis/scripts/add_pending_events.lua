@@ -0,0 +1,7 @@
+if redis.call("EXISTS", instanceKey(args.instance_id)) == 0 then
+	return redis.error_reply("workflow instance does not exist")
+end
+
+addPendingEvents(args.instance_id, args.events)
+
+return true
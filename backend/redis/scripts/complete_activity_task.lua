@@ -0,0 +1,17 @@
+local ref = redis.call("HGET", activityIDsKey, args.activity_id)
+
+if not ref or redis.call("HGET", activityWorkersKey, ref) ~= args.worker then
+	return redis.error_reply("could not find locked activity")
+end
+
+-- Remove activity
+local stream, id = parseActivityRef(ref)
+redis.call("XACK", stream, args.group, id)
+redis.call("XDEL", stream, id)
+redis.call("ZREM", activitiesLockedKey, ref)
+redis.call("HDEL", activityIDsKey, args.activity_id)
+redis.call("HDEL", activityWorkersKey, ref)
+
+addPendingEvents(args.instance_id, args.events)
+
+return true
@@ -0,0 +1,50 @@
+local instanceID = args.instance_id
+local key = instanceKey(instanceID)
+local state = redis.call("HMGET", key, "execution_id", "worker")
+
+if not redis.call("ZSCORE", lockedKey, instanceID) or state[1] ~= args.execution_id or state[2] ~= args.worker then
+	return redis.error_reply("could not find workflow instance to unlock")
+end
+
+-- Unlock instance, but keep it sticky to the current worker
+redis.call("ZREM", lockedKey, instanceID)
+redis.call("HSET", key, "sticky_until", args.sticky_until)
+
+-- Remove handled events
+local executed = {}
+for _, id in ipairs(args.executed_event_ids or {}) do
+	executed[id] = true
+end
+
+for _, entry in ipairs(redis.call("XRANGE", pendingEventsKey(instanceID), "-", "+")) do
+	if executed[field(entry, "id")] then
+		redis.call("XDEL", pendingEventsKey(instanceID), entry[1])
+	end
+end
+
+-- Add executed events to the history
+for _, e in ipairs(args.history_events or {}) do
+	redis.call("XADD", historyKey(instanceID), "*", "event", e)
+end
+
+-- Schedule activities
+for _, a in ipairs(args.activities or {}) do
+	scheduleActivity(args.group, a.priority or 0, instanceID, args.execution_id, a)
+end
+
+if args.completed_at then
+	redis.call("HSET", key, "completed_at", args.completed_at)
+end
+
+-- Add new events for this and other workflow instances
+for _, w in ipairs(args.workflow_events or {}) do
+	if w.instance.instance_id ~= instanceID then
+		createInstance(w.instance)
+	end
+
+	addPendingEvents(w.instance.instance_id, w.events)
+end
+
+updateReady(instanceID)
+
+return true
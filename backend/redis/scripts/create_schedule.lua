@@ -0,0 +1,11 @@
+-- Returns 0 if the schedule already exists, and 1 if it was created
+local key = scheduleKey(args.id)
+if redis.call("EXISTS", key) == 1 then
+	return 0
+end
+
+redis.call("HSET", key, "version", 1, "data", args.data)
+redis.call("ZADD", schedulesKey, 0, args.id)
+setScheduleDueAt(args.id, args.due_at)
+
+return 1
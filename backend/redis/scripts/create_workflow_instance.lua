@@ -0,0 +1,4 @@
+createInstance(args.instance)
+addPendingEvents(args.instance.instance_id, args.events)
+
+return true
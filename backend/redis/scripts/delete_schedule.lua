@@ -0,0 +1,9 @@
+-- Returns 0 if the schedule doesn't exist, and 1 if it was deleted
+if redis.call("DEL", scheduleKey(args.id)) == 0 then
+	return 0
+end
+
+redis.call("ZREM", schedulesKey, args.id)
+redis.call("ZREM", schedulesDueKey, args.id)
+
+return 1
@@ -0,0 +1,9 @@
+local ref = redis.call("HGET", activityIDsKey, args.activity_id)
+
+if not ref or redis.call("HGET", activityWorkersKey, ref) ~= args.worker then
+	return redis.error_reply("could not extend activity")
+end
+
+redis.call("ZADD", activitiesLockedKey, args.locked_until, ref)
+
+return true
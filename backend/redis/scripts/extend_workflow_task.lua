@@ -0,0 +1,10 @@
+local key = instanceKey(args.instance_id)
+local state = redis.call("HMGET", key, "execution_id", "worker")
+
+if not redis.call("ZSCORE", lockedKey, args.instance_id) or state[1] ~= args.execution_id or state[2] ~= args.worker then
+	return redis.error_reply("could not extend workflow task")
+end
+
+redis.call("ZADD", lockedKey, args.locked_until, args.instance_id)
+
+return true
@@ -0,0 +1,200 @@
+-- Shared helpers, prepended to every script. Keys have to match the ones in keys.go.
+
+local readyKey = "instances:ready"
+local readyPrioritiesKey = "instances:ready:priorities"
+local lockedKey = "instances:locked"
+local activitiesKey = "activities"
+local activitiesLockedKey = "activities:locked"
+local activityIDsKey = "activities:ids"
+local activityWorkersKey = "activities:workers"
+local activityPrioritiesKey = "activities:priorities"
+local activityCursorsKey = "activities:cursors"
+local activitySeqKey = "activities:seq"
+local instancesKey = "instances"
+local schedulesKey = "schedules"
+local schedulesDueKey = "schedules:due"
+
+local function instanceKey(instanceID)
+	return "instance:" .. instanceID
+end
+
+local function readyPriorityKey(priority)
+	return readyKey .. ":" .. priority
+end
+
+local function subInstancesKey(instanceID)
+	return "sub-instances:" .. instanceID
+end
+
+local function pendingEventsKey(instanceID)
+	return "pending-events:" .. instanceID
+end
+
+local function historyKey(instanceID)
+	return "history:" .. instanceID
+end
+
+local function scheduleKey(scheduleID)
+	return "schedule:" .. scheduleID
+end
+
+-- activityStreamKey returns the stream of activities with the given priority. Activities without
+-- a priority are kept in the activities stream.
+local function activityStreamKey(priority)
+	if tonumber(priority) == 0 then
+		return activitiesKey
+	end
+
+	return activitiesKey .. ":" .. priority
+end
+
+-- activityRef returns the reference to an activity stream entry used to track locked activities.
+-- References to activities without a priority are just the entry ID.
+local function activityRef(priority, id)
+	if tonumber(priority) == 0 then
+		return id
+	end
+
+	return priority .. "/" .. id
+end
+
+-- parseActivityRef returns the stream and the entry ID of the given activity reference
+local function parseActivityRef(ref)
+	local priority, id = string.match(ref, "^(-?%d+)/(.+)$")
+	if not priority then
+		return activitiesKey, ref
+	end
+
+	return activityStreamKey(priority), id
+end
+
+-- scheduleActivity adds the given activity to the stream of its priority, creating the stream
+-- and its consumer group if necessary
+local function scheduleActivity(group, priority, instanceID, executionID, activity)
+	local stream = activityStreamKey(priority)
+	if redis.call("EXISTS", stream) == 0 then
+		redis.call("XGROUP", "CREATE", stream, group, "0", "MKSTREAM")
+	end
+
+	-- Entry IDs are only unique per stream, the sequence number orders activities of all priorities
+	local seq = redis.call("INCR", activitySeqKey)
+
+	redis.call("ZADD", activityPrioritiesKey, priority, priority)
+	redis.call("XADD", stream, "*", "id", activity.id, "instance_id", instanceID, "execution_id", executionID, "event", activity.event, "seq", seq)
+end
+
+-- setScheduleDueAt updates when the given schedule is due, or removes it from the due schedules
+local function setScheduleDueAt(scheduleID, dueAt)
+	if dueAt then
+		redis.call("ZADD", schedulesDueKey, dueAt, scheduleID)
+	else
+		redis.call("ZREM", schedulesDueKey, scheduleID)
+	end
+end
+
+-- field returns the value of the given field of a stream entry
+local function field(entry, name)
+	local fields = entry[2]
+	for i = 1, #fields, 2 do
+		if fields[i] == name then
+			return fields[i + 1]
+		end
+	end
+
+	return nil
+end
+
+-- createInstance creates the given workflow instance if it doesn't exist yet
+local function createInstance(instance)
+	local key = instanceKey(instance.instance_id)
+	if redis.call("EXISTS", key) == 1 then
+		return
+	end
+
+	local fields = {
+		"instance_id", instance.instance_id,
+		"execution_id", instance.execution_id,
+		"created_at", instance.created_at,
+	}
+
+	if instance.start_at then
+		table.insert(fields, "start_at")
+		table.insert(fields, instance.start_at)
+	end
+
+	if instance.priority then
+		table.insert(fields, "priority")
+		table.insert(fields, instance.priority)
+	end
+
+	if instance.parent_instance_id then
+		table.insert(fields, "parent_instance_id")
+		table.insert(fields, instance.parent_instance_id)
+		table.insert(fields, "parent_event_id")
+		table.insert(fields, instance.parent_event_id)
+
+		if instance.parent_execution_id then
+			table.insert(fields, "parent_execution_id")
+			table.insert(fields, instance.parent_execution_id)
+		end
+
+		redis.call("SADD", subInstancesKey(instance.parent_instance_id), instance.instance_id)
+	end
+
+	redis.call("HSET", key, unpack(fields))
+	redis.call("ZADD", instancesKey, 0, instance.instance_id)
+end
+
+-- setReady marks the instance as ready at the given time, or as not ready if readyAt is nil. Ready
+-- instances are tracked in a sorted set of all instances, and in a sorted set per priority.
+-- priority is used for instances that don't exist anymore.
+local function setReady(instanceID, readyAt, priority)
+	priority = redis.call("HGET", instanceKey(instanceID), "priority") or priority or "0"
+
+	if readyAt then
+		redis.call("ZADD", readyKey, readyAt, instanceID)
+		redis.call("ZADD", readyPriorityKey(priority), readyAt, instanceID)
+		redis.call("ZADD", readyPrioritiesKey, priority, priority)
+	else
+		redis.call("ZREM", readyKey, instanceID)
+		redis.call("ZREM", readyPriorityKey(priority), instanceID)
+	end
+end
+
+-- addPendingEvents adds the given events to the pending events of the instance and marks the
+-- instance as ready once the earliest event becomes visible. Events for instances scheduled to
+-- start in the future only become visible once the instance started.
+local function addPendingEvents(instanceID, events)
+	local startAt = redis.call("HGET", instanceKey(instanceID), "start_at")
+
+	for _, e in ipairs(events) do
+		if startAt and tonumber(e.visible_at) < tonumber(startAt) then
+			e.visible_at = startAt
+		end
+
+		redis.call("XADD", pendingEventsKey(instanceID), "*", "id", e.id, "visible_at", e.visible_at, "event", e.event)
+
+		local current = redis.call("ZSCORE", readyKey, instanceID)
+		if not current or tonumber(e.visible_at) < tonumber(current) then
+			setReady(instanceID, e.visible_at)
+		end
+	end
+end
+
+-- updateReady sets the ready score of the instance to the time when the earliest pending event
+-- becomes visible, or removes it if there are no pending events
+local function updateReady(instanceID)
+	local entries = redis.call("XRANGE", pendingEventsKey(instanceID), "-", "+")
+
+	local earliest = nil
+	for _, entry in ipairs(entries) do
+		local visibleAt = tonumber(field(entry, "visible_at"))
+		if earliest == nil or visibleAt < earliest then
+			earliest = visibleAt
+		end
+	end
+
+	setReady(instanceID, earliest)
+end
+
+local args = cjson.decode(ARGV[1])
@@ -0,0 +1,69 @@
+-- nextActivitySeq returns the sequence number of the oldest entry of the stream with the given
+-- priority that hasn't been read yet, or nil if there is none
+local function nextActivitySeq(priority)
+	local lastID = redis.call("HGET", activityCursorsKey, priority) or "0-0"
+
+	for _, entry in ipairs(redis.call("XRANGE", activityStreamKey(priority), lastID, "+", "COUNT", 2)) do
+		if entry[1] ~= lastID then
+			-- Activities scheduled before sequence numbers were recorded are the oldest
+			return tonumber(field(entry, "seq") or 0)
+		end
+	end
+
+	return nil
+end
+
+-- Prefer activities whose lock has expired, otherwise read a new activity from the streams. If
+-- priorities are honored, the stream with the highest priority is read first, otherwise the one
+-- whose next activity was scheduled first.
+local ref, stream, id
+local expired = redis.call("ZRANGEBYSCORE", activitiesLockedKey, "-inf", args.now, "LIMIT", 0, 1)
+
+if #expired > 0 then
+	ref = expired[1]
+	stream, id = parseActivityRef(ref)
+	redis.call("XCLAIM", stream, args.group, args.worker, 0, id)
+else
+	local priorities = redis.call("ZREVRANGE", activityPrioritiesKey, 0, -1)
+
+	if not args.honor_priority then
+		local oldestPriority, oldestSeq
+		for _, priority in ipairs(priorities) do
+			local seq = nextActivitySeq(priority)
+			if seq and (not oldestSeq or seq < oldestSeq) then
+				oldestPriority, oldestSeq = priority, seq
+			end
+		end
+
+		if oldestPriority then
+			table.insert(priorities, 1, oldestPriority)
+		end
+	end
+
+	for _, priority in ipairs(priorities) do
+		stream = activityStreamKey(priority)
+		local res = redis.call("XREADGROUP", "GROUP", args.group, args.worker, "COUNT", 1, "STREAMS", stream, ">")
+		if res and res[1] and #res[1][2] > 0 then
+			id = res[1][2][1][1]
+			ref = activityRef(priority, id)
+			redis.call("HSET", activityCursorsKey, priority, id)
+			break
+		end
+	end
+
+	if not ref then
+		return false
+	end
+end
+
+local entries = redis.call("XRANGE", stream, id, id)
+if #entries == 0 then
+	redis.call("ZREM", activitiesLockedKey, ref)
+	return false
+end
+
+redis.call("ZADD", activitiesLockedKey, args.locked_until, ref)
+redis.call("HSET", activityIDsKey, field(entries[1], "id"), ref)
+redis.call("HSET", activityWorkersKey, ref, args.worker)
+
+return entries[1]
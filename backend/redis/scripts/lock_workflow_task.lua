@@ -0,0 +1,65 @@
+-- Find an instance with visible pending events, which isn't locked and isn't sticky to another worker.
+-- If priorities are honored, the instance with the highest priority is locked, otherwise the one
+-- that has been ready the longest.
+local now = tonumber(args.now)
+local pageSize = 100
+
+-- findReady returns the instance that has been ready the longest among the instances in the given
+-- sorted set that can be locked, and whether it's sticky to this worker
+local function findReady(key, priority)
+	local offset = 0
+
+	while true do
+		local instanceIDs = redis.call("ZRANGEBYSCORE", key, "-inf", args.now, "LIMIT", offset, pageSize)
+		local removed = 0
+
+		for _, instanceID in ipairs(instanceIDs) do
+			local lockedUntil = redis.call("ZSCORE", lockedKey, instanceID)
+			if not lockedUntil or tonumber(lockedUntil) < now then
+				local state = redis.call("HMGET", instanceKey(instanceID), "instance_id", "completed_at", "worker", "sticky_until")
+
+				if not state[1] or state[2] then
+					-- Instance does not exist or is already completed, don't consider it again
+					setReady(instanceID, nil, priority)
+					removed = removed + 1
+				else
+					local sticky = state[4] and tonumber(state[4]) > now
+					if not sticky or state[3] == args.worker then
+						return instanceID, sticky
+					end
+				end
+			end
+		end
+
+		if #instanceIDs < pageSize then
+			return nil
+		end
+
+		offset = offset + pageSize - removed
+	end
+end
+
+local lockedID, lockedSticky
+
+if args.honor_priority then
+	for _, priority in ipairs(redis.call("ZREVRANGE", readyPrioritiesKey, 0, -1)) do
+		lockedID, lockedSticky = findReady(readyPriorityKey(priority), priority)
+		if lockedID then
+			break
+		end
+	end
+end
+
+-- This also finds instances that became ready before they were tracked per priority
+if not lockedID then
+	lockedID, lockedSticky = findReady(readyKey)
+end
+
+if not lockedID then
+	return false
+end
+
+redis.call("ZADD", lockedKey, args.locked_until, lockedID)
+redis.call("HSET", instanceKey(lockedID), "worker", args.worker)
+
+return { lockedID, lockedSticky and 1 or 0 }
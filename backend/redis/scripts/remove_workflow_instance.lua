@@ -0,0 +1,23 @@
+-- Returns 0 if the instance doesn't exist, -1 if it's not completed, and 1 if it was removed
+local instanceID = args.instance_id
+local key = instanceKey(instanceID)
+local state = redis.call("HMGET", key, "execution_id", "completed_at", "parent_instance_id")
+
+if not state[1] or state[1] ~= args.execution_id then
+	return 0
+end
+
+if not state[2] then
+	return -1
+end
+
+setReady(instanceID, nil)
+redis.call("DEL", key, pendingEventsKey(instanceID), historyKey(instanceID), subInstancesKey(instanceID))
+redis.call("ZREM", lockedKey, instanceID)
+redis.call("ZREM", instancesKey, instanceID)
+
+if state[3] then
+	redis.call("SREM", subInstancesKey(state[3]), instanceID)
+end
+
+return 1
@@ -0,0 +1,4 @@
+redis.call("ZREM", lockedKey, args.instance_id)
+updateReady(args.instance_id)
+
+return true
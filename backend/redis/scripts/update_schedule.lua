@@ -0,0 +1,16 @@
+-- Returns 0 if the schedule doesn't exist, -1 if its version doesn't match, and 1 if it was updated
+local key = scheduleKey(args.id)
+local version = redis.call("HGET", key, "version")
+
+if not version then
+	return 0
+end
+
+if tonumber(version) ~= args.version then
+	return -1
+end
+
+redis.call("HSET", key, "version", args.version + 1, "data", args.data)
+setScheduleDueAt(args.id, args.due_at)
+
+return 1
@@ -4,11 +4,12 @@ import (
 	"context"
 	"database/sql"
 
+	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/internal/history"
 )
 
-func scheduleActivity(ctx context.Context, tx *sql.Tx, instanceID, executionID string, event history.Event) error {
-	attributes, err := history.SerializeAttributes(event.Attributes)
+func (sb *sqliteBackend) scheduleActivity(ctx context.Context, tx *sql.Tx, instanceID, executionID string, event history.Event) error {
+	attributes, err := sb.serializeAttributes(event.Attributes)
 	if err != nil {
 		return err
 	}
@@ -16,7 +17,7 @@ func scheduleActivity(ctx context.Context, tx *sql.Tx, instanceID, executionID s
 	_, err = tx.ExecContext(
 		ctx,
 		`INSERT INTO activities
-			(id, instance_id, execution_id, event_type, timestamp, schedule_event_id, attributes, visible_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			(id, instance_id, execution_id, event_type, timestamp, schedule_event_id, attributes, visible_at, priority) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		event.ID,
 		instanceID,
 		executionID,
@@ -25,6 +26,7 @@ func scheduleActivity(ctx context.Context, tx *sql.Tx, instanceID, executionID s
 		event.ScheduleEventID,
 		attributes,
 		event.VisibleAt,
+		backend.ActivityPriority(event),
 	)
 
 	return err
@@ -75,15 +75,19 @@ func scanEvent(row Scanner) (history.Event, error) {
 	return historyEvent, nil
 }
 
-func insertNewEvents(ctx context.Context, tx *sql.Tx, instanceID string, newEvents []history.Event) error {
-	return insertEvents(ctx, tx, "pending_events", instanceID, newEvents)
+func (sb *sqliteBackend) serializeAttributes(attributes interface{}) ([]byte, error) {
+	return history.SerializeAttributes(attributes, history.WithCompression(sb.options.Compression, sb.options.CompressionThreshold))
 }
 
-func insertHistoryEvents(ctx context.Context, tx *sql.Tx, instanceID string, historyEvents []history.Event) error {
-	return insertEvents(ctx, tx, "history", instanceID, historyEvents)
+func (sb *sqliteBackend) insertNewEvents(ctx context.Context, tx *sql.Tx, instanceID string, newEvents []history.Event) error {
+	return sb.insertEvents(ctx, tx, "pending_events", instanceID, newEvents)
 }
 
-func insertEvents(ctx context.Context, tx *sql.Tx, tableName string, instanceID string, events []history.Event) error {
+func (sb *sqliteBackend) insertHistoryEvents(ctx context.Context, tx *sql.Tx, instanceID string, historyEvents []history.Event) error {
+	return sb.insertEvents(ctx, tx, "history", instanceID, historyEvents)
+}
+
+func (sb *sqliteBackend) insertEvents(ctx context.Context, tx *sql.Tx, tableName string, instanceID string, events []history.Event) error {
 	const batchSize = 20
 	for batchStart := 0; batchStart < len(events); batchStart += batchSize {
 		batchEnd := batchStart + batchSize
@@ -98,7 +102,7 @@ func insertEvents(ctx context.Context, tx *sql.Tx, tableName string, instanceID
 		args := make([]interface{}, 0, len(batchEvents)*7)
 
 		for _, newEvent := range batchEvents {
-			a, err := history.SerializeAttributes(newEvent.Attributes)
+			a, err := sb.serializeAttributes(newEvent.Attributes)
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,36 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/cschleiden/go-workflows/internal/metrickeys"
+	"github.com/cschleiden/go-workflows/metrics"
+)
+
+// measureQueueDepth returns the number of pending events and activities
+func (sb *sqliteBackend) measureQueueDepth(ctx context.Context) (map[string]int64, error) {
+	var pendingEvents, pendingActivities int64
+	if err := sb.db.QueryRowContext(
+		ctx,
+		"SELECT (SELECT COUNT(*) FROM pending_events), (SELECT COUNT(*) FROM activities)",
+	).Scan(&pendingEvents, &pendingActivities); err != nil {
+		return nil, err
+	}
+
+	return map[string]int64{
+		metrickeys.PendingEvents:     pendingEvents,
+		metrickeys.PendingActivities: pendingActivities,
+	}, nil
+}
+
+func (sb *sqliteBackend) recordLockExtension(taskType string, err error) {
+	result := metrickeys.ResultSuccess
+	if err != nil {
+		result = metrickeys.ResultError
+	}
+
+	sb.options.Metrics.Counter(metrickeys.LockExtensions, metrics.Tags{
+		metrickeys.Type:   taskType,
+		metrickeys.Result: result,
+	}, 1)
+}
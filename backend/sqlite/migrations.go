@@ -0,0 +1,49 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+
+	"github.com/cschleiden/go-workflows/internal/migrations"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// Migrate applies all pending schema migrations
+func (sb *sqliteBackend) Migrate(ctx context.Context) error {
+	m, err := migrations.Load(migrationsFS, "migrations")
+	if err != nil {
+		return err
+	}
+
+	return migrations.Migrate(ctx, sb.db, dialect{}, m)
+}
+
+// dialect runs all migrations in a single write transaction. Other connections can't write
+// until it completes, and a failed migration is rolled back completely.
+type dialect struct{}
+
+func (dialect) Lock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE")
+	return err
+}
+
+func (dialect) Unlock(ctx context.Context, conn *sql.Conn, failed bool) error {
+	if failed {
+		_, err := conn.ExecContext(ctx, "ROLLBACK")
+		return err
+	}
+
+	_, err := conn.ExecContext(ctx, "COMMIT")
+	return err
+}
+
+func (dialect) CreateVersionTable() string {
+	return "CREATE TABLE IF NOT EXISTS `schema_version` (`version` INTEGER PRIMARY KEY, `applied_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP)"
+}
+
+func (dialect) InsertVersion() string {
+	return "INSERT INTO `schema_version` (`version`) VALUES (?)"
+}
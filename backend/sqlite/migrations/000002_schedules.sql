@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS `schedules` (
+  `id` TEXT PRIMARY KEY,
+  `version` INTEGER NOT NULL,
+  `due_at` DATETIME NULL,
+  `data` BLOB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS `idx_schedules_due_at` ON `schedules` (`due_at`);
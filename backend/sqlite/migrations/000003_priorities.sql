@@ -0,0 +1,7 @@
+ALTER TABLE `instances` ADD COLUMN `priority` INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS `idx_instances_completed_at_priority` ON `instances` (`completed_at`, `priority` DESC);
+
+ALTER TABLE `activities` ADD COLUMN `priority` INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS `idx_activities_priority` ON `activities` (`priority` DESC);
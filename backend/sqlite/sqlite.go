@@ -3,14 +3,18 @@ package sqlite
 import (
 	"context"
 	"database/sql"
-	_ "embed"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/internal/metrickeys"
+	"github.com/cschleiden/go-workflows/internal/retention"
+	"github.com/cschleiden/go-workflows/internal/sqlbackend"
 	"github.com/cschleiden/go-workflows/internal/task"
 	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/google/uuid"
@@ -19,43 +23,122 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-//go:embed schema.sql
-var schema string
-
 func NewInMemoryBackend(opts ...backend.BackendOption) backend.Backend {
-	b := newSqliteBackend("file::memory:", opts...)
+	db, err := sql.Open("sqlite3", "file::memory:")
+	if err != nil {
+		panic(err)
+	}
 
-	b.db.SetMaxOpenConns(1)
+	// Every connection to an in-memory database gets its own database, so all queries have to
+	// share a single connection.
+	db.SetMaxOpenConns(1)
+
+	b, err := newSqliteBackend(db, backend.ApplyOptions(opts...))
+	if err != nil {
+		panic(err)
+	}
+
+	b.ownsDB = true
 
 	return b
 }
 
 func NewSqliteBackend(path string, opts ...backend.BackendOption) backend.Backend {
-	return newSqliteBackend(fmt.Sprintf("file:%v", path), opts...)
+	b, err := NewSqliteBackendFromDSN(fmt.Sprintf("file:%v", path), opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return b
 }
 
-func newSqliteBackend(dsn string, opts ...backend.BackendOption) *sqliteBackend {
+// NewSqliteBackendFromDSN creates a backend using the SQLite database identified by the given
+// data source name. The connection pool can be configured using backend.WithConnectionPool.
+func NewSqliteBackendFromDSN(dsn string, opts ...backend.BackendOption) (backend.Backend, error) {
+	options := backend.ApplyOptions(opts...)
+
 	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
-		panic(err)
+		return nil, errors.Wrap(err, "could not open database")
 	}
 
-	// Initialize database
-	if _, err := db.Exec(schema); err != nil {
-		panic(err)
+	options.ConnectionPool.Apply(db)
+
+	b, err := newSqliteBackend(db, options)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	b.ownsDB = true
+
+	return b, nil
+}
+
+// NewSqliteBackendFromDB creates a backend using an existing database. The caller remains
+// responsible for configuring and closing db.
+func NewSqliteBackendFromDB(db *sql.DB, opts ...backend.BackendOption) (backend.Backend, error) {
+	b, err := newSqliteBackend(db, backend.ApplyOptions(opts...))
+	if err != nil {
+		return nil, err
 	}
 
-	return &sqliteBackend{
+	return b, nil
+}
+
+func newSqliteBackend(db *sql.DB, options backend.Options) (*sqliteBackend, error) {
+	b := &sqliteBackend{
 		db:         db,
 		workerName: fmt.Sprintf("worker-%v", uuid.NewString()),
-		options:    backend.ApplyOptions(opts...),
+		options:    options,
+
+		workflowFairness: backend.NewPriorityFairness(options.PriorityFairness),
+		activityFairness: backend.NewPriorityFairness(options.PriorityFairness),
+
+		Notifications: backend.NewNotifications(),
 	}
+
+	b.Store = sqlbackend.New(db, queries, options, b.Notifications)
+	b.queueDepth = backend.NewQueueDepth(options.Metrics, b.measureQueueDepth)
+
+	if options.ApplyMigrations {
+		if err := b.Migrate(context.Background()); err != nil {
+			return nil, errors.Wrap(err, "could not migrate database")
+		}
+	}
+
+	b.stopRetention = retention.Start(clock.New(), options.RetentionPeriod, retention.Interval, options.Logger, b.PurgeCompletedInstances)
+
+	return b, nil
 }
 
 type sqliteBackend struct {
 	db         *sql.DB
+	ownsDB     bool
 	workerName string
 	options    backend.Options
+
+	stopRetention func()
+
+	workflowFairness *backend.PriorityFairness
+	activityFairness *backend.PriorityFairness
+
+	*backend.Notifications
+	*sqlbackend.Store
+
+	queueDepth *backend.QueueDepth
+}
+
+// Close stops the background cleanup of completed instances and closes the database, if it was
+// opened by the backend
+func (sb *sqliteBackend) Close() error {
+	sb.stopRetention()
+
+	if !sb.ownsDB {
+		return nil
+	}
+
+	return sb.db.Close()
 }
 
 func (sb *sqliteBackend) CreateWorkflowInstance(ctx context.Context, m history.WorkflowEvent) error {
@@ -66,12 +149,12 @@ func (sb *sqliteBackend) CreateWorkflowInstance(ctx context.Context, m history.W
 	defer tx.Rollback()
 
 	// Create workflow instance
-	if err := createInstance(ctx, tx, m.WorkflowInstance); err != nil {
+	if err := createInstance(ctx, tx, m.WorkflowInstance, backend.InstancePriority(m.HistoryEvent)); err != nil {
 		return err
 	}
 
 	// Initial history is empty, store only new events
-	if err := insertNewEvents(ctx, tx, m.WorkflowInstance.GetInstanceID(), []history.Event{m.HistoryEvent}); err != nil {
+	if err := sb.insertNewEvents(ctx, tx, m.WorkflowInstance.GetInstanceID(), []history.Event{m.HistoryEvent}); err != nil {
 		return errors.Wrap(err, "could not insert new event")
 	}
 
@@ -79,10 +162,12 @@ func (sb *sqliteBackend) CreateWorkflowInstance(ctx context.Context, m history.W
 		return errors.Wrap(err, "could not create workflow instance")
 	}
 
+	sb.NotifyWorkflowTasks()
+
 	return nil
 }
 
-func createInstance(ctx context.Context, tx *sql.Tx, wfi workflow.Instance) error {
+func createInstance(ctx context.Context, tx *sql.Tx, wfi workflow.Instance, priority int) error {
 	var parentInstanceID *string
 	var parentEventID *int
 	if wfi.SubWorkflow() {
@@ -95,11 +180,12 @@ func createInstance(ctx context.Context, tx *sql.Tx, wfi workflow.Instance) erro
 
 	if _, err := tx.ExecContext(
 		ctx,
-		"INSERT OR IGNORE INTO `instances` (id, execution_id, parent_instance_id, parent_schedule_event_id) VALUES (?, ?, ?, ?)",
+		"INSERT OR IGNORE INTO `instances` (id, execution_id, parent_instance_id, parent_schedule_event_id, priority) VALUES (?, ?, ?, ?, ?)",
 		wfi.GetInstanceID(),
 		wfi.GetExecutionID(),
 		parentInstanceID,
 		parentEventID,
+		priority,
 	); err != nil {
 		return errors.Wrap(err, "could not insert workflow instance")
 	}
@@ -116,8 +202,15 @@ func (sb *sqliteBackend) CancelWorkflowInstance(ctx context.Context, instance wo
 
 	instanceID := instance.GetInstanceID()
 
+	startAt, err := sb.ScheduledStart(ctx, tx, instanceID)
+	if err != nil {
+		return err
+	}
+
 	// Cancel workflow instance
-	if err := insertNewEvents(ctx, tx, instanceID, []history.Event{history.NewWorkflowCancellationEvent(time.Now())}); err != nil {
+	events := []history.Event{history.NewWorkflowCancellationEvent(time.Now())}
+	backend.DelayUntilStart(events, startAt)
+	if err := sb.insertNewEvents(ctx, tx, instanceID, events); err != nil {
 		return errors.Wrap(err, "could not insert cancellation event")
 	}
 
@@ -136,14 +229,39 @@ func (sb *sqliteBackend) CancelWorkflowInstance(ctx context.Context, instance wo
 		}
 
 		// Cancel sub-workflow instance
-		if err := insertNewEvents(ctx, tx, subWorkflowInstanceID, []history.Event{history.NewWorkflowCancellationEvent(time.Now())}); err != nil {
+		if err := sb.insertNewEvents(ctx, tx, subWorkflowInstanceID, []history.Event{history.NewWorkflowCancellationEvent(time.Now())}); err != nil {
 			return errors.Wrap(err, "could not insert cancellation event")
 		}
 
 		instanceID = subWorkflowInstanceID
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	sb.NotifyWorkflowTasks()
+
+	return nil
+}
+
+func (sb *sqliteBackend) GetWorkflowInstanceHistory(ctx context.Context, instance workflow.Instance) ([]history.Event, error) {
+	tx, err := sb.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRowContext(ctx, "SELECT 1 FROM `instances` WHERE id = ? AND execution_id = ?", instance.GetInstanceID(), instance.GetExecutionID()).Scan(&exists); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, backend.ErrInstanceNotFound
+		}
+
+		return nil, errors.Wrap(err, "could not get workflow instance")
+	}
+
+	return getHistory(ctx, tx, instance.GetInstanceID())
 }
 
 func (sb *sqliteBackend) SignalWorkflow(ctx context.Context, instanceID string, event history.Event) error {
@@ -153,14 +271,29 @@ func (sb *sqliteBackend) SignalWorkflow(ctx context.Context, instanceID string,
 	}
 	defer tx.Rollback()
 
-	if err := insertNewEvents(ctx, tx, instanceID, []history.Event{event}); err != nil {
+	startAt, err := sb.ScheduledStart(ctx, tx, instanceID)
+	if err != nil {
+		return err
+	}
+
+	events := []history.Event{event}
+	backend.DelayUntilStart(events, startAt)
+	if err := sb.insertNewEvents(ctx, tx, instanceID, events); err != nil {
 		return errors.Wrap(err, "could not insert signal event")
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	sb.NotifyWorkflowTasks()
+
+	return nil
 }
 
 func (sb *sqliteBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, error) {
+	sb.queueDepth.Report(ctx)
+
 	tx, err := sb.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -168,7 +301,14 @@ func (sb *sqliteBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, e
 	defer tx.Rollback()
 
 	// Lock next workflow task by finding an unlocked instance with new events to process
-	// (work around missing LIMIT support in sqlite driver for UPDATE statements by using sub-query)
+	// (work around missing LIMIT support in sqlite driver for UPDATE statements by using sub-query).
+	// Polls ignoring priorities pick the instance whose earliest pending event became visible first,
+	// pending events that aren't visible yet are always later than that.
+	orderBy := "(SELECT MIN(COALESCE(visible_at, timestamp)) FROM pending_events WHERE instance_id = i.id), rowid"
+	if sb.workflowFairness.HonorPriority() {
+		orderBy = "priority DESC, rowid"
+	}
+
 	now := time.Now()
 	row := tx.QueryRowContext(
 		ctx,
@@ -185,6 +325,7 @@ func (sb *sqliteBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, e
 								FROM pending_events
 								WHERE instance_id = i.id AND execution_id = i.execution_id AND (visible_at IS NULL OR visible_at <= ?)
 						)
+					ORDER BY `+orderBy+`
 					LIMIT 1
 			) RETURNING id, execution_id, parent_instance_id, parent_schedule_event_id, sticky_until`,
 		now.Add(sb.options.WorkflowLockTimeout), // new locked_until
@@ -265,6 +406,13 @@ func (sb *sqliteBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, e
 		return nil, err
 	}
 
+	sb.options.Logger.Debug("Locked workflow task",
+		log.InstanceIDKey, instanceID,
+		log.ExecutionIDKey, executionID,
+		"new_events", len(t.NewEvents),
+		"continuation", kind == task.Continuation,
+	)
+
 	return t, nil
 }
 
@@ -316,7 +464,7 @@ func (sb *sqliteBackend) CompleteWorkflowTask(
 	}
 
 	// Add events from last execution to history
-	if err := insertHistoryEvents(ctx, tx, instance.GetInstanceID(), executedEvents); err != nil {
+	if err := sb.insertHistoryEvents(ctx, tx, instance.GetInstanceID(), executedEvents); err != nil {
 		return errors.Wrap(err, "could not insert new history events")
 	}
 
@@ -326,7 +474,7 @@ func (sb *sqliteBackend) CompleteWorkflowTask(
 	for _, event := range executedEvents {
 		switch event.Type {
 		case history.EventType_ActivityScheduled:
-			if err := scheduleActivity(ctx, tx, instance.GetInstanceID(), instance.GetExecutionID(), event); err != nil {
+			if err := sb.scheduleActivity(ctx, tx, instance.GetInstanceID(), instance.GetExecutionID(), event); err != nil {
 				return errors.Wrap(err, "could not schedule activity")
 			}
 
@@ -348,13 +496,13 @@ func (sb *sqliteBackend) CompleteWorkflowTask(
 	for targetInstance, events := range groupedEvents {
 		if instance.GetInstanceID() != targetInstance.GetInstanceID() {
 			// Create new instance
-			if err := createInstance(ctx, tx, targetInstance); err != nil {
+			if err := createInstance(ctx, tx, targetInstance, backend.InstancePriority(events...)); err != nil {
 				return err
 			}
 		}
 
 		// Insert pending events for target instance
-		if err := insertNewEvents(ctx, tx, targetInstance.GetInstanceID(), events); err != nil {
+		if err := sb.insertNewEvents(ctx, tx, targetInstance.GetInstanceID(), events); err != nil {
 			return errors.Wrap(err, "could not insert messages")
 		}
 	}
@@ -371,10 +519,29 @@ func (sb *sqliteBackend) CompleteWorkflowTask(
 		}
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if workflowCompleted && sb.options.ArchiveOnCompletion {
+		sb.ArchiveCompletedInstance(ctx, instance.GetInstanceID())
+	}
+
+	sb.options.Logger.Debug("Completed workflow task",
+		log.InstanceIDKey, instance.GetInstanceID(),
+		log.ExecutionIDKey, instance.GetExecutionID(),
+		"executed_events", len(executedEvents),
+		"workflow_events", len(workflowEvents),
+	)
+
+	sb.NotifyCompletedWorkflowTask(executedEvents, workflowEvents)
+
+	return nil
 }
 
-func (sb *sqliteBackend) ExtendWorkflowTask(ctx context.Context, instance workflow.Instance) error {
+func (sb *sqliteBackend) ExtendWorkflowTask(ctx context.Context, instance workflow.Instance) (err error) {
+	defer func() { sb.recordLockExtension(metrickeys.TypeWorkflow, err) }()
+
 	tx, err := sb.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
@@ -404,6 +571,8 @@ func (sb *sqliteBackend) ExtendWorkflowTask(ctx context.Context, instance workfl
 }
 
 func (sb *sqliteBackend) GetActivityTask(ctx context.Context) (*task.Activity, error) {
+	sb.queueDepth.Report(ctx)
+
 	tx, err := sb.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -412,13 +581,18 @@ func (sb *sqliteBackend) GetActivityTask(ctx context.Context) (*task.Activity, e
 
 	// Lock next activity
 	// (work around missing LIMIT support in sqlite driver for UPDATE statements by using sub-query)
+	orderBy := "rowid"
+	if sb.activityFairness.HonorPriority() {
+		orderBy = "priority DESC, rowid"
+	}
+
 	now := time.Now()
 	row, err := tx.QueryContext(
 		ctx,
 		`UPDATE activities
 			SET locked_until = ?, worker = ?
 			WHERE rowid = (
-				SELECT rowid FROM activities WHERE locked_until IS NULL OR locked_until < ? LIMIT 1
+				SELECT rowid FROM activities WHERE locked_until IS NULL OR locked_until < ? ORDER BY `+orderBy+` LIMIT 1
 			) RETURNING id, instance_id, execution_id, event_type, timestamp, schedule_event_id, attributes, visible_at`,
 		now.Add(sb.options.ActivityLockTimeout),
 		sb.workerName,
@@ -458,6 +632,13 @@ func (sb *sqliteBackend) GetActivityTask(ctx context.Context) (*task.Activity, e
 		return nil, err
 	}
 
+	sb.options.Logger.Debug("Locked activity task",
+		log.InstanceIDKey, instanceID,
+		log.ExecutionIDKey, executionID,
+		log.ActivityIDKey, event.ID,
+		log.ScheduleEventIDKey, event.ScheduleEventID,
+	)
+
 	return t, nil
 }
 
@@ -484,14 +665,29 @@ func (sb *sqliteBackend) CompleteActivityTask(ctx context.Context, instance work
 	}
 
 	// Insert new event generated during this workflow execution
-	if err := insertNewEvents(ctx, tx, instance.GetInstanceID(), []history.Event{event}); err != nil {
+	if err := sb.insertNewEvents(ctx, tx, instance.GetInstanceID(), []history.Event{event}); err != nil {
 		return errors.Wrap(err, "could not insert new events for completed activity")
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	sb.options.Logger.Debug("Completed activity task",
+		log.InstanceIDKey, instance.GetInstanceID(),
+		log.ExecutionIDKey, instance.GetExecutionID(),
+		log.ActivityIDKey, id,
+		log.EventIDKey, event.ID,
+	)
+
+	sb.NotifyWorkflowTasks()
+
+	return nil
 }
 
-func (sb *sqliteBackend) ExtendActivityTask(ctx context.Context, activityID string) error {
+func (sb *sqliteBackend) ExtendActivityTask(ctx context.Context, activityID string) (err error) {
+	defer func() { sb.recordLockExtension(metrickeys.TypeActivity, err) }()
+
 	tx, err := sb.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
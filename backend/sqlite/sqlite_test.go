@@ -1,17 +1,499 @@
 package sqlite
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/cschleiden/go-workflows/archive"
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/backend/test"
+	"github.com/cschleiden/go-workflows/blob"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/compression"
+	"github.com/cschleiden/go-workflows/converter"
+	"github.com/cschleiden/go-workflows/export"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_SqliteBackend(t *testing.T) {
 	test.TestBackend(t, test.Tester{
-		New: func() backend.Backend {
+		New: func(options ...backend.BackendOption) backend.Backend {
 			// Disable sticky workflow behavior for the test execution
-			return NewInMemoryBackend(backend.WithStickyTimeout(0))
+			return NewInMemoryBackend(append([]backend.BackendOption{backend.WithStickyTimeout(0)}, options...)...)
 		},
 	})
 }
+
+func Test_SqliteBackend_Migrate(t *testing.T) {
+	ctx := context.Background()
+
+	b := NewSqliteBackend(filepath.Join(t.TempDir(), "test.sqlite"), backend.WithApplyMigrations(false))
+	sb := b.(*sqliteBackend)
+
+	// Schema is not created without migrations
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()),
+		HistoryEvent:     history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.Error(t, err)
+
+	m, ok := b.(backend.Migrator)
+	require.True(t, ok)
+	require.NoError(t, m.Migrate(ctx))
+
+	// Migrating again doesn't change anything
+	require.NoError(t, m.Migrate(ctx))
+
+	var version int
+	require.NoError(t, sb.db.QueryRow("SELECT MAX(version) FROM schema_version").Scan(&version))
+	require.Equal(t, 3, version)
+
+	err = b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()),
+		HistoryEvent:     history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.NoError(t, err)
+}
+
+func Test_NewSqliteBackendFromDB(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "test.sqlite"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	b, err := NewSqliteBackendFromDB(db)
+	require.NoError(t, err)
+
+	err = b.CreateWorkflowInstance(context.Background(), history.WorkflowEvent{
+		WorkflowInstance: core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()),
+		HistoryEvent:     history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.NoError(t, err)
+}
+
+func Test_SqliteBackend_Close(t *testing.T) {
+	// A backend created from a DSN owns its database
+	b, err := NewSqliteBackendFromDSN("file:" + filepath.Join(t.TempDir(), "test.sqlite"))
+	require.NoError(t, err)
+
+	sb := b.(*sqliteBackend)
+	require.NoError(t, sb.Close())
+	require.Error(t, sb.db.Ping())
+
+	// A database passed by the caller is left open
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "test.sqlite"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	b, err = NewSqliteBackendFromDB(db)
+	require.NoError(t, err)
+
+	require.NoError(t, b.(io.Closer).Close())
+	require.NoError(t, db.Ping())
+}
+
+func Test_NewSqliteBackendFromDSN_ReturnsError(t *testing.T) {
+	b, err := NewSqliteBackendFromDSN("file:" + filepath.Join(t.TempDir(), "missing", "test.sqlite"))
+	require.Error(t, err)
+	require.Nil(t, b)
+}
+
+func Test_SqliteBackend_PurgeCompletedInstances(t *testing.T) {
+	ctx := context.Background()
+
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0))
+	sb := b.(*sqliteBackend)
+
+	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	require.NoError(t, b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: wfi,
+		HistoryEvent:     history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	}))
+
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, task)
+
+	require.NoError(t, b.CompleteWorkflowTask(ctx, wfi, append(task.NewEvents,
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{}),
+	), []history.WorkflowEvent{}))
+
+	// Instances completed after the cutoff are kept
+	removed, err := sb.PurgeCompletedInstances(ctx, time.Now().Add(-time.Hour), 10)
+	require.NoError(t, err)
+	require.Equal(t, 0, removed)
+
+	removed, err = sb.PurgeCompletedInstances(ctx, time.Now().Add(time.Second), 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, removed)
+
+	for _, table := range []string{"instances", "history", "pending_events", "activities"} {
+		var count int
+		require.NoError(t, sb.db.QueryRow("SELECT COUNT(*) FROM "+table).Scan(&count))
+		require.Equal(t, 0, count, table)
+	}
+}
+
+type failingSink struct{}
+
+func (failingSink) Store(ctx context.Context, a *archive.Archive) error {
+	return errors.New("sink unavailable")
+}
+
+func Test_SqliteBackend_Archive(t *testing.T) {
+	ctx := context.Background()
+
+	fs, err := archive.NewFileSystem(t.TempDir())
+	require.NoError(t, err)
+
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0), backend.WithArchiver(fs))
+
+	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	require.NoError(t, b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: wfi,
+		HistoryEvent:     history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	}))
+
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, task)
+
+	require.NoError(t, b.CompleteWorkflowTask(ctx, wfi, append(task.NewEvents,
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{Result: payload.New([]byte("42"), nil)}),
+	), []history.WorkflowEvent{}))
+
+	// Instances are only archived when they are removed
+	_, err = fs.Load(ctx, wfi.GetInstanceID(), wfi.GetExecutionID())
+	require.ErrorIs(t, err, archive.ErrNotFound)
+
+	require.NoError(t, b.RemoveWorkflowInstance(ctx, wfi))
+
+	a, err := fs.Load(ctx, wfi.GetInstanceID(), wfi.GetExecutionID())
+	require.NoError(t, err)
+	require.Equal(t, wfi.GetExecutionID(), a.ExecutionID)
+	require.Equal(t, []byte("42"), a.Result.Data)
+
+	h, err := export.FromArchive(a)
+	require.NoError(t, err)
+	require.Len(t, h.Events, 2)
+	require.Equal(t, history.EventType_WorkflowExecutionFinished, h.Events[1].Type)
+}
+
+func Test_SqliteBackend_ArchiveOnCompletion(t *testing.T) {
+	ctx := context.Background()
+
+	fs, err := archive.NewFileSystem(t.TempDir())
+	require.NoError(t, err)
+
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0), backend.WithArchiver(fs), backend.WithArchiveOnCompletion(true))
+
+	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	require.NoError(t, b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: wfi,
+		HistoryEvent:     history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	}))
+
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, b.CompleteWorkflowTask(ctx, wfi, append(task.NewEvents,
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{Error: "failed"}),
+	), []history.WorkflowEvent{}))
+
+	a, err := fs.Load(ctx, wfi.GetInstanceID(), wfi.GetExecutionID())
+	require.NoError(t, err)
+	require.Equal(t, "failed", a.Error)
+}
+
+func Test_SqliteBackend_ArchiveFailureKeepsInstance(t *testing.T) {
+	ctx := context.Background()
+
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0), backend.WithArchiver(failingSink{}))
+	sb := b.(*sqliteBackend)
+
+	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	require.NoError(t, b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: wfi,
+		HistoryEvent:     history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	}))
+
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, b.CompleteWorkflowTask(ctx, wfi, append(task.NewEvents,
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{}),
+	), []history.WorkflowEvent{}))
+
+	_, err = sb.PurgeCompletedInstances(ctx, time.Now().Add(time.Second), 10)
+	require.Error(t, err)
+
+	var count int
+	require.NoError(t, sb.db.QueryRow("SELECT COUNT(*) FROM instances").Scan(&count))
+	require.Equal(t, 1, count)
+}
+
+func Test_SqliteBackend_EncryptedPayloads(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	codec, err := converter.NewAESGCMCodec("k1", map[string][]byte{"k1": []byte("0123456789abcdef0123456789abcdef")})
+	require.NoError(t, err)
+	conv := converter.NewCodecConverter(converter.DefaultConverter, codec)
+
+	b := NewInMemoryBackend()
+	sb := b.(*sqliteBackend)
+
+	w := worker.New(b, &worker.Options{WorkflowPollers: 1, ActivityPollers: 1, Converter: conv})
+	require.NoError(t, w.RegisterWorkflow(workflowGreet))
+	require.NoError(t, w.RegisterActivity(greet))
+	require.NoError(t, w.Start(ctx))
+
+	c := client.NewWithOptions(b, &client.Options{Converter: conv})
+
+	instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+	}, workflowGreet, "hello")
+	require.NoError(t, err)
+	require.NoError(t, c.SignalWorkflow(ctx, instance.GetInstanceID(), "name", "secret"))
+
+	require.Eventually(t, func() bool {
+		var completed int
+		require.NoError(t, sb.db.QueryRow("SELECT COUNT(*) FROM instances WHERE completed_at IS NOT NULL").Scan(&completed))
+		return completed == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, w.Stop())
+
+	// No payload is stored without encryption
+	rows, err := sb.db.Query("SELECT attributes FROM history UNION ALL SELECT attributes FROM pending_events UNION ALL SELECT attributes FROM activities")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	for rows.Next() {
+		var attributes []byte
+		require.NoError(t, rows.Scan(&attributes))
+		require.NotContains(t, string(attributes), converter.EncodingJSON)
+	}
+	require.NoError(t, rows.Err())
+
+	events, err := b.GetWorkflowInstanceHistory(context.Background(), instance)
+	require.NoError(t, err)
+
+	last := events[len(events)-2]
+	require.Equal(t, history.EventType_WorkflowExecutionFinished, last.Type)
+	result := last.Attributes.(*history.ExecutionCompletedAttributes).Result
+	require.Equal(t, converter.EncodingEncrypted, result.Encoding())
+
+	decrypted, err := codec.Decode(result)
+	require.NoError(t, err)
+
+	var r string
+	require.NoError(t, converter.DefaultConverter.From(decrypted.Data, &r))
+	require.Equal(t, "hello secret", r)
+}
+
+func workflowGreet(ctx workflow.Context, greeting string) (string, error) {
+	var name string
+	workflow.NewSignalChannel(ctx, "name").Receive(ctx, &name)
+
+	var r string
+	err := workflow.ExecuteActivity(ctx, workflow.DefaultActivityOptions, greet, greeting, name).Get(ctx, &r)
+
+	return r, err
+}
+
+func greet(ctx context.Context, greeting, name string) (string, error) {
+	return greeting + " " + name, nil
+}
+
+func Test_SqliteBackend_Compression(t *testing.T) {
+	ctx := context.Background()
+
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0), backend.WithCompression(compression.Zstd, 256))
+	sb := b.(*sqliteBackend)
+
+	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	require.NoError(t, b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: wfi,
+		HistoryEvent:     history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Name: "wf"}),
+	}))
+
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+
+	result := payload.New([]byte(`"`+strings.Repeat("a", 1024)+`"`), nil)
+	require.NoError(t, b.CompleteWorkflowTask(ctx, wfi, append(task.NewEvents,
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{Result: result}),
+	), []history.WorkflowEvent{}))
+
+	var size int
+	require.NoError(t, sb.db.QueryRow("SELECT LENGTH(attributes) FROM history WHERE event_type = ?", history.EventType_WorkflowExecutionFinished).Scan(&size))
+	require.Less(t, size, 1024)
+
+	// Small attributes are not compressed, both are read transparently
+	events, err := b.GetWorkflowInstanceHistory(ctx, wfi)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, "wf", events[0].Attributes.(*history.ExecutionStartedAttributes).Name)
+	require.Equal(t, result, events[1].Attributes.(*history.ExecutionCompletedAttributes).Result)
+}
+
+func Test_SqliteBackend_OffloadedPayloads(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir := t.TempDir()
+	store, err := blob.NewFileSystem(dir)
+	require.NoError(t, err)
+	codec := converter.NewOffloadCodec(store, 1024)
+	conv := converter.NewCodecConverter(converter.DefaultConverter, codec)
+
+	b := NewInMemoryBackend(backend.WithBlobStore(store))
+	sb := b.(*sqliteBackend)
+
+	w := worker.New(b, &worker.Options{WorkflowPollers: 1, ActivityPollers: 1, Converter: conv})
+	require.NoError(t, w.RegisterWorkflow(workflowGreet))
+	require.NoError(t, w.RegisterActivity(greet))
+	require.NoError(t, w.Start(ctx))
+
+	c := client.NewWithOptions(b, &client.Options{Converter: conv})
+
+	name := strings.Repeat("a", 4096)
+	instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+	}, workflowGreet, "hello")
+	require.NoError(t, err)
+	require.NoError(t, c.SignalWorkflow(ctx, instance.GetInstanceID(), "name", name))
+
+	require.Eventually(t, func() bool {
+		var completed int
+		require.NoError(t, sb.db.QueryRow("SELECT COUNT(*) FROM instances WHERE completed_at IS NOT NULL").Scan(&completed))
+		return completed == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, w.Stop())
+
+	events, err := b.GetWorkflowInstanceHistory(context.Background(), instance)
+	require.NoError(t, err)
+
+	result := events[len(events)-2].Attributes.(*history.ExecutionCompletedAttributes).Result
+	_, ok := blob.Reference(result)
+	require.True(t, ok)
+
+	loaded, err := codec.Decode(result)
+	require.NoError(t, err)
+
+	var r string
+	require.NoError(t, converter.DefaultConverter.From(loaded.Data, &r))
+	require.Equal(t, "hello "+name, r)
+
+	// Signal, activity input and result, and workflow result are offloaded
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 4)
+
+	// Blobs are removed together with the instance
+	require.NoError(t, b.RemoveWorkflowInstance(context.Background(), instance))
+
+	files, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+type checkingBlobStore struct {
+	blob.Store
+
+	check func()
+}
+
+func (s *checkingBlobStore) Delete(ctx context.Context, key string) error {
+	s.check()
+	return s.Store.Delete(ctx, key)
+}
+
+func Test_SqliteBackend_DeletesBlobsAfterRemoval(t *testing.T) {
+	ctx := context.Background()
+
+	fs, err := blob.NewFileSystem(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, fs.Put(ctx, "input", []byte("{}")))
+
+	store := &checkingBlobStore{Store: fs}
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0), backend.WithBlobStore(store))
+	sb := b.(*sqliteBackend)
+
+	deleted := 0
+	store.check = func() {
+		// The instance has already been removed when its blobs are deleted
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+
+		var count int
+		require.NoError(t, sb.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM instances").Scan(&count))
+		require.Equal(t, 0, count)
+		deleted++
+	}
+
+	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	require.NoError(t, b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: wfi,
+		HistoryEvent: history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+			Inputs: []payload.Payload{payload.New([]byte{}, map[string]string{
+				payload.MetadataEncoding: blob.EncodingReference,
+				blob.MetadataKey:         "input",
+			})},
+		}),
+	}))
+
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, b.CompleteWorkflowTask(ctx, wfi, append(task.NewEvents,
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{}),
+	), []history.WorkflowEvent{}))
+
+	require.NoError(t, b.RemoveWorkflowInstance(ctx, wfi))
+	require.Equal(t, 1, deleted)
+
+	_, err = fs.Get(ctx, "input")
+	require.ErrorIs(t, err, blob.ErrNotFound)
+}
+
+func Test_SqliteBackend_DeleteSchedule_DeletesInputBlobs(t *testing.T) {
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	store, err := blob.NewFileSystem(dir)
+	require.NoError(t, err)
+	conv := converter.NewCodecConverter(converter.DefaultConverter, converter.NewOffloadCodec(store, 1024))
+
+	b := NewInMemoryBackend(backend.WithBlobStore(store))
+	c := client.NewWithOptions(b, &client.Options{Converter: conv})
+
+	_, err = c.CreateSchedule(ctx, "schedule", client.ScheduleSpec{Interval: time.Hour}, workflowGreet, strings.Repeat("a", 4096))
+	require.NoError(t, err)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	require.NoError(t, c.DeleteSchedule(ctx, "schedule"))
+
+	files, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
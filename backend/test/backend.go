@@ -2,12 +2,14 @@ package test
 
 import (
 	"context"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/schedule"
 	"github.com/cschleiden/go-workflows/internal/task"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
@@ -15,7 +17,9 @@ import (
 )
 
 type Tester struct {
-	New func() backend.Backend
+	// New creates the backend to test, with the given options in addition to the ones required
+	// by the tests
+	New func(options ...backend.BackendOption) backend.Backend
 
 	Teardown func()
 }
@@ -39,6 +43,15 @@ func (s *BackendTestSuite) SetupTest() {
 	s.b = s.Tester.New()
 }
 
+// newBackend replaces the backend of the current test with one created with the given options
+func (s *BackendTestSuite) newBackend(options ...backend.BackendOption) {
+	if s.Tester.Teardown != nil {
+		s.Tester.Teardown()
+	}
+
+	s.b = s.Tester.New(options...)
+}
+
 func (s *BackendTestSuite) TearDownTest() {
 	if s.Tester.Teardown != nil {
 		s.Tester.Teardown()
@@ -184,3 +197,417 @@ func (s *BackendTestSuite) Test_CompleteWorkflowTask_AddsNewEventsToHistory() {
 	s.Len(t.NewEvents, 1)
 	s.Equal(activityCompletedEvent.Type, t.NewEvents[0].Type, "Expected new events to be returned")
 }
+
+func (s *BackendTestSuite) Test_RemoveWorkflowInstance() {
+	ctx := context.Background()
+
+	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := s.b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: wfi,
+		HistoryEvent:     history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	s.NoError(err)
+
+	// Running instances can't be removed
+	err = s.b.RemoveWorkflowInstance(ctx, wfi)
+	s.ErrorIs(err, backend.ErrInstanceNotCompleted)
+
+	t, err := s.b.GetWorkflowTask(ctx)
+	s.NoError(err)
+	s.NotNil(t)
+
+	err = s.b.CompleteWorkflowTask(ctx, wfi, []history.Event{
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{}),
+	}, []history.WorkflowEvent{})
+	s.NoError(err)
+
+	err = s.b.RemoveWorkflowInstance(ctx, wfi)
+	s.NoError(err)
+
+	err = s.b.RemoveWorkflowInstance(ctx, wfi)
+	s.ErrorIs(err, backend.ErrInstanceNotFound)
+}
+
+func (s *BackendTestSuite) Test_GetWorkflowInstanceHistory() {
+	ctx := context.Background()
+
+	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+	_, err := s.b.GetWorkflowInstanceHistory(ctx, wfi)
+	s.ErrorIs(err, backend.ErrInstanceNotFound)
+
+	err = s.b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: wfi,
+		HistoryEvent:     history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Name: "wf"}),
+	})
+	s.NoError(err)
+
+	// Pending events are not part of the history
+	h, err := s.b.GetWorkflowInstanceHistory(ctx, wfi)
+	s.NoError(err)
+	s.Len(h, 0)
+
+	t, err := s.b.GetWorkflowTask(ctx)
+	s.NoError(err)
+	s.NotNil(t)
+
+	err = s.b.CompleteWorkflowTask(ctx, wfi, append(t.NewEvents,
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{}),
+	), []history.WorkflowEvent{})
+	s.NoError(err)
+
+	h, err = s.b.GetWorkflowInstanceHistory(ctx, wfi)
+	s.NoError(err)
+	s.Len(h, 2)
+	s.Equal(history.EventType_WorkflowExecutionStarted, h[0].Type)
+	s.Equal("wf", h[0].Attributes.(*history.ExecutionStartedAttributes).Name)
+	s.Equal(history.EventType_WorkflowExecutionFinished, h[1].Type)
+
+	// A different execution of the same instance is not found
+	_, err = s.b.GetWorkflowInstanceHistory(ctx, core.NewWorkflowInstance(wfi.GetInstanceID(), uuid.NewString()))
+	s.ErrorIs(err, backend.ErrInstanceNotFound)
+}
+
+func (s *BackendTestSuite) Test_Notifier_NotifiesOnNewWork() {
+	n, ok := s.b.(backend.Notifier)
+	if !ok {
+		s.T().Skip("backend does not support notifications")
+	}
+
+	ctx := context.Background()
+
+	// Creating a workflow instance notifies workflow pollers
+	notification := n.WorkflowTasksNotification()
+
+	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := s.b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: wfi,
+		HistoryEvent:     history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	s.NoError(err)
+	s.True(isClosed(notification), "expected workflow task notification")
+
+	_, err = s.b.GetWorkflowTask(ctx)
+	s.NoError(err)
+
+	// Scheduling an activity notifies activity pollers
+	notification = n.ActivityTasksNotification()
+
+	err = s.b.CompleteWorkflowTask(ctx, wfi, []history.Event{
+		history.NewHistoryEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{}, history.ScheduleEventID(1)),
+	}, []history.WorkflowEvent{})
+	s.NoError(err)
+	s.True(isClosed(notification), "expected activity task notification")
+}
+
+func (s *BackendTestSuite) Test_DescribeWorkflowInstance() {
+	ctx := context.Background()
+
+	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := s.b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: wfi,
+		HistoryEvent:     history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	s.NoError(err)
+
+	info, err := s.b.DescribeWorkflowInstance(ctx, wfi)
+	s.NoError(err)
+	s.Equal(wfi.GetInstanceID(), info.Instance.GetInstanceID())
+	s.Equal(wfi.GetExecutionID(), info.Instance.GetExecutionID())
+	s.Equal(backend.WorkflowInstanceStateActive, info.State)
+	s.Nil(info.StartAt)
+	s.Nil(info.CompletedAt)
+
+	_, err = s.b.DescribeWorkflowInstance(ctx, core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()))
+	s.ErrorIs(err, backend.ErrInstanceNotFound)
+}
+
+func (s *BackendTestSuite) Test_DescribeWorkflowInstance_SubWorkflow() {
+	ctx := context.Background()
+
+	parent := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := s.b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: parent,
+		HistoryEvent:     history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	s.NoError(err)
+
+	wfi := core.NewSubWorkflowInstance(uuid.NewString(), uuid.NewString(), parent, 1)
+	err = s.b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: wfi,
+		HistoryEvent:     history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	s.NoError(err)
+
+	info, err := s.b.DescribeWorkflowInstance(ctx, wfi)
+	s.NoError(err)
+	s.True(info.Instance.SubWorkflow())
+	s.Equal(parent.GetInstanceID(), info.Instance.ParentInstance().GetInstanceID())
+	s.Equal(parent.GetExecutionID(), info.Instance.ParentInstance().GetExecutionID())
+	s.Equal(1, info.Instance.ParentEventID())
+}
+
+func (s *BackendTestSuite) Test_DelayedStart() {
+	ctx := context.Background()
+
+	startAt := time.Now().Add(time.Hour)
+
+	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := s.b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: wfi,
+		HistoryEvent: history.NewHistoryEvent(
+			time.Now(),
+			history.EventType_WorkflowExecutionStarted,
+			&history.ExecutionStartedAttributes{},
+			history.VisibleAt(startAt),
+		),
+	})
+	s.NoError(err)
+
+	info, err := s.b.DescribeWorkflowInstance(ctx, wfi)
+	s.NoError(err)
+	s.Equal(backend.WorkflowInstanceStateScheduled, info.State)
+	s.NotNil(info.StartAt)
+	s.WithinDuration(startAt, *info.StartAt, time.Second)
+
+	// Signals don't make the instance start early
+	err = s.b.SignalWorkflow(ctx, wfi.GetInstanceID(), history.NewHistoryEvent(time.Now(), history.EventType_SignalReceived, &history.SignalReceivedAttributes{Name: "signal"}))
+	s.NoError(err)
+
+	tctx, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+	defer cancel()
+
+	t, err := s.b.GetWorkflowTask(tctx)
+	s.NoError(err)
+	s.Nil(t)
+
+	infos, err := s.b.ListWorkflowInstances(ctx, "", 1000)
+	s.NoError(err)
+
+	found := false
+	for _, info := range infos {
+		if info.Instance.GetInstanceID() == wfi.GetInstanceID() {
+			found = true
+			s.Equal(backend.WorkflowInstanceStateScheduled, info.State)
+		}
+	}
+	s.True(found)
+}
+
+func (s *BackendTestSuite) Test_ListWorkflowInstances() {
+	ctx := context.Background()
+
+	prefix := uuid.NewString()
+	for i := 0; i < 3; i++ {
+		err := s.b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+			WorkflowInstance: core.NewWorkflowInstance(prefix+"-"+strconv.Itoa(i), uuid.NewString()),
+			HistoryEvent:     history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+		})
+		s.NoError(err)
+	}
+
+	infos, err := s.b.ListWorkflowInstances(ctx, prefix, 2)
+	s.NoError(err)
+	s.Len(infos, 2)
+	s.Equal(prefix+"-0", infos[0].Instance.GetInstanceID())
+	s.Equal(prefix+"-1", infos[1].Instance.GetInstanceID())
+
+	infos, err = s.b.ListWorkflowInstances(ctx, infos[1].Instance.GetInstanceID(), 1)
+	s.NoError(err)
+	s.Len(infos, 1)
+	s.Equal(prefix+"-2", infos[0].Instance.GetInstanceID())
+}
+
+func (s *BackendTestSuite) Test_GetWorkflowTask_Priority() {
+	ctx := context.Background()
+
+	createInstance := func(priority int, opts ...history.HistoryEventOption) string {
+		wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+		err := s.b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+			WorkflowInstance: wfi,
+			HistoryEvent:     history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Priority: priority}, opts...),
+		})
+		s.NoError(err)
+
+		return wfi.GetInstanceID()
+	}
+
+	const fairness = 5
+	s.newBackend(backend.WithPriorityFairness(fairness))
+
+	for i := 0; i < fairness; i++ {
+		createInstance(10)
+	}
+
+	// The low priority instance is created last, but has been ready the longest
+	low := createInstance(0, history.VisibleAt(time.Now().Add(-time.Minute)))
+
+	// Instances with a higher priority are returned first
+	for i := 0; i < fairness-1; i++ {
+		t, err := s.b.GetWorkflowTask(ctx)
+		s.NoError(err)
+		s.NotNil(t)
+		s.NotEqual(low, t.WorkflowInstance.GetInstanceID())
+	}
+
+	// Every n-th poll ignores priorities and picks the instance that has been ready the longest,
+	// so that the low priority instance isn't starved
+	t, err := s.b.GetWorkflowTask(ctx)
+	s.NoError(err)
+	s.NotNil(t)
+	s.Equal(low, t.WorkflowInstance.GetInstanceID())
+}
+
+func (s *BackendTestSuite) Test_GetActivityTask_Priority() {
+	ctx := context.Background()
+
+	startedEvent := history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{})
+	lowEvent := history.NewHistoryEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{}, history.ScheduleEventID(1))
+	highEvent := history.NewHistoryEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{Priority: 10}, history.ScheduleEventID(2))
+
+	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := s.b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: wfi,
+		HistoryEvent:     startedEvent,
+	})
+	s.NoError(err)
+
+	_, err = s.b.GetWorkflowTask(ctx)
+	s.NoError(err)
+
+	events := []history.Event{
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowTaskStarted, &history.WorkflowTaskStartedAttributes{}),
+		startedEvent,
+		lowEvent,
+		highEvent,
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowTaskFinished, &history.WorkflowTaskFinishedAttributes{}),
+	}
+
+	err = s.b.CompleteWorkflowTask(ctx, wfi, events, []history.WorkflowEvent{})
+	s.NoError(err)
+
+	t, err := s.b.GetActivityTask(ctx)
+	s.NoError(err)
+	s.NotNil(t)
+	s.Equal(highEvent.ID, t.Event.ID)
+
+	t, err = s.b.GetActivityTask(ctx)
+	s.NoError(err)
+	s.NotNil(t)
+	s.Equal(lowEvent.ID, t.Event.ID)
+}
+
+func (s *BackendTestSuite) Test_Schedules_CreateGetDelete() {
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	sched, err := schedule.New(uuid.NewString(), schedule.Spec{Interval: time.Minute}, "wf", nil, now)
+	s.NoError(err)
+
+	s.NoError(s.b.CreateSchedule(ctx, sched))
+	s.Equal(int64(1), sched.Version)
+
+	s.ErrorIs(s.b.CreateSchedule(ctx, sched), backend.ErrScheduleAlreadyExists)
+
+	stored, err := s.b.GetSchedule(ctx, sched.ID)
+	s.NoError(err)
+	s.Equal(sched.ID, stored.ID)
+	s.Equal("wf", stored.WorkflowName)
+	s.Equal(int64(1), stored.Version)
+	s.True(sched.NextRunAt.Equal(stored.NextRunAt))
+
+	schedules, err := s.b.ListSchedules(ctx)
+	s.NoError(err)
+	s.Contains(scheduleIDs(schedules), sched.ID)
+
+	s.NoError(s.b.DeleteSchedule(ctx, sched.ID))
+	s.ErrorIs(s.b.DeleteSchedule(ctx, sched.ID), backend.ErrScheduleNotFound)
+
+	_, err = s.b.GetSchedule(ctx, sched.ID)
+	s.ErrorIs(err, backend.ErrScheduleNotFound)
+}
+
+func (s *BackendTestSuite) Test_Schedules_Update() {
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	sched, err := schedule.New(uuid.NewString(), schedule.Spec{Interval: time.Minute}, "wf", nil, now)
+	s.NoError(err)
+	s.NoError(s.b.CreateSchedule(ctx, sched))
+
+	stale, err := s.b.GetSchedule(ctx, sched.ID)
+	s.NoError(err)
+
+	sched.Pause(now)
+	s.NoError(s.b.UpdateSchedule(ctx, sched))
+	s.Equal(int64(2), sched.Version)
+
+	stored, err := s.b.GetSchedule(ctx, sched.ID)
+	s.NoError(err)
+	s.True(stored.Paused)
+	s.Equal(int64(2), stored.Version)
+
+	// Updates based on an outdated version are rejected
+	s.ErrorIs(s.b.UpdateSchedule(ctx, stale), backend.ErrScheduleModified)
+
+	s.NoError(s.b.DeleteSchedule(ctx, sched.ID))
+	s.ErrorIs(s.b.UpdateSchedule(ctx, sched), backend.ErrScheduleNotFound)
+}
+
+func (s *BackendTestSuite) Test_Schedules_GetDueSchedules() {
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+
+	due, err := schedule.New(uuid.NewString(), schedule.Spec{Interval: time.Minute}, "wf", nil, now.Add(-2*time.Minute))
+	s.NoError(err)
+	s.NoError(s.b.CreateSchedule(ctx, due))
+
+	notDue, err := schedule.New(uuid.NewString(), schedule.Spec{Interval: time.Hour}, "wf", nil, now)
+	s.NoError(err)
+	s.NoError(s.b.CreateSchedule(ctx, notDue))
+
+	paused, err := schedule.New(uuid.NewString(), schedule.Spec{Interval: time.Minute}, "wf", nil, now.Add(-2*time.Minute))
+	s.NoError(err)
+	paused.Pause(now)
+	s.NoError(s.b.CreateSchedule(ctx, paused))
+
+	schedules, err := s.b.GetDueSchedules(ctx, now, 100)
+	s.NoError(err)
+
+	ids := scheduleIDs(schedules)
+	s.Contains(ids, due.ID)
+	s.NotContains(ids, notDue.ID)
+	s.NotContains(ids, paused.ID)
+
+	// Once processed, the schedule isn't due anymore
+	for _, sched := range schedules {
+		if sched.ID == due.ID {
+			_, _, err := sched.Process(now, false)
+			s.NoError(err)
+			s.NoError(s.b.UpdateSchedule(ctx, sched))
+		}
+	}
+
+	schedules, err = s.b.GetDueSchedules(ctx, now, 100)
+	s.NoError(err)
+	s.NotContains(scheduleIDs(schedules), due.ID)
+}
+
+func scheduleIDs(schedules []*schedule.Schedule) []string {
+	ids := make([]string, 0, len(schedules))
+	for _, s := range schedules {
+		ids = append(ids, s.ID)
+	}
+
+	return ids
+}
+
+func isClosed(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,76 @@
+// Package blob stores large payloads outside of the backend. Payloads are replaced with references
+// in the workflow history, and fetched again when they are decoded.
+package blob
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// ErrNotFound is returned by stores if there is no blob for the requested key
+var ErrNotFound = errors.New("blob not found")
+
+// Store stores blobs by key
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+
+	// Get returns ErrNotFound if there is no blob with the given key
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Delete removes the blob with the given key. Deleting a blob that doesn't exist is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// EncodingReference is the encoding of payloads that reference a blob
+const EncodingReference = "reference/blob"
+
+// MetadataKey is the metadata entry holding the key of the referenced blob
+const MetadataKey = "blob-key"
+
+// Reference returns the key of the referenced blob if the payload is a reference
+func Reference(p payload.Payload) (string, bool) {
+	if p.Encoding() != EncodingReference {
+		return "", false
+	}
+
+	key, ok := p.Metadata[MetadataKey]
+	return key, ok
+}
+
+// OwnedReferences returns the keys of the blobs referenced by the history of an instance that are
+// owned by it. The inputs of a sub-workflow are also part of its parent's history, and are owned by
+// the sub-workflow, which reads them whenever it's replayed. The result of a sub-workflow is owned
+// by the parent for the same reason.
+func OwnedReferences(events []history.Event, subWorkflow bool) []string {
+	keys := make([]string, 0)
+
+	for _, e := range events {
+		if e.Type == history.EventType_SubWorkflowScheduled {
+			continue
+		}
+
+		if subWorkflow && e.Type == history.EventType_WorkflowExecutionFinished {
+			continue
+		}
+
+		keys = append(keys, References(history.Payloads(e))...)
+	}
+
+	return keys
+}
+
+// References returns the keys of the blobs referenced by the given payloads
+func References(payloads []payload.Payload) []string {
+	keys := make([]string, 0)
+
+	for _, p := range payloads {
+		if key, ok := Reference(p); ok {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,36 @@
+package blob
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/stretchr/testify/require"
+)
+
+func ref(key string) payload.Payload {
+	return payload.New([]byte{}, map[string]string{
+		payload.MetadataEncoding: EncodingReference,
+		MetadataKey:              key,
+	})
+}
+
+func Test_OwnedReferences(t *testing.T) {
+	events := []history.Event{
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+			Inputs: []payload.Payload{ref("input"), payload.New([]byte("42"), nil)},
+		}),
+		history.NewHistoryEvent(time.Now(), history.EventType_ActivityCompleted, &history.ActivityCompletedAttributes{Result: ref("activity")}),
+		history.NewHistoryEvent(time.Now(), history.EventType_SignalReceived, &history.SignalReceivedAttributes{Arg: ref("signal")}),
+		history.NewHistoryEvent(time.Now(), history.EventType_SubWorkflowScheduled, &history.SubWorkflowScheduledAttributes{Inputs: []payload.Payload{ref("child-input")}}),
+		history.NewHistoryEvent(time.Now(), history.EventType_SubWorkflowCompleted, &history.SubWorkflowCompletedAttributes{Result: ref("child-result")}),
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{Result: ref("result")}),
+	}
+
+	// Inputs of sub-workflows are owned by the sub-workflow
+	require.Equal(t, []string{"input", "activity", "signal", "child-result", "result"}, OwnedReferences(events, false))
+
+	// Results of sub-workflows are owned by the parent
+	require.Equal(t, []string{"input", "activity", "signal", "child-result"}, OwnedReferences(events, true))
+}
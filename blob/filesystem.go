@@ -0,0 +1,76 @@
+package blob
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// FileSystem stores blobs as files in a local directory
+type FileSystem struct {
+	dir string
+}
+
+var _ Store = (*FileSystem)(nil)
+
+// NewFileSystem creates a store for blobs in dir. The directory is created if it doesn't exist.
+func NewFileSystem(dir string) (*FileSystem, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "could not create blob directory")
+	}
+
+	return &FileSystem{dir: dir}, nil
+}
+
+func (fs *FileSystem) Put(ctx context.Context, key string, data []byte) error {
+	// Write to a temporary file first, so that readers never see partial blobs
+	f, err := ioutil.TempFile(fs.dir, ".blob-*")
+	if err != nil {
+		return errors.Wrap(err, "could not create blob file")
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return errors.Wrap(err, "could not write blob")
+	}
+
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "could not write blob")
+	}
+
+	if err := os.Rename(f.Name(), fs.path(key)); err != nil {
+		return errors.Wrap(err, "could not write blob")
+	}
+
+	return nil
+}
+
+func (fs *FileSystem) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := ioutil.ReadFile(fs.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+
+		return nil, errors.Wrap(err, "could not read blob")
+	}
+
+	return data, nil
+}
+
+func (fs *FileSystem) Delete(ctx context.Context, key string) error {
+	if err := os.Remove(fs.path(key)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "could not delete blob")
+	}
+
+	return nil
+}
+
+func (fs *FileSystem) path(key string) string {
+	return filepath.Join(fs.dir, url.PathEscape(key))
+}
@@ -0,0 +1,28 @@
+package blob
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FileSystem(t *testing.T) {
+	ctx := context.Background()
+
+	fs, err := NewFileSystem(t.TempDir())
+	require.NoError(t, err)
+
+	require.NoError(t, fs.Put(ctx, "a/b", []byte("data")))
+
+	data, err := fs.Get(ctx, "a/b")
+	require.NoError(t, err)
+	require.Equal(t, []byte("data"), data)
+
+	require.NoError(t, fs.Delete(ctx, "a/b"))
+	_, err = fs.Get(ctx, "a/b")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// Deleting is idempotent
+	require.NoError(t, fs.Delete(ctx, "a/b"))
+}
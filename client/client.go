@@ -10,13 +10,30 @@ import (
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/internal/tracing"
 	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type WorkflowInstanceOptions struct {
 	InstanceID string
+
+	// StartAt delays the start of the workflow instance until the given time. Until then, the
+	// instance is reported as scheduled and signals sent to it are delayed, too.
+	StartAt time.Time
+
+	// StartDelay delays the start of the workflow instance by the given duration. It must not be
+	// negative. Only one of StartAt and StartDelay can be set.
+	StartDelay time.Duration
+
+	// Priority of the workflow instance's tasks. When workers cannot keep up, tasks with a higher
+	// priority are picked up first. Default is 0.
+	Priority int
 }
 
 type Client interface {
@@ -25,33 +42,151 @@ type Client interface {
 	CancelWorkflowInstance(ctx context.Context, instance workflow.Instance) error
 
 	SignalWorkflow(ctx context.Context, instanceID string, name string, arg interface{}) error
+
+	// RemoveWorkflowInstance removes a completed workflow instance including its history
+	RemoveWorkflowInstance(ctx context.Context, instance workflow.Instance) error
+
+	// DescribeWorkflowInstance returns the state of a workflow instance
+	DescribeWorkflowInstance(ctx context.Context, instance workflow.Instance) (*backend.WorkflowInstanceInfo, error)
+
+	// ListWorkflowInstances returns up to limit workflow instances ordered by instance ID, starting
+	// after the given instance ID. Pass the ID of the last returned instance to get the next page.
+	ListWorkflowInstances(ctx context.Context, afterInstanceID string, limit int) ([]*backend.WorkflowInstanceInfo, error)
+
+	// CreateSchedule creates a schedule starting instances of the given workflow according to
+	// spec. Workers start the instances, using the schedule ID and the time each run is scheduled
+	// for as instance ID.
+	CreateSchedule(ctx context.Context, id string, spec ScheduleSpec, wf workflow.Workflow, args ...interface{}) (*Schedule, error)
+
+	// GetSchedule returns the schedule with the given ID
+	GetSchedule(ctx context.Context, id string) (*Schedule, error)
+
+	// ListSchedules returns all schedules ordered by ID
+	ListSchedules(ctx context.Context) ([]*Schedule, error)
+
+	// PauseSchedule stops a schedule from starting new runs
+	PauseSchedule(ctx context.Context, id string) error
+
+	// ResumeSchedule resumes a paused schedule. Runs missed while it was paused are not started.
+	ResumeSchedule(ctx context.Context, id string) error
+
+	// BackfillSchedule starts the runs the schedule would have started between start and end,
+	// regardless of the overlap policy and of whether the schedule is paused
+	BackfillSchedule(ctx context.Context, id string, start, end time.Time) error
+
+	// DeleteSchedule removes a schedule. Instances it already started keep running.
+	DeleteSchedule(ctx context.Context, id string) error
+}
+
+type Options struct {
+	// Interceptors are invoked for every call made through the client
+	Interceptors []Interceptor
+
+	// TracerProvider is used to record spans for created workflow instances. The trace context
+	// is propagated to the workflow and its activities. If not set, no spans are recorded.
+	TracerProvider trace.TracerProvider
+
+	// Logger is used for log messages of the client. Defaults to a logger writing to the
+	// standard library's default logger.
+	Logger log.Logger
+
+	// Converter is used to convert workflow inputs and signal arguments to payloads. It has to
+	// match the converter used by the workers. Defaults to converter.DefaultConverter.
+	Converter converter.DataConverter
 }
 
+var DefaultOptions = Options{}
+
 type client struct {
-	backend backend.Backend
+	backend   backend.Backend
+	options   *Options
+	tracer    trace.Tracer
+	logger    log.Logger
+	converter converter.Converter
 }
 
+// New creates a client for the given backend using the default options
 func New(backend backend.Backend) Client {
+	return NewWithOptions(backend, nil)
+}
+
+// NewWithOptions creates a client for the given backend. If options is nil, the default options
+// are used.
+func NewWithOptions(backend backend.Backend, options *Options) Client {
+	if options == nil {
+		options = &DefaultOptions
+	}
+
+	logger := options.Logger
+	if logger == nil {
+		logger = log.NewDefaultLogger()
+	}
+
+	var conv converter.Converter = converter.DefaultConverter
+	if options.Converter != nil {
+		conv = converter.FromDataConverter(options.Converter)
+	}
+
 	return &client{
-		backend: backend,
+		backend:   backend,
+		options:   options,
+		tracer:    tracing.Tracer(options.TracerProvider),
+		logger:    logger,
+		converter: conv,
 	}
 }
 
 func (c *client) CreateWorkflowInstance(ctx context.Context, options WorkflowInstanceOptions, wf workflow.Workflow, args ...interface{}) (workflow.Instance, error) {
-	inputs, err := a.ArgsToInputs(converter.DefaultConverter, args...)
+	return interceptCreateWorkflowInstance(c.options.Interceptors, c.createWorkflowInstance)(ctx, options, wf, args)
+}
+
+func (c *client) createWorkflowInstance(ctx context.Context, options WorkflowInstanceOptions, wf workflow.Workflow, args []interface{}) (workflow.Instance, error) {
+	if !options.StartAt.IsZero() && options.StartDelay != 0 {
+		return nil, errors.New("only one of StartAt and StartDelay can be set")
+	}
+
+	if options.StartDelay < 0 {
+		return nil, errors.New("start delay must not be negative")
+	}
+
+	inputs, err := a.ArgsToInputs(converter.WithContext(ctx, c.converter), args...)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not convert arguments")
 	}
 
+	wfi := core.NewWorkflowInstance(options.InstanceID, uuid.NewString())
+	name := fn.Name(wf)
+
+	ctx, span := c.tracer.Start(ctx, "CreateWorkflowInstance", trace.WithAttributes(
+		attribute.String(tracing.AttributeInstanceID, wfi.GetInstanceID()),
+		attribute.String(tracing.AttributeExecutionID, wfi.GetExecutionID()),
+		attribute.String(tracing.AttributeWorkflowName, name),
+	))
+	defer span.End()
+
+	now := time.Now()
+
+	var opts []history.HistoryEventOption
+	startAt := options.StartAt
+	if options.StartDelay > 0 {
+		startAt = now.Add(options.StartDelay)
+	}
+	if startAt.After(now) {
+		// The instance is picked up by workers once the started event becomes visible
+		opts = append(opts, history.VisibleAt(startAt))
+	}
+
 	startedEvent := history.NewHistoryEvent(
-		time.Now(),
+		now,
 		history.EventType_WorkflowExecutionStarted,
 		&history.ExecutionStartedAttributes{
-			Name:   fn.Name(wf),
-			Inputs: inputs,
-		})
-
-	wfi := core.NewWorkflowInstance(options.InstanceID, uuid.NewString())
+			Name:     name,
+			Inputs:   inputs,
+			Metadata: tracing.Inject(ctx),
+			Priority: options.Priority,
+		},
+		opts...,
+	)
 
 	startMessage := &history.WorkflowEvent{
 		WorkflowInstance: wfi,
@@ -59,18 +194,70 @@ func (c *client) CreateWorkflowInstance(ctx context.Context, options WorkflowIns
 	}
 
 	if err := c.backend.CreateWorkflowInstance(ctx, *startMessage); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
 		return nil, errors.Wrap(err, "could not create workflow instance")
 	}
 
+	c.logger.Debug("Created workflow instance",
+		log.InstanceIDKey, wfi.GetInstanceID(),
+		log.ExecutionIDKey, wfi.GetExecutionID(),
+		log.WorkflowNameKey, name,
+		log.EventIDKey, startedEvent.ID,
+	)
+
 	return wfi, nil
 }
 
 func (c *client) CancelWorkflowInstance(ctx context.Context, instance workflow.Instance) error {
-	return c.backend.CancelWorkflowInstance(ctx, instance)
+	return interceptCancelWorkflowInstance(c.options.Interceptors, c.cancelWorkflowInstance)(ctx, instance)
+}
+
+func (c *client) cancelWorkflowInstance(ctx context.Context, instance workflow.Instance) error {
+	if err := c.backend.CancelWorkflowInstance(ctx, instance); err != nil {
+		return err
+	}
+
+	c.logger.Debug("Canceled workflow instance",
+		log.InstanceIDKey, instance.GetInstanceID(),
+		log.ExecutionIDKey, instance.GetExecutionID(),
+	)
+
+	return nil
+}
+
+func (c *client) RemoveWorkflowInstance(ctx context.Context, instance workflow.Instance) error {
+	return interceptRemoveWorkflowInstance(c.options.Interceptors, c.removeWorkflowInstance)(ctx, instance)
+}
+
+func (c *client) removeWorkflowInstance(ctx context.Context, instance workflow.Instance) error {
+	if err := c.backend.RemoveWorkflowInstance(ctx, instance); err != nil {
+		return err
+	}
+
+	c.logger.Debug("Removed workflow instance",
+		log.InstanceIDKey, instance.GetInstanceID(),
+		log.ExecutionIDKey, instance.GetExecutionID(),
+	)
+
+	return nil
+}
+
+func (c *client) DescribeWorkflowInstance(ctx context.Context, instance workflow.Instance) (*backend.WorkflowInstanceInfo, error) {
+	return c.backend.DescribeWorkflowInstance(ctx, instance)
+}
+
+func (c *client) ListWorkflowInstances(ctx context.Context, afterInstanceID string, limit int) ([]*backend.WorkflowInstanceInfo, error) {
+	return c.backend.ListWorkflowInstances(ctx, afterInstanceID, limit)
 }
 
 func (c *client) SignalWorkflow(ctx context.Context, instanceID string, name string, arg interface{}) error {
-	input, err := converter.DefaultConverter.To(arg)
+	return interceptSignalWorkflow(c.options.Interceptors, c.signalWorkflow)(ctx, instanceID, name, arg)
+}
+
+func (c *client) signalWorkflow(ctx context.Context, instanceID string, name string, arg interface{}) error {
+	input, err := converter.WithContext(ctx, c.converter).To(arg)
 	if err != nil {
 		return errors.Wrap(err, "could not convert arguments")
 	}
@@ -84,5 +271,15 @@ func (c *client) SignalWorkflow(ctx context.Context, instanceID string, name str
 		},
 	)
 
-	return c.backend.SignalWorkflow(ctx, instanceID, event)
+	if err := c.backend.SignalWorkflow(ctx, instanceID, event); err != nil {
+		return err
+	}
+
+	c.logger.Debug("Signaled workflow instance",
+		log.InstanceIDKey, instanceID,
+		log.SignalNameKey, name,
+		log.EventIDKey, event.ID,
+	)
+
+	return nil
 }
@@ -3,14 +3,23 @@ package client
 import (
 	"bytes"
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/schedule"
+	"github.com/cschleiden/go-workflows/internal/tracing"
+	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func Test_Client_SignalWorkflow(t *testing.T) {
@@ -24,9 +33,7 @@ func Test_Client_SignalWorkflow(t *testing.T) {
 			event.Attributes.(*history.SignalReceivedAttributes).Name == "test"
 	})).Return(nil)
 
-	c := &client{
-		backend: b,
-	}
+	c := New(b)
 
 	err := c.SignalWorkflow(ctx, instanceID, "test", "signal")
 
@@ -47,15 +54,205 @@ func Test_Client_SignalWorkflow_WithArgs(t *testing.T) {
 	b.On("SignalWorkflow", ctx, instanceID, mock.MatchedBy(func(event history.Event) bool {
 		return event.Type == history.EventType_SignalReceived &&
 			event.Attributes.(*history.SignalReceivedAttributes).Name == "test" &&
-			bytes.Equal(event.Attributes.(*history.SignalReceivedAttributes).Arg, input)
+			bytes.Equal(event.Attributes.(*history.SignalReceivedAttributes).Arg.Data, input.Data)
 	})).Return(nil)
 
-	c := &client{
-		backend: b,
-	}
+	c := New(b)
 
 	err := c.SignalWorkflow(ctx, instanceID, "test", arg)
 
 	require.Nil(t, err)
 	b.AssertExpectations(t)
 }
+
+type upperConverter struct{}
+
+func (upperConverter) To(v interface{}) ([]byte, error) {
+	return []byte(strings.ToUpper(v.(string))), nil
+}
+
+func (upperConverter) From(data []byte, v interface{}) error {
+	*(v.(*string)) = string(data)
+	return nil
+}
+
+func Test_Client_SignalWorkflow_Converter(t *testing.T) {
+	instanceID := uuid.NewString()
+
+	ctx := context.Background()
+
+	b := &backend.MockBackend{}
+	b.On("SignalWorkflow", ctx, instanceID, mock.MatchedBy(func(event history.Event) bool {
+		return string(event.Attributes.(*history.SignalReceivedAttributes).Arg.Data) == "SIGNAL"
+	})).Return(nil)
+
+	c := NewWithOptions(b, &Options{
+		Converter: upperConverter{},
+	})
+
+	err := c.SignalWorkflow(ctx, instanceID, "test", "signal")
+
+	require.Nil(t, err)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_RemoveWorkflowInstance(t *testing.T) {
+	ctx := context.Background()
+
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+	b := &backend.MockBackend{}
+	b.On("RemoveWorkflowInstance", ctx, instance).Return(backend.ErrInstanceNotCompleted).Once()
+	b.On("RemoveWorkflowInstance", ctx, instance).Return(nil).Once()
+
+	c := New(b)
+
+	err := c.RemoveWorkflowInstance(ctx, instance)
+	require.ErrorIs(t, err, backend.ErrInstanceNotCompleted)
+
+	err = c.RemoveWorkflowInstance(ctx, instance)
+	require.NoError(t, err)
+	b.AssertExpectations(t)
+}
+
+type signalInterceptor struct {
+	NoopInterceptor
+
+	calls int
+}
+
+func (i *signalInterceptor) SignalWorkflow(ctx context.Context, instanceID string, name string, arg interface{}, next SignalWorkflowFunc) error {
+	i.calls++
+
+	return next(ctx, instanceID, "intercepted-"+name, arg)
+}
+
+func Test_Client_SignalWorkflow_Interceptors(t *testing.T) {
+	instanceID := uuid.NewString()
+
+	ctx := context.Background()
+
+	b := &backend.MockBackend{}
+	b.On("SignalWorkflow", ctx, instanceID, mock.MatchedBy(func(event history.Event) bool {
+		return event.Type == history.EventType_SignalReceived &&
+			event.Attributes.(*history.SignalReceivedAttributes).Name == "intercepted-intercepted-test"
+	})).Return(nil)
+
+	i1 := &signalInterceptor{}
+	i2 := &signalInterceptor{}
+
+	c := NewWithOptions(b, &Options{
+		Interceptors: []Interceptor{i1, i2},
+	})
+
+	err := c.SignalWorkflow(ctx, instanceID, "test", "signal")
+
+	require.Nil(t, err)
+	require.Equal(t, 1, i1.calls)
+	require.Equal(t, 1, i2.calls)
+	b.AssertExpectations(t)
+}
+
+func workflowToTrace(ctx workflow.Context) error {
+	return nil
+}
+
+func Test_Client_CreateWorkflowInstance_Tracing(t *testing.T) {
+	ctx := context.Background()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	var startedEvent history.WorkflowEvent
+
+	b := &backend.MockBackend{}
+	b.On("CreateWorkflowInstance", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		startedEvent = args.Get(1).(history.WorkflowEvent)
+	}).Return(nil)
+
+	c := NewWithOptions(b, &Options{
+		TracerProvider: tp,
+	})
+
+	wfi, err := c.CreateWorkflowInstance(ctx, WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+	}, workflowToTrace)
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, "CreateWorkflowInstance", spans[0].Name)
+	require.Contains(t, spans[0].Attributes, attribute.String(tracing.AttributeInstanceID, wfi.GetInstanceID()))
+
+	// Trace context is propagated to the workflow
+	a := startedEvent.HistoryEvent.Attributes.(*history.ExecutionStartedAttributes)
+	require.Contains(t, a.Metadata["traceparent"], spans[0].SpanContext.TraceID().String())
+	b.AssertExpectations(t)
+}
+
+func Test_Client_PauseSchedule_RetriesOnConflict(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := schedule.New("schedule", ScheduleSpec{Interval: time.Minute}, "wf", nil, time.Now())
+	require.NoError(t, err)
+	s.Version = 1
+
+	b := &backend.MockBackend{}
+	b.On("GetSchedule", ctx, "schedule").Return(func(context.Context, string) *schedule.Schedule {
+		c := *s
+		return &c
+	}, nil)
+	b.On("UpdateSchedule", ctx, mock.Anything).Return(backend.ErrScheduleModified).Once()
+	b.On("UpdateSchedule", ctx, mock.MatchedBy(func(s *schedule.Schedule) bool {
+		return s.Paused
+	})).Return(nil).Once()
+
+	c := New(b)
+
+	require.NoError(t, c.PauseSchedule(ctx, "schedule"))
+	b.AssertExpectations(t)
+	b.AssertNumberOfCalls(t, "GetSchedule", 2)
+}
+
+func Test_Client_CreateSchedule_InvalidSpec(t *testing.T) {
+	b := &backend.MockBackend{}
+	c := New(b)
+
+	_, err := c.CreateSchedule(context.Background(), "schedule", ScheduleSpec{Cron: "invalid"}, workflowToTrace)
+	require.Error(t, err)
+	b.AssertNotCalled(t, "CreateSchedule", mock.Anything, mock.Anything)
+}
+
+func Test_Client_CreateWorkflowInstance_StartDelay(t *testing.T) {
+	ctx := context.Background()
+
+	b := &backend.MockBackend{}
+	b.On("CreateWorkflowInstance", mock.Anything, mock.MatchedBy(func(m history.WorkflowEvent) bool {
+		return m.HistoryEvent.Type == history.EventType_WorkflowExecutionStarted &&
+			m.HistoryEvent.VisibleAt != nil &&
+			m.HistoryEvent.VisibleAt.Sub(m.HistoryEvent.Timestamp) == time.Hour
+	})).Return(nil)
+
+	c := New(b)
+
+	_, err := c.CreateWorkflowInstance(ctx, WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+		StartDelay: time.Hour,
+	}, workflowToTrace)
+	require.NoError(t, err)
+	b.AssertExpectations(t)
+
+	_, err = c.CreateWorkflowInstance(ctx, WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+		StartAt:    time.Now().Add(time.Hour),
+		StartDelay: time.Hour,
+	}, workflowToTrace)
+	require.Error(t, err)
+
+	_, err = c.CreateWorkflowInstance(ctx, WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+		StartDelay: -time.Hour,
+	}, workflowToTrace)
+	require.Error(t, err)
+	b.AssertNumberOfCalls(t, "CreateWorkflowInstance", 1)
+}
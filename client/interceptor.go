@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+type CreateWorkflowInstanceFunc func(ctx context.Context, options WorkflowInstanceOptions, wf workflow.Workflow, args []interface{}) (workflow.Instance, error)
+
+type CancelWorkflowInstanceFunc func(ctx context.Context, instance workflow.Instance) error
+
+type RemoveWorkflowInstanceFunc func(ctx context.Context, instance workflow.Instance) error
+
+type SignalWorkflowFunc func(ctx context.Context, instanceID string, name string, arg interface{}) error
+
+// Interceptor allows to add cross-cutting behavior to client calls. Interceptors are invoked in
+// the order they are registered, each one has to call next to continue the chain. Embed
+// NoopInterceptor to only override some methods.
+type Interceptor interface {
+	CreateWorkflowInstance(ctx context.Context, options WorkflowInstanceOptions, wf workflow.Workflow, args []interface{}, next CreateWorkflowInstanceFunc) (workflow.Instance, error)
+
+	CancelWorkflowInstance(ctx context.Context, instance workflow.Instance, next CancelWorkflowInstanceFunc) error
+
+	RemoveWorkflowInstance(ctx context.Context, instance workflow.Instance, next RemoveWorkflowInstanceFunc) error
+
+	SignalWorkflow(ctx context.Context, instanceID string, name string, arg interface{}, next SignalWorkflowFunc) error
+}
+
+// NoopInterceptor passes all calls on to the next interceptor in the chain
+type NoopInterceptor struct{}
+
+var _ Interceptor = (*NoopInterceptor)(nil)
+
+func (NoopInterceptor) CreateWorkflowInstance(ctx context.Context, options WorkflowInstanceOptions, wf workflow.Workflow, args []interface{}, next CreateWorkflowInstanceFunc) (workflow.Instance, error) {
+	return next(ctx, options, wf, args)
+}
+
+func (NoopInterceptor) CancelWorkflowInstance(ctx context.Context, instance workflow.Instance, next CancelWorkflowInstanceFunc) error {
+	return next(ctx, instance)
+}
+
+func (NoopInterceptor) RemoveWorkflowInstance(ctx context.Context, instance workflow.Instance, next RemoveWorkflowInstanceFunc) error {
+	return next(ctx, instance)
+}
+
+func (NoopInterceptor) SignalWorkflow(ctx context.Context, instanceID string, name string, arg interface{}, next SignalWorkflowFunc) error {
+	return next(ctx, instanceID, name, arg)
+}
+
+func interceptCreateWorkflowInstance(interceptors []Interceptor, fn CreateWorkflowInstanceFunc) CreateWorkflowInstanceFunc {
+	if len(interceptors) == 0 {
+		return fn
+	}
+
+	return func(ctx context.Context, options WorkflowInstanceOptions, wf workflow.Workflow, args []interface{}) (workflow.Instance, error) {
+		return interceptors[0].CreateWorkflowInstance(ctx, options, wf, args, interceptCreateWorkflowInstance(interceptors[1:], fn))
+	}
+}
+
+func interceptCancelWorkflowInstance(interceptors []Interceptor, fn CancelWorkflowInstanceFunc) CancelWorkflowInstanceFunc {
+	if len(interceptors) == 0 {
+		return fn
+	}
+
+	return func(ctx context.Context, instance workflow.Instance) error {
+		return interceptors[0].CancelWorkflowInstance(ctx, instance, interceptCancelWorkflowInstance(interceptors[1:], fn))
+	}
+}
+
+func interceptRemoveWorkflowInstance(interceptors []Interceptor, fn RemoveWorkflowInstanceFunc) RemoveWorkflowInstanceFunc {
+	if len(interceptors) == 0 {
+		return fn
+	}
+
+	return func(ctx context.Context, instance workflow.Instance) error {
+		return interceptors[0].RemoveWorkflowInstance(ctx, instance, interceptRemoveWorkflowInstance(interceptors[1:], fn))
+	}
+}
+
+func interceptSignalWorkflow(interceptors []Interceptor, fn SignalWorkflowFunc) SignalWorkflowFunc {
+	if len(interceptors) == 0 {
+		return fn
+	}
+
+	return func(ctx context.Context, instanceID string, name string, arg interface{}) error {
+		return interceptors[0].SignalWorkflow(ctx, instanceID, name, arg, interceptSignalWorkflow(interceptors[1:], fn))
+	}
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	a "github.com/cschleiden/go-workflows/internal/args"
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/fn"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/internal/schedule"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/pkg/errors"
+)
+
+// ScheduleSpec describes when a schedule starts workflow instances
+type ScheduleSpec = schedule.Spec
+
+// Schedule is a schedule together with its current state
+type Schedule = schedule.Schedule
+
+// OverlapPolicy determines what happens if a scheduled run is due while the previous run is
+// still running
+type OverlapPolicy = schedule.OverlapPolicy
+
+const (
+	OverlapSkip        = schedule.OverlapSkip
+	OverlapBuffer      = schedule.OverlapBuffer
+	OverlapCancelOther = schedule.OverlapCancelOther
+)
+
+// maxScheduleUpdateAttempts is the number of times an update is retried when the schedule is
+// modified concurrently, e.g. by a worker starting a run
+const maxScheduleUpdateAttempts = 10
+
+func (c *client) CreateSchedule(ctx context.Context, id string, spec ScheduleSpec, wf workflow.Workflow, args ...interface{}) (*Schedule, error) {
+	inputs, err := a.ArgsToInputs(converter.WithContext(ctx, c.converter), args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not convert arguments")
+	}
+
+	s, err := schedule.New(id, spec, fn.Name(wf), inputs, time.Now())
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid schedule")
+	}
+
+	if err := c.backend.CreateSchedule(ctx, s); err != nil {
+		return nil, err
+	}
+
+	c.logger.Debug("Created schedule", log.ScheduleIDKey, id, log.WorkflowNameKey, s.WorkflowName)
+
+	return s, nil
+}
+
+func (c *client) GetSchedule(ctx context.Context, id string) (*Schedule, error) {
+	return c.backend.GetSchedule(ctx, id)
+}
+
+func (c *client) ListSchedules(ctx context.Context) ([]*Schedule, error) {
+	return c.backend.ListSchedules(ctx)
+}
+
+func (c *client) PauseSchedule(ctx context.Context, id string) error {
+	return c.updateSchedule(ctx, id, func(s *Schedule, now time.Time) error {
+		s.Pause(now)
+		return nil
+	})
+}
+
+func (c *client) ResumeSchedule(ctx context.Context, id string) error {
+	return c.updateSchedule(ctx, id, func(s *Schedule, now time.Time) error {
+		return s.Resume(now)
+	})
+}
+
+func (c *client) BackfillSchedule(ctx context.Context, id string, start, end time.Time) error {
+	return c.updateSchedule(ctx, id, func(s *Schedule, now time.Time) error {
+		times, err := s.Spec.Times(s.CreatedAt, start, end, schedule.MaxBackfillRuns)
+		if err != nil {
+			return errors.Wrap(err, "could not backfill schedule")
+		}
+
+		s.Backfill(times, now)
+		return nil
+	})
+}
+
+func (c *client) DeleteSchedule(ctx context.Context, id string) error {
+	if err := c.backend.DeleteSchedule(ctx, id); err != nil {
+		return err
+	}
+
+	c.logger.Debug("Deleted schedule", log.ScheduleIDKey, id)
+
+	return nil
+}
+
+// updateSchedule applies update to the current state of the schedule, retrying if it was modified concurrently
+func (c *client) updateSchedule(ctx context.Context, id string, update func(s *Schedule, now time.Time) error) error {
+	for attempt := 0; ; attempt++ {
+		s, err := c.backend.GetSchedule(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := update(s, time.Now()); err != nil {
+			return err
+		}
+
+		err = c.backend.UpdateSchedule(ctx, s)
+		if err == nil || !errors.Is(err, backend.ErrScheduleModified) || attempt+1 == maxScheduleUpdateAttempts {
+			return err
+		}
+	}
+}
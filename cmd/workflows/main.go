@@ -0,0 +1,113 @@
+// Command workflows provides tools for operating go-workflows backends.
+//
+// Export the history of a workflow instance:
+//
+//	workflows export -backend sqlite -dsn simple.sqlite -instance <id> -execution <id> [-o history.json]
+//
+// Exported histories can be replayed against workflow code using the replayer package.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/mysql"
+	"github.com/cschleiden/go-workflows/backend/postgres"
+	redisbackend "github.com/cschleiden/go-workflows/backend/redis"
+	"github.com/cschleiden/go-workflows/backend/sqlite"
+	"github.com/cschleiden/go-workflows/export"
+	"github.com/go-redis/redis/v8"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = runExport(os.Args[2:])
+	default:
+		usage()
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: workflows export -backend <sqlite|mysql|postgres|redis> -dsn <dsn> -instance <id> -execution <id> [-o <file>]")
+	os.Exit(2)
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	backendType := fs.String("backend", "sqlite", "backend type: sqlite, mysql, postgres, or redis")
+	dsn := fs.String("dsn", "", "data source name of the backend, a redis:// URL for redis")
+	instanceID := fs.String("instance", "", "workflow instance id")
+	executionID := fs.String("execution", "", "workflow execution id")
+	out := fs.String("o", "", "output file, defaults to stdout")
+	fs.Parse(args)
+
+	if *dsn == "" || *instanceID == "" || *executionID == "" {
+		fs.Usage()
+		return fmt.Errorf("-dsn, -instance, and -execution are required")
+	}
+
+	b, err := openBackend(*backendType, *dsn)
+	if err != nil {
+		return err
+	}
+
+	if c, ok := b.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	h, err := export.Export(context.Background(), b, *instanceID, *executionID)
+	if err != nil {
+		return err
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		w = f
+	}
+
+	return h.Write(w)
+}
+
+func openBackend(backendType, dsn string) (backend.Backend, error) {
+	// Exporting only reads, never change the schema
+	opts := []backend.BackendOption{backend.WithApplyMigrations(false)}
+
+	switch backendType {
+	case "sqlite":
+		return sqlite.NewSqliteBackendFromDSN(dsn, opts...)
+	case "mysql":
+		return mysql.NewMysqlBackendFromDSN(dsn, opts...)
+	case "postgres":
+		return postgres.NewPostgresBackendFromDSN(dsn, opts...)
+	case "redis":
+		redisOptions, err := redis.ParseURL(dsn)
+		if err != nil {
+			return nil, err
+		}
+
+		return redisbackend.NewRedisBackend(redis.NewClient(redisOptions), opts...), nil
+	default:
+		return nil, fmt.Errorf("unknown backend type %q", backendType)
+	}
+}
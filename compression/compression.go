@@ -0,0 +1,100 @@
+// Package compression implements the compression algorithms available for payloads and
+// serialized event attributes.
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type Algorithm string
+
+const (
+	// None disables compression
+	None Algorithm = ""
+
+	Gzip Algorithm = "gzip"
+
+	Zstd Algorithm = "zstd"
+)
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+func initZstd() {
+	zstdOnce.Do(func() {
+		zstdEncoder, zstdErr = zstd.NewWriter(nil)
+		if zstdErr != nil {
+			return
+		}
+
+		zstdDecoder, zstdErr = zstd.NewReader(nil)
+	})
+}
+
+// Compress compresses data with the given algorithm
+func Compress(a Algorithm, data []byte) ([]byte, error) {
+	switch a {
+	case None:
+		return data, nil
+
+	case Gzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+
+	case Zstd:
+		initZstd()
+		if zstdErr != nil {
+			return nil, zstdErr
+		}
+
+		return zstdEncoder.EncodeAll(data, nil), nil
+	}
+
+	return nil, fmt.Errorf("unknown compression algorithm %q", a)
+}
+
+// Decompress decompresses data compressed with the given algorithm
+func Decompress(a Algorithm, data []byte) ([]byte, error) {
+	switch a {
+	case None:
+		return data, nil
+
+	case Gzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		return io.ReadAll(r)
+
+	case Zstd:
+		initZstd()
+		if zstdErr != nil {
+			return nil, zstdErr
+		}
+
+		return zstdDecoder.DecodeAll(data, nil)
+	}
+
+	return nil, fmt.Errorf("unknown compression algorithm %q", a)
+}
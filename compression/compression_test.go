@@ -0,0 +1,35 @@
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Compression(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"value":"abcdefgh"}`), 100)
+
+	for _, a := range []Algorithm{None, Gzip, Zstd} {
+		t.Run(string(a), func(t *testing.T) {
+			c, err := Compress(a, data)
+			require.NoError(t, err)
+
+			if a != None {
+				require.Less(t, len(c), len(data))
+			}
+
+			d, err := Decompress(a, c)
+			require.NoError(t, err)
+			require.Equal(t, data, d)
+		})
+	}
+}
+
+func Test_Compression_UnknownAlgorithm(t *testing.T) {
+	_, err := Compress("lz4", []byte("data"))
+	require.Error(t, err)
+
+	_, err = Decompress("lz4", []byte("data"))
+	require.Error(t, err)
+}
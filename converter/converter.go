@@ -0,0 +1,134 @@
+package converter
+
+import (
+	"time"
+
+	"github.com/cschleiden/go-workflows/blob"
+	"github.com/cschleiden/go-workflows/compression"
+	internal "github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// Payload is the serialized form of a value created by a Converter
+type Payload = []byte
+
+// Converter converts values to and from payloads. Clients and workers operating on the same
+// backend need to use compatible converters.
+type Converter = internal.DataConverter
+
+// EncodingConverter is a converter with a name for its encoding, which is recorded in the metadata
+// of the payloads created by clients and workers
+type EncodingConverter = internal.EncodingDataConverter
+
+// EncodedPayload is the form in which payloads are stored in workflow histories, together with
+// metadata describing how they were serialized. Codecs operate on encoded payloads.
+type EncodedPayload = payload.Payload
+
+// Keys of well-known payload metadata entries
+const (
+	MetadataEncoding    = payload.MetadataEncoding
+	MetadataContentType = payload.MetadataContentType
+	MetadataSchemaID    = payload.MetadataSchemaID
+)
+
+// NewEncodedPayload returns an encoded payload with the given data and metadata
+func NewEncodedPayload(data []byte, metadata map[string]string) EncodedPayload {
+	return payload.New(data, metadata)
+}
+
+// EncodingJSON is the encoding of payloads produced by JSONConverter
+const EncodingJSON = internal.EncodingJSON
+
+// JSONConverter serializes values using encoding/json
+var JSONConverter = internal.ToEncodingDataConverter(internal.JSONConverter)
+
+// DefaultConverter serializes values as JSON
+var DefaultConverter = internal.ToDataConverter(internal.DefaultConverter)
+
+// NewCompositeConverter creates a converter that encodes values with the first of the given
+// converters, and decodes payloads with the converter matching their encoding. Payloads without
+// encoding metadata are decoded as JSON.
+func NewCompositeConverter(converters ...EncodingConverter) EncodingConverter {
+	cs := make([]internal.EncodingConverter, 0, len(converters))
+	for _, c := range converters {
+		cs = append(cs, internal.FromEncodingDataConverter(c))
+	}
+
+	return internal.ToEncodingDataConverter(internal.NewCompositeConverter(cs...))
+}
+
+// Encodings of payloads produced by the protobuf converters
+const (
+	EncodingProto     = internal.EncodingProto
+	EncodingProtoJSON = internal.EncodingProtoJSON
+)
+
+// NewProtoConverter returns a converter encoding proto.Message values in the protobuf binary
+// format, and all other values as JSON. Payloads for message arguments and results can be
+// decoded into pointers to messages, e.g. *pb.Message.
+func NewProtoConverter() EncodingConverter {
+	return internal.ToEncodingDataConverter(internal.NewProtoConverter())
+}
+
+// NewProtoJSONConverter returns a converter encoding proto.Message values using protojson, and
+// all other values as JSON.
+func NewProtoJSONConverter() EncodingConverter {
+	return internal.ToEncodingDataConverter(internal.NewProtoJSONConverter())
+}
+
+// Codec transforms payloads after they have been created by a converter, and before they are
+// decoded again, e.g. to encrypt them
+type Codec = internal.Codec
+
+// NewCodecConverter wraps the given converter, so that payloads are passed through the codecs in
+// order after encoding, and in reverse order before decoding.
+func NewCodecConverter(c Converter, codecs ...Codec) Converter {
+	return internal.ToDataConverter(internal.NewCodecConverter(internal.FromDataConverter(c), codecs...))
+}
+
+// EncodingEncrypted is the encoding of payloads encrypted by the AES-GCM codec
+const EncodingEncrypted = internal.EncodingEncrypted
+
+// MetadataEncryptionKeyID is the metadata entry recording the ID of the key a payload was encrypted with
+const MetadataEncryptionKeyID = internal.MetadataEncryptionKeyID
+
+// NewAESGCMCodec returns a codec encrypting payloads with AES-GCM. New payloads are encrypted with
+// the key identified by keyID, and payloads are decrypted with the key recorded in their metadata.
+// Payloads that are not encrypted are passed through unchanged. Only payloads are encrypted, error
+// messages and other parts of the history are stored in plain text.
+func NewAESGCMCodec(keyID string, keys map[string][]byte) (Codec, error) {
+	return internal.NewAESGCMCodec(keyID, keys)
+}
+
+// EncodingCompressed is the encoding of payloads compressed by the compression codec
+const EncodingCompressed = internal.EncodingCompressed
+
+// MetadataCompression is the metadata entry recording the algorithm a payload was compressed with
+const MetadataCompression = internal.MetadataCompression
+
+// NewCompressionCodec returns a codec compressing payloads whose data is at least threshold bytes
+// long. When combined with encryption, the compression codec needs to come first.
+func NewCompressionCodec(algorithm compression.Algorithm, threshold int) Codec {
+	return internal.NewCompressionCodec(algorithm, threshold)
+}
+
+// MetadataBlobSize is the metadata entry recording the size of an offloaded payload's data
+const MetadataBlobSize = internal.MetadataBlobSize
+
+// NewOffloadCodec returns a codec storing payloads whose data is at least threshold bytes long in
+// the given blob store, and replacing them with a reference. Referenced payloads are only fetched
+// when they are decoded, e.g. in Future.Get. Offloading needs to be the last codec.
+func NewOffloadCodec(store blob.Store, threshold int, opts ...OffloadOption) Codec {
+	return internal.NewOffloadCodec(store, threshold, opts...)
+}
+
+// DefaultOffloadTimeout is the default timeout for storing and fetching a single blob
+const DefaultOffloadTimeout = internal.DefaultOffloadTimeout
+
+type OffloadOption = internal.OffloadOption
+
+// WithOffloadTimeout sets the timeout for storing and fetching a single blob. Defaults to
+// DefaultOffloadTimeout.
+func WithOffloadTimeout(timeout time.Duration) OffloadOption {
+	return internal.WithOffloadTimeout(timeout)
+}
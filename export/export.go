@@ -0,0 +1,104 @@
+// Package export defines a stable JSON format for the history of a workflow instance, to move
+// histories between systems, for example to reproduce problems locally.
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/cschleiden/go-workflows/archive"
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/pkg/errors"
+)
+
+// FormatVersion is the version of the export format written by this package. Version 2 writes
+// payloads as objects with their metadata, version 1 wrote them as plain base64 strings. Both
+// versions can be read.
+const FormatVersion = 2
+
+// History is the exported history of a workflow instance. Events are written with the name of
+// their type and the same attributes that are stored by the backends.
+type History struct {
+	FormatVersion int       `json:"format_version"`
+	InstanceID    string    `json:"instance_id"`
+	ExecutionID   string    `json:"execution_id"`
+	ExportedAt    time.Time `json:"exported_at"`
+
+	Events []history.Event `json:"events"`
+}
+
+// Export reads the history of the given workflow instance from b
+func Export(ctx context.Context, b backend.Backend, instanceID, executionID string) (*History, error) {
+	events, err := b.GetWorkflowInstanceHistory(ctx, core.NewWorkflowInstance(instanceID, executionID))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get workflow instance history")
+	}
+
+	return &History{
+		FormatVersion: FormatVersion,
+		InstanceID:    instanceID,
+		ExecutionID:   executionID,
+		ExportedAt:    time.Now().UTC(),
+		Events:        events,
+	}, nil
+}
+
+// FromArchive converts an archived workflow instance into an exported history, for example to
+// replay it
+func FromArchive(a *archive.Archive) (*History, error) {
+	var events []history.Event
+	if err := json.Unmarshal(a.History, &events); err != nil {
+		return nil, errors.Wrap(err, "could not read archived history")
+	}
+
+	return &History{
+		FormatVersion: FormatVersion,
+		InstanceID:    a.InstanceID,
+		ExecutionID:   a.ExecutionID,
+		ExportedAt:    time.Now().UTC(),
+		Events:        events,
+	}, nil
+}
+
+// Write writes the history as indented JSON to w
+func (h *History) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(h); err != nil {
+		return errors.Wrap(err, "could not write history")
+	}
+
+	return nil
+}
+
+// Read reads a history written by Write
+func Read(r io.Reader) (*History, error) {
+	var h History
+	if err := json.NewDecoder(r).Decode(&h); err != nil {
+		return nil, errors.Wrap(err, "could not read history")
+	}
+
+	if h.FormatVersion < 1 || h.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("unsupported history format version %d", h.FormatVersion)
+	}
+
+	return &h, nil
+}
+
+// ReadFile reads a history from the file at path
+func ReadFile(path string) (*History, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open history")
+	}
+	defer f.Close()
+
+	return Read(f)
+}
@@ -0,0 +1,94 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/memory"
+	"github.com/cschleiden/go-workflows/internal/archiving"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ExportAndRead(t *testing.T) {
+	ctx := context.Background()
+	b := memory.NewMemoryBackend()
+
+	wfi := core.NewWorkflowInstance("instance", "execution")
+	require.NoError(t, b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: wfi,
+		HistoryEvent:     history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Name: "wf"}),
+	}))
+
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+	require.NoError(t, b.CompleteWorkflowTask(ctx, wfi, task.NewEvents, []history.WorkflowEvent{}))
+
+	h, err := Export(ctx, b, "instance", "execution")
+	require.NoError(t, err)
+	require.Equal(t, FormatVersion, h.FormatVersion)
+	require.Len(t, h.Events, 1)
+
+	var buf bytes.Buffer
+	require.NoError(t, h.Write(&buf))
+	require.Contains(t, buf.String(), `"type": "WorkflowExecutionStarted"`)
+
+	h2, err := Read(&buf)
+	require.NoError(t, err)
+	require.Equal(t, "instance", h2.InstanceID)
+	require.Equal(t, "wf", h2.Events[0].Attributes.(*history.ExecutionStartedAttributes).Name)
+}
+
+func Test_FromArchive(t *testing.T) {
+	events := []history.Event{
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Name: "wf"}),
+	}
+
+	a, err := archiving.New(context.Background(), nil, &archiving.Instance{
+		InstanceID:  "instance",
+		ExecutionID: "execution",
+		CompletedAt: time.Now(),
+		History:     events,
+	})
+	require.NoError(t, err)
+
+	h, err := FromArchive(a)
+	require.NoError(t, err)
+	require.Equal(t, FormatVersion, h.FormatVersion)
+	require.Equal(t, "execution", h.ExecutionID)
+	require.Len(t, h.Events, 1)
+	require.Equal(t, "wf", h.Events[0].Attributes.(*history.ExecutionStartedAttributes).Name)
+}
+
+func Test_Export_NotFound(t *testing.T) {
+	_, err := Export(context.Background(), memory.NewMemoryBackend(), "instance", "execution")
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+}
+
+func Test_Read_Version1(t *testing.T) {
+	h, err := Read(strings.NewReader(`{
+		"format_version": 1,
+		"instance_id": "instance",
+		"events": [{
+			"id": "1",
+			"type": "WorkflowExecutionFinished",
+			"attributes": {"result": "NDI="}
+		}]
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, 1, h.FormatVersion)
+
+	result := h.Events[0].Attributes.(*history.ExecutionCompletedAttributes).Result
+	require.Equal(t, []byte("42"), result.Data)
+	require.Empty(t, result.Metadata)
+}
+
+func Test_Read_UnsupportedVersion(t *testing.T) {
+	_, err := Read(strings.NewReader(`{"format_version": 3, "events": []}`))
+	require.Error(t, err)
+}
@@ -4,72 +4,158 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/cschleiden/go-workflows/internal/args"
 	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/metrickeys"
 	"github.com/cschleiden/go-workflows/internal/payload"
 	"github.com/cschleiden/go-workflows/internal/task"
 	"github.com/cschleiden/go-workflows/internal/workflow"
+	"github.com/cschleiden/go-workflows/metrics"
 	"github.com/pkg/errors"
 )
 
 type Executor struct {
-	r *workflow.Registry
+	r            *workflow.Registry
+	interceptors []Interceptor
+	metrics      metrics.Client
+	converter    converter.Converter
 }
 
-func NewExecutor(r *workflow.Registry) Executor {
-	return Executor{
-		r: r,
+type ExecutorOption func(*Executor)
+
+// WithInterceptors registers interceptors that are invoked for every activity execution
+func WithInterceptors(interceptors ...Interceptor) ExecutorOption {
+	return func(e *Executor) {
+		e.interceptors = append(e.interceptors, interceptors...)
+	}
+}
+
+// WithMetrics sets the client used to record activity executions and their duration
+func WithMetrics(client metrics.Client) ExecutorOption {
+	return func(e *Executor) {
+		e.metrics = client
+	}
+}
+
+// WithConverter sets the converter used for activity inputs and results
+func WithConverter(c converter.Converter) ExecutorOption {
+	return func(e *Executor) {
+		e.converter = c
 	}
 }
+
+func NewExecutor(r *workflow.Registry, opts ...ExecutorOption) Executor {
+	e := Executor{
+		r:         r,
+		metrics:   metrics.NewNoopMetricsClient(),
+		converter: converter.DefaultConverter,
+	}
+
+	for _, opt := range opts {
+		opt(&e)
+	}
+
+	return e
+}
+
 func (e *Executor) ExecuteActivity(ctx context.Context, task *task.Activity) (payload.Payload, error) {
 	a := task.Event.Attributes.(*history.ActivityScheduledAttributes)
 
 	activity, err := e.r.GetActivity(a.Name)
 	if err != nil {
-		return nil, errors.Wrap(err, "could not find activity in registry")
+		return payload.Payload{}, errors.Wrap(err, "could not find activity in registry")
 	}
 
 	activityFn := reflect.ValueOf(activity)
 	if activityFn.Type().Kind() != reflect.Func {
-		return nil, errors.New("activity not a function")
+		return payload.Payload{}, errors.New("activity not a function")
 	}
 
-	args, addContext, err := args.InputsToArgs(converter.DefaultConverter, activityFn, a.Inputs)
-	if err != nil {
-		return nil, errors.Wrap(err, "could not convert activity inputs")
+	numOut := activityFn.Type().NumOut()
+	if numOut < 1 || numOut > 2 {
+		return payload.Payload{}, errors.New("activity has to return either (error) or (<result>, error)")
 	}
 
-	if addContext {
-		args[0] = reflect.ValueOf(ctx)
+	args, addContext, err := args.InputsToArgs(converter.WithContext(ctx, e.converter), activityFn, a.Inputs)
+	if err != nil {
+		return payload.Payload{}, errors.Wrap(err, "could not convert activity inputs")
 	}
 
-	r := activityFn.Call(args)
+	// Arguments passed to interceptors do not include the context
+	rawArgs := make([]interface{}, 0, len(args))
+	for i, arg := range args {
+		if i == 0 && addContext {
+			continue
+		}
 
-	if len(r) < 1 || len(r) > 2 {
-		return nil, errors.New("activity has to return either (error) or (<result>, error)")
+		rawArgs = append(rawArgs, arg.Interface())
 	}
 
-	var result payload.Payload
+	call := func(ctx context.Context, rawArgs []interface{}) (interface{}, error) {
+		callArgs := make([]reflect.Value, 0, len(rawArgs)+1)
+		if addContext {
+			callArgs = append(callArgs, reflect.ValueOf(ctx))
+		}
 
-	if len(r) > 1 {
-		var err error
-		result, err = converter.DefaultConverter.To(r[0].Interface())
-		if err != nil {
-			return nil, errors.Wrap(err, "could not convert activity result")
+		for _, arg := range rawArgs {
+			if arg == nil {
+				// Interceptors might have replaced arguments with untyped nil values
+				callArgs = append(callArgs, reflect.Zero(activityFn.Type().In(len(callArgs))))
+				continue
+			}
+
+			callArgs = append(callArgs, reflect.ValueOf(arg))
+		}
+
+		r := activityFn.Call(callArgs)
+
+		var result interface{}
+		if len(r) > 1 {
+			result = r[0].Interface()
+		}
+
+		errResult := r[len(r)-1]
+		if errResult.IsNil() {
+			return result, nil
+		}
+
+		errInterface, ok := errResult.Interface().(error)
+		if !ok {
+			return nil, fmt.Errorf("activity error result does not satisfy error interface (%T): %v", errResult, errResult)
 		}
+
+		return result, errInterface
 	}
 
-	errResult := r[len(r)-1]
-	if errResult.IsNil() {
-		return result, nil
+	start := time.Now()
+
+	r, err := intercept(e.interceptors, task.WorkflowInstance, a.Name, call)(ctx, rawArgs)
+
+	result := metrickeys.ResultSuccess
+	if err != nil {
+		result = metrickeys.ResultError
 	}
 
-	errInterface, ok := errResult.Interface().(error)
-	if !ok {
-		return nil, fmt.Errorf("activity error result does not satisfy error interface (%T): %v", errResult, errResult)
+	e.metrics.Distribution(metrickeys.ActivityDuration, metrics.Tags{
+		metrickeys.ActivityName: a.Name,
+	}, time.Since(start).Seconds())
+	e.metrics.Counter(metrickeys.ActivityExecutions, metrics.Tags{
+		metrickeys.ActivityName: a.Name,
+		metrickeys.Result:       result,
+	}, 1)
+
+	var p payload.Payload
+
+	if numOut > 1 {
+		var err error
+		p, err = converter.WithContext(ctx, e.converter).To(r)
+		if err != nil {
+			return payload.Payload{}, errors.Wrap(err, "could not convert activity result")
+		}
 	}
 
-	return result, errInterface
+	return p, err
 }
@@ -0,0 +1,114 @@
+package activity
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/cschleiden/go-workflows/internal/workflow"
+	"github.com/stretchr/testify/require"
+)
+
+func activity1(ctx context.Context, a int) (int, error) {
+	return a * 2, nil
+}
+
+type doubleInterceptor struct {
+	names []string
+}
+
+func (i *doubleInterceptor) ExecuteActivity(ctx context.Context, instance core.WorkflowInstance, name string, args []interface{}, next ExecuteActivityFunc) (interface{}, error) {
+	i.names = append(i.names, name)
+
+	r, err := next(ctx, []interface{}{args[0].(int) * 2})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.(int) + 1, nil
+}
+
+func Test_Executor_Interceptors(t *testing.T) {
+	r := workflow.NewRegistry()
+	r.RegisterActivity(activity1)
+
+	i := &doubleInterceptor{}
+	e := NewExecutor(r, WithInterceptors(i))
+
+	input, _ := converter.DefaultConverter.To(21)
+
+	result, err := e.ExecuteActivity(context.Background(), &task.Activity{
+		ID:               "activityID",
+		WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+		Event: history.NewHistoryEvent(
+			time.Now(),
+			history.EventType_ActivityScheduled,
+			&history.ActivityScheduledAttributes{
+				Name:   "activity1",
+				Inputs: []payload.Payload{input},
+			},
+		),
+	})
+	require.NoError(t, err)
+
+	var v int
+	require.NoError(t, converter.DefaultConverter.From(result, &v))
+	require.Equal(t, 85, v)
+	require.Equal(t, []string{"activity1"}, i.names)
+}
+
+// prefixConverter wraps JSON payloads in a marker, so payloads created by the default
+// converter cannot be read
+type prefixConverter struct{}
+
+func (prefixConverter) To(v interface{}) (payload.Payload, error) {
+	p, err := converter.DefaultConverter.To(v)
+	if err != nil {
+		return payload.Payload{}, err
+	}
+
+	return payload.New(append([]byte("x:"), p.Data...), nil), nil
+}
+
+func (prefixConverter) From(data payload.Payload, v interface{}) error {
+	if !bytes.HasPrefix(data.Data, []byte("x:")) {
+		return errors.New("missing prefix")
+	}
+
+	return converter.DefaultConverter.From(payload.New(data.Data[2:], nil), v)
+}
+
+func Test_Executor_Converter(t *testing.T) {
+	r := workflow.NewRegistry()
+	r.RegisterActivity(activity1)
+
+	c := prefixConverter{}
+	e := NewExecutor(r, WithConverter(c))
+
+	input, _ := c.To(21)
+
+	result, err := e.ExecuteActivity(context.Background(), &task.Activity{
+		ID:               "activityID",
+		WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+		Event: history.NewHistoryEvent(
+			time.Now(),
+			history.EventType_ActivityScheduled,
+			&history.ActivityScheduledAttributes{
+				Name:   "activity1",
+				Inputs: []payload.Payload{input},
+			},
+		),
+	})
+	require.NoError(t, err)
+
+	var v int
+	require.NoError(t, c.From(result, &v))
+	require.Equal(t, 42, v)
+}
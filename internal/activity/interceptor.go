@@ -0,0 +1,26 @@
+package activity
+
+import (
+	"context"
+
+	"github.com/cschleiden/go-workflows/internal/core"
+)
+
+type ExecuteActivityFunc func(ctx context.Context, args []interface{}) (interface{}, error)
+
+// Interceptor allows to add cross-cutting behavior to activity executions. Interceptors are
+// invoked in the order they are registered, each one has to call next to continue the chain.
+type Interceptor interface {
+	// ExecuteActivity is called when an activity is executed by a worker
+	ExecuteActivity(ctx context.Context, instance core.WorkflowInstance, name string, args []interface{}, next ExecuteActivityFunc) (interface{}, error)
+}
+
+func intercept(interceptors []Interceptor, instance core.WorkflowInstance, name string, fn ExecuteActivityFunc) ExecuteActivityFunc {
+	if len(interceptors) == 0 {
+		return fn
+	}
+
+	return func(ctx context.Context, args []interface{}) (interface{}, error) {
+		return interceptors[0].ExecuteActivity(ctx, instance, name, args, intercept(interceptors[1:], instance, name, fn))
+	}
+}
@@ -0,0 +1,78 @@
+// Package archiving creates archives of completed workflow instances for the backends.
+package archiving
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/cschleiden/go-workflows/archive"
+	"github.com/cschleiden/go-workflows/blob"
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/pkg/errors"
+)
+
+// Instance is a completed workflow instance read from a backend
+type Instance struct {
+	InstanceID       string
+	ExecutionID      string
+	ParentInstanceID string
+	CompletedAt      time.Time
+	History          []history.Event
+}
+
+// New creates an archive for the given instance. The result is taken from the history's finished
+// event, if there is one. If store is set, offloaded payloads are fetched and stored in the archive,
+// so that it's still complete once the instance's blobs have been deleted.
+func New(ctx context.Context, store blob.Store, instance *Instance) (*archive.Archive, error) {
+	if store != nil {
+		if err := inlineBlobs(ctx, store, instance.History); err != nil {
+			return nil, err
+		}
+	}
+
+	h, err := json.Marshal(instance.History)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not serialize history")
+	}
+
+	a := &archive.Archive{
+		InstanceID:       instance.InstanceID,
+		ExecutionID:      instance.ExecutionID,
+		ParentInstanceID: instance.ParentInstanceID,
+		CompletedAt:      instance.CompletedAt,
+		History:          h,
+	}
+
+	for _, e := range instance.History {
+		if e.Type != history.EventType_WorkflowExecutionFinished {
+			continue
+		}
+
+		if attr, ok := e.Attributes.(*history.ExecutionCompletedAttributes); ok {
+			a.Result = attr.Result
+			a.Error = attr.Error
+		}
+	}
+
+	return a, nil
+}
+
+func inlineBlobs(ctx context.Context, store blob.Store, events []history.Event) error {
+	for _, e := range events {
+		if err := history.ReplacePayloads(e, func(p payload.Payload) (payload.Payload, error) {
+			key, ok := blob.Reference(p)
+			if !ok {
+				return p, nil
+			}
+
+			return converter.FetchBlob(ctx, store, key)
+		}); err != nil {
+			return errors.Wrap(err, "could not inline offloaded payload")
+		}
+	}
+
+	return nil
+}
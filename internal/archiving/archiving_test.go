@@ -0,0 +1,73 @@
+package archiving
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/blob"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_New(t *testing.T) {
+	events := []history.Event{
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Name: "wf"}),
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{Result: payload.New([]byte(`"done"`), nil), Error: "failed"}),
+	}
+
+	a, err := New(context.Background(), nil, &Instance{
+		InstanceID:       "orders/1",
+		ExecutionID:      "exec",
+		ParentInstanceID: "parent",
+		CompletedAt:      time.Now(),
+		History:          events,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "parent", a.ParentInstanceID)
+	require.Equal(t, `"done"`, string(a.Result.Data))
+	require.Equal(t, "failed", a.Error)
+
+	var loaded []history.Event
+	require.NoError(t, json.Unmarshal(a.History, &loaded))
+	require.Len(t, loaded, 2)
+	require.Equal(t, events[0].ID, loaded[0].ID)
+	require.Equal(t, "wf", loaded[0].Attributes.(*history.ExecutionStartedAttributes).Name)
+}
+
+func Test_New_InlinesBlobs(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := blob.NewFileSystem(t.TempDir())
+	require.NoError(t, err)
+
+	input := payload.New([]byte(`"input"`), map[string]string{payload.MetadataEncoding: "json/plain"})
+	data, err := json.Marshal(input)
+	require.NoError(t, err)
+	require.NoError(t, store.Put(ctx, "input", data))
+
+	ref := payload.New([]byte{}, map[string]string{
+		payload.MetadataEncoding: blob.EncodingReference,
+		blob.MetadataKey:         "input",
+	})
+
+	a, err := New(ctx, store, &Instance{
+		InstanceID:  "instance",
+		ExecutionID: "exec",
+		History: []history.Event{
+			history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+				Inputs: []payload.Payload{ref},
+			}),
+		},
+	})
+	require.NoError(t, err)
+
+	// The archive does not depend on the blob anymore
+	require.NoError(t, store.Delete(ctx, "input"))
+
+	var loaded []history.Event
+	require.NoError(t, json.Unmarshal(a.History, &loaded))
+	require.Equal(t, input, loaded[0].Attributes.(*history.ExecutionStartedAttributes).Inputs[0])
+}
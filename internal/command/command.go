@@ -24,6 +24,25 @@ const (
 	CommandType_CompleteWorkflow
 )
 
+func (ct CommandType) String() string {
+	switch ct {
+	case CommandType_ScheduleActivityTask:
+		return "ScheduleActivityTask"
+	case CommandType_ScheduleSubWorkflow:
+		return "ScheduleSubWorkflow"
+	case CommandType_ScheduleTimer:
+		return "ScheduleTimer"
+	case CommandType_CancelTimer:
+		return "CancelTimer"
+	case CommandType_SideEffect:
+		return "SideEffect"
+	case CommandType_CompleteWorkflow:
+		return "CompleteWorkflow"
+	default:
+		return "Unknown"
+	}
+}
+
 type CommandState int
 
 const (
@@ -43,17 +62,19 @@ type Command struct {
 }
 
 type ScheduleActivityTaskCommandAttr struct {
-	Name   string
-	Inputs []payload.Payload
+	Name     string
+	Inputs   []payload.Payload
+	Priority int
 }
 
-func NewScheduleActivityTaskCommand(id int, name string, inputs []payload.Payload) Command {
+func NewScheduleActivityTaskCommand(id int, name string, inputs []payload.Payload, priority int) Command {
 	return Command{
 		ID:   id,
 		Type: CommandType_ScheduleActivityTask,
 		Attr: &ScheduleActivityTaskCommandAttr{
-			Name:   name,
-			Inputs: inputs,
+			Name:     name,
+			Inputs:   inputs,
+			Priority: priority,
 		},
 	}
 }
@@ -62,9 +83,10 @@ type ScheduleSubWorkflowCommandAttr struct {
 	InstanceID string
 	Name       string
 	Inputs     []payload.Payload
+	Priority   int
 }
 
-func NewScheduleSubWorkflowCommand(id int, instanceID, name string, inputs []payload.Payload) Command {
+func NewScheduleSubWorkflowCommand(id int, instanceID, name string, inputs []payload.Payload, priority int) Command {
 	if instanceID == "" {
 		instanceID = uuid.New().String()
 	}
@@ -76,20 +98,25 @@ func NewScheduleSubWorkflowCommand(id int, instanceID, name string, inputs []pay
 			InstanceID: instanceID,
 			Name:       name,
 			Inputs:     inputs,
+			Priority:   priority,
 		},
 	}
 }
 
 type ScheduleTimerCommandAttr struct {
 	At time.Time
+
+	// Delay is the duration the timer was scheduled with
+	Delay time.Duration
 }
 
-func NewScheduleTimerCommand(id int, at time.Time) Command {
+func NewScheduleTimerCommand(id int, at time.Time, delay time.Duration) Command {
 	return Command{
 		ID:   id,
 		Type: CommandType_ScheduleTimer,
 		Attr: &ScheduleTimerCommandAttr{
-			At: at,
+			At:    at,
+			Delay: delay,
 		},
 	}
 }
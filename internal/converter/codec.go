@@ -0,0 +1,89 @@
+package converter
+
+import (
+	"context"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// Codec transforms payloads after they have been created by a converter, and before they are
+// decoded again, e.g. to encrypt them.
+type Codec interface {
+	Encode(p payload.Payload) (payload.Payload, error)
+	Decode(p payload.Payload) (payload.Payload, error)
+}
+
+// ContextCodec is a codec whose operations depend on a context, e.g. because they store payloads
+// in an external system
+type ContextCodec interface {
+	Codec
+
+	// WithContext returns a codec using ctx for its operations
+	WithContext(ctx context.Context) Codec
+}
+
+// WithContext returns c with its context-dependent codecs using ctx, so that encoding and
+// decoding payloads is canceled together with ctx and honors its deadline. Codecs use a
+// background context otherwise.
+func WithContext(ctx context.Context, c Converter) Converter {
+	cc, ok := c.(*codecConverter)
+	if !ok {
+		return c
+	}
+
+	codecs := make([]Codec, len(cc.codecs))
+	for i, codec := range cc.codecs {
+		if contextCodec, ok := codec.(ContextCodec); ok {
+			codec = contextCodec.WithContext(ctx)
+		}
+
+		codecs[i] = codec
+	}
+
+	return &codecConverter{
+		c:      WithContext(ctx, cc.c),
+		codecs: codecs,
+	}
+}
+
+type codecConverter struct {
+	c      Converter
+	codecs []Codec
+}
+
+// NewCodecConverter wraps the given converter, so that payloads are passed through the codecs in
+// order after encoding, and in reverse order before decoding.
+func NewCodecConverter(c Converter, codecs ...Codec) Converter {
+	return &codecConverter{
+		c:      c,
+		codecs: codecs,
+	}
+}
+
+func (cc *codecConverter) To(v interface{}) (payload.Payload, error) {
+	p, err := cc.c.To(v)
+	if err != nil {
+		return payload.Payload{}, err
+	}
+
+	for _, codec := range cc.codecs {
+		p, err = codec.Encode(p)
+		if err != nil {
+			return payload.Payload{}, err
+		}
+	}
+
+	return p, nil
+}
+
+func (cc *codecConverter) From(data payload.Payload, vptr interface{}) error {
+	for i := len(cc.codecs) - 1; i >= 0; i-- {
+		var err error
+		data, err = cc.codecs[i].Decode(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return cc.c.From(data, vptr)
+}
@@ -0,0 +1,66 @@
+package converter
+
+import (
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// CompositeConverter encodes values with the first of its converters, and decodes payloads with
+// the converter matching the encoding recorded in their metadata. This allows switching to a
+// different encoding while histories recorded with the previous one are still being processed.
+type CompositeConverter struct {
+	encoder  EncodingConverter
+	decoders map[string]EncodingConverter
+}
+
+var _ EncodingConverter = (*CompositeConverter)(nil)
+
+// NewCompositeConverter creates a converter from the given converters. The first converter is used
+// for encoding values. Payloads without encoding metadata are decoded as JSON, and if no converters
+// are given, values are encoded as JSON, too.
+func NewCompositeConverter(converters ...EncodingConverter) *CompositeConverter {
+	cc := &CompositeConverter{
+		encoder:  JSONConverter,
+		decoders: map[string]EncodingConverter{},
+	}
+
+	if len(converters) > 0 {
+		cc.encoder = converters[0]
+	}
+
+	for _, c := range converters {
+		if _, ok := cc.decoders[c.Encoding()]; !ok {
+			cc.decoders[c.Encoding()] = c
+		}
+	}
+
+	if _, ok := cc.decoders[EncodingJSON]; !ok {
+		cc.decoders[EncodingJSON] = JSONConverter
+	}
+
+	return cc
+}
+
+// Encoding returns the encoding of payloads created by this converter
+func (cc *CompositeConverter) Encoding() string {
+	return cc.encoder.Encoding()
+}
+
+func (cc *CompositeConverter) To(v interface{}) (payload.Payload, error) {
+	return cc.encoder.To(v)
+}
+
+func (cc *CompositeConverter) From(data payload.Payload, vptr interface{}) error {
+	enc := data.Encoding()
+	if enc == "" {
+		enc = EncodingJSON
+	}
+
+	c, ok := cc.decoders[enc]
+	if !ok {
+		return fmt.Errorf("no converter registered for payload encoding %q", enc)
+	}
+
+	return c.From(data, vptr)
+}
@@ -0,0 +1,63 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/stretchr/testify/require"
+)
+
+const encodingUpper = "test/upper"
+
+type upperConverter struct{}
+
+func (upperConverter) Encoding() string {
+	return encodingUpper
+}
+
+func (upperConverter) To(v interface{}) (payload.Payload, error) {
+	return payload.New([]byte(strings.ToUpper(v.(string))), map[string]string{
+		payload.MetadataEncoding: encodingUpper,
+	}), nil
+}
+
+func (upperConverter) From(data payload.Payload, vptr interface{}) error {
+	*(vptr.(*string)) = string(data.Data)
+	return nil
+}
+
+func Test_CompositeConverter(t *testing.T) {
+	c := NewCompositeConverter(upperConverter{}, JSONConverter)
+
+	p, err := c.To("hello")
+	require.NoError(t, err)
+	require.Equal(t, encodingUpper, p.Encoding())
+
+	var s string
+	require.NoError(t, c.From(p, &s))
+	require.Equal(t, "HELLO", s)
+
+	// Payloads are decoded with the converter matching their encoding
+	jp, err := JSONConverter.To("hello")
+	require.NoError(t, err)
+	require.NoError(t, c.From(jp, &s))
+	require.Equal(t, "hello", s)
+}
+
+func Test_CompositeConverter_Legacy(t *testing.T) {
+	c := NewCompositeConverter(upperConverter{})
+
+	// Payloads without metadata are always JSON
+	var s string
+	require.NoError(t, c.From(payload.New([]byte(`"hello"`), nil), &s))
+	require.Equal(t, "hello", s)
+}
+
+func Test_CompositeConverter_UnknownEncoding(t *testing.T) {
+	c := NewCompositeConverter()
+
+	var s string
+	err := c.From(payload.New([]byte("x"), map[string]string{payload.MetadataEncoding: "unknown"}), &s)
+	require.Error(t, err)
+}
@@ -0,0 +1,73 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/compression"
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// EncodingCompressed is the encoding of payloads compressed by the compression codec
+const EncodingCompressed = "binary/compressed"
+
+// MetadataCompression is the metadata entry recording the algorithm a payload was compressed with
+const MetadataCompression = "compression"
+
+type compressionCodec struct {
+	algorithm compression.Algorithm
+	threshold int
+}
+
+// NewCompressionCodec returns a codec compressing payloads whose data is at least threshold bytes
+// long. Payloads that are not compressed are passed through unchanged on decoding.
+func NewCompressionCodec(algorithm compression.Algorithm, threshold int) Codec {
+	return &compressionCodec{
+		algorithm: algorithm,
+		threshold: threshold,
+	}
+}
+
+func (c *compressionCodec) Encode(p payload.Payload) (payload.Payload, error) {
+	if c.algorithm == compression.None || len(p.Data) < c.threshold {
+		return p, nil
+	}
+
+	// Compress the complete payload, so that its metadata is restored on decompression
+	data, err := json.Marshal(p)
+	if err != nil {
+		return payload.Payload{}, err
+	}
+
+	compressed, err := compression.Compress(c.algorithm, data)
+	if err != nil {
+		return payload.Payload{}, fmt.Errorf("compressing payload: %w", err)
+	}
+
+	if len(compressed) >= len(p.Data) {
+		return p, nil
+	}
+
+	return payload.New(compressed, map[string]string{
+		payload.MetadataEncoding: EncodingCompressed,
+		MetadataCompression:      string(c.algorithm),
+	}), nil
+}
+
+func (c *compressionCodec) Decode(p payload.Payload) (payload.Payload, error) {
+	if p.Encoding() != EncodingCompressed {
+		return p, nil
+	}
+
+	data, err := compression.Decompress(compression.Algorithm(p.Metadata[MetadataCompression]), p.Data)
+	if err != nil {
+		return payload.Payload{}, fmt.Errorf("decompressing payload: %w", err)
+	}
+
+	var r payload.Payload
+	if err := json.Unmarshal(data, &r); err != nil {
+		return payload.Payload{}, fmt.Errorf("decoding decompressed payload: %w", err)
+	}
+
+	return r, nil
+}
@@ -0,0 +1,57 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cschleiden/go-workflows/compression"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CompressionCodec(t *testing.T) {
+	for _, a := range []compression.Algorithm{compression.Gzip, compression.Zstd} {
+		t.Run(string(a), func(t *testing.T) {
+			c := NewCodecConverter(DefaultConverter, NewCompressionCodec(a, 256))
+
+			v := strings.Repeat("abcd", 256)
+			p, err := c.To(v)
+			require.NoError(t, err)
+			require.Equal(t, EncodingCompressed, p.Encoding())
+			require.Equal(t, string(a), p.Metadata[MetadataCompression])
+			require.Less(t, len(p.Data), len(v))
+
+			var r string
+			require.NoError(t, c.From(p, &r))
+			require.Equal(t, v, r)
+		})
+	}
+}
+
+func Test_CompressionCodec_BelowThreshold(t *testing.T) {
+	c := NewCodecConverter(DefaultConverter, NewCompressionCodec(compression.Gzip, 256))
+
+	p, err := c.To("small")
+	require.NoError(t, err)
+	require.Equal(t, EncodingJSON, p.Encoding())
+
+	var r string
+	require.NoError(t, c.From(p, &r))
+	require.Equal(t, "small", r)
+}
+
+func Test_CompressionCodec_Encryption(t *testing.T) {
+	// Payloads need to be compressed before they are encrypted
+	encryption, err := NewAESGCMCodec("k1", map[string][]byte{"k1": key1})
+	require.NoError(t, err)
+	c := NewCodecConverter(DefaultConverter, NewCompressionCodec(compression.Zstd, 256), encryption)
+
+	v := strings.Repeat("abcd", 256)
+	p, err := c.To(v)
+	require.NoError(t, err)
+	require.Equal(t, EncodingEncrypted, p.Encoding())
+	require.Less(t, len(p.Data), len(v))
+
+	var r string
+	require.NoError(t, c.From(p, &r))
+	require.Equal(t, v, r)
+}
@@ -12,7 +12,16 @@ type Converter interface {
 	From(data payload.Payload, v interface{}) error
 }
 
-var DefaultConverter Converter = &jsonConverter{}
+// EncodingConverter is a converter that records the name of its encoding in the metadata of the
+// payloads it creates.
+type EncodingConverter interface {
+	Converter
+
+	// Encoding returns the name of the encoding, e.g. "json/plain"
+	Encoding() string
+}
+
+var DefaultConverter Converter = NewCompositeConverter(JSONConverter)
 
 func AssignValue(c Converter, v interface{}, vptr interface{}) error {
 	vvptr := reflect.ValueOf(vptr)
@@ -28,7 +37,7 @@ func AssignValue(c Converter, v interface{}, vptr interface{}) error {
 
 	// Try converting value first
 	if vp, ok := v.(payload.Payload); ok {
-		if vp == nil {
+		if vp.IsEmpty() {
 			vvptr.Elem().Set(reflect.Zero(vvptr.Elem().Type()))
 			return nil
 		}
@@ -0,0 +1,120 @@
+package converter
+
+import (
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// DataConverter converts values to and from serialized data. This is the interface implemented by
+// user converters, payload metadata is added when the data is wrapped in a payload.
+type DataConverter interface {
+	To(v interface{}) ([]byte, error)
+	From(data []byte, vptr interface{}) error
+}
+
+// EncodingDataConverter is a data converter with a name for its encoding. The name is recorded in
+// the metadata of the payloads created from its data.
+type EncodingDataConverter interface {
+	DataConverter
+
+	// Encoding returns the name of the encoding, e.g. "json/plain"
+	Encoding() string
+}
+
+// FromDataConverter returns a converter creating payloads from the data of c. Data converters
+// returned by ToDataConverter are unwrapped again, so that no metadata is lost.
+func FromDataConverter(c DataConverter) Converter {
+	if dc, ok := c.(*payloadDataConverter); ok {
+		return dc.c
+	}
+
+	if dc, ok := c.(*encodingPayloadDataConverter); ok {
+		return dc.c
+	}
+
+	if ec, ok := c.(EncodingDataConverter); ok {
+		return &dataConverter{c: c, encoding: ec.Encoding()}
+	}
+
+	return &dataConverter{c: c}
+}
+
+// FromEncodingDataConverter is like FromDataConverter, but keeps the encoding of c
+func FromEncodingDataConverter(c EncodingDataConverter) EncodingConverter {
+	if dc, ok := c.(*encodingPayloadDataConverter); ok {
+		return dc.c
+	}
+
+	return &dataConverter{c: c, encoding: c.Encoding()}
+}
+
+// ToDataConverter returns a data converter for c. Payload metadata is dropped when the data
+// converter is used directly, but kept when it's converted back using FromDataConverter.
+func ToDataConverter(c Converter) DataConverter {
+	if ec, ok := c.(EncodingConverter); ok {
+		return ToEncodingDataConverter(ec)
+	}
+
+	return &payloadDataConverter{c: c}
+}
+
+// ToEncodingDataConverter is like ToDataConverter, but keeps the encoding of c
+func ToEncodingDataConverter(c EncodingConverter) EncodingDataConverter {
+	return &encodingPayloadDataConverter{c: c}
+}
+
+type dataConverter struct {
+	c        DataConverter
+	encoding string
+}
+
+func (dc *dataConverter) Encoding() string {
+	return dc.encoding
+}
+
+func (dc *dataConverter) To(v interface{}) (payload.Payload, error) {
+	data, err := dc.c.To(v)
+	if err != nil {
+		return payload.Payload{}, err
+	}
+
+	var metadata map[string]string
+	if dc.encoding != "" {
+		metadata = map[string]string{payload.MetadataEncoding: dc.encoding}
+	}
+
+	return payload.New(data, metadata), nil
+}
+
+func (dc *dataConverter) From(data payload.Payload, vptr interface{}) error {
+	return dc.c.From(data.Data, vptr)
+}
+
+type payloadDataConverter struct {
+	c Converter
+}
+
+func (dc *payloadDataConverter) To(v interface{}) ([]byte, error) {
+	p, err := dc.c.To(v)
+	return p.Data, err
+}
+
+func (dc *payloadDataConverter) From(data []byte, vptr interface{}) error {
+	return dc.c.From(payload.New(data, nil), vptr)
+}
+
+type encodingPayloadDataConverter struct {
+	c EncodingConverter
+}
+
+func (dc *encodingPayloadDataConverter) Encoding() string {
+	return dc.c.Encoding()
+}
+
+func (dc *encodingPayloadDataConverter) To(v interface{}) ([]byte, error) {
+	p, err := dc.c.To(v)
+	return p.Data, err
+}
+
+func (dc *encodingPayloadDataConverter) From(data []byte, vptr interface{}) error {
+	return dc.c.From(payload.New(data, map[string]string{payload.MetadataEncoding: dc.c.Encoding()}), vptr)
+}
@@ -0,0 +1,84 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/stretchr/testify/require"
+)
+
+type upperDataConverter struct{}
+
+func (upperDataConverter) To(v interface{}) ([]byte, error) {
+	return []byte(strings.ToUpper(v.(string))), nil
+}
+
+func (upperDataConverter) From(data []byte, vptr interface{}) error {
+	*(vptr.(*string)) = string(data)
+	return nil
+}
+
+type upperEncodingDataConverter struct {
+	upperDataConverter
+}
+
+func (upperEncodingDataConverter) Encoding() string {
+	return encodingUpper
+}
+
+func Test_FromDataConverter(t *testing.T) {
+	c := FromDataConverter(upperDataConverter{})
+
+	p, err := c.To("hello")
+	require.NoError(t, err)
+	require.Equal(t, []byte("HELLO"), p.Data)
+	require.Empty(t, p.Encoding())
+
+	var s string
+	require.NoError(t, c.From(p, &s))
+	require.Equal(t, "HELLO", s)
+}
+
+func Test_FromDataConverter_RecordsEncoding(t *testing.T) {
+	c := FromDataConverter(upperEncodingDataConverter{})
+
+	p, err := c.To("hello")
+	require.NoError(t, err)
+	require.Equal(t, encodingUpper, p.Encoding())
+
+	// Can be combined with converters working on payloads
+	cc := NewCompositeConverter(FromEncodingDataConverter(upperEncodingDataConverter{}), JSONConverter)
+
+	var s string
+	require.NoError(t, cc.From(p, &s))
+	require.Equal(t, "HELLO", s)
+
+	jp, err := JSONConverter.To("world")
+	require.NoError(t, err)
+	require.NoError(t, cc.From(jp, &s))
+	require.Equal(t, "world", s)
+}
+
+func Test_ToDataConverter_Unwraps(t *testing.T) {
+	require.Same(t, DefaultConverter, FromDataConverter(ToDataConverter(DefaultConverter)))
+
+	proto := NewProtoConverter()
+	require.Same(t, proto, FromEncodingDataConverter(ToEncodingDataConverter(proto)))
+}
+
+func Test_ToDataConverter(t *testing.T) {
+	dc := ToDataConverter(JSONConverter)
+
+	data, err := dc.To("hello")
+	require.NoError(t, err)
+	require.Equal(t, `"hello"`, string(data))
+
+	var s string
+	require.NoError(t, dc.From(data, &s))
+	require.Equal(t, "hello", s)
+
+	// Data without metadata is decoded as JSON
+	require.NoError(t, DefaultConverter.From(payload.New(data, nil), &s))
+	require.Equal(t, "hello", s)
+}
@@ -0,0 +1,111 @@
+package converter
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// EncodingEncrypted is the encoding of payloads encrypted by the AES-GCM codec
+const EncodingEncrypted = "binary/encrypted"
+
+// MetadataEncryptionKeyID is the metadata entry recording the ID of the key a payload was encrypted with
+const MetadataEncryptionKeyID = "encryption-key-id"
+
+type aesGCMCodec struct {
+	keyID string
+	aeads map[string]cipher.AEAD
+}
+
+// NewAESGCMCodec returns a codec encrypting payloads with AES-GCM. New payloads are encrypted with
+// the key identified by keyID, and payloads are decrypted with the key recorded in their metadata.
+// To rotate keys, add a new key and make it the active one, keeping old keys as long as histories
+// encrypted with them are still around. Keys need to be 16, 24, or 32 bytes long.
+//
+// Payloads that are not encrypted, e.g. ones recorded before encryption was enabled, are passed
+// through unchanged. Error messages and other parts of the history are not payloads, and are not
+// encrypted.
+func NewAESGCMCodec(keyID string, keys map[string][]byte) (Codec, error) {
+	if _, ok := keys[keyID]; !ok {
+		return nil, fmt.Errorf("key %q not found", keyID)
+	}
+
+	c := &aesGCMCodec{
+		keyID: keyID,
+		aeads: make(map[string]cipher.AEAD, len(keys)),
+	}
+
+	for id, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("creating cipher for key %q: %w", id, err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("creating cipher for key %q: %w", id, err)
+		}
+
+		c.aeads[id] = aead
+	}
+
+	return c, nil
+}
+
+func (c *aesGCMCodec) Encode(p payload.Payload) (payload.Payload, error) {
+	if p.IsEmpty() {
+		return p, nil
+	}
+
+	// Encrypt the complete payload, so that its metadata is restored on decryption
+	plaintext, err := json.Marshal(p)
+	if err != nil {
+		return payload.Payload{}, err
+	}
+
+	aead := c.aeads[c.keyID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return payload.Payload{}, fmt.Errorf("generating nonce: %w", err)
+	}
+
+	return payload.New(aead.Seal(nonce, nonce, plaintext, []byte(c.keyID)), map[string]string{
+		payload.MetadataEncoding: EncodingEncrypted,
+		MetadataEncryptionKeyID:  c.keyID,
+	}), nil
+}
+
+func (c *aesGCMCodec) Decode(p payload.Payload) (payload.Payload, error) {
+	if p.Encoding() != EncodingEncrypted {
+		return p, nil
+	}
+
+	keyID := p.Metadata[MetadataEncryptionKeyID]
+	aead, ok := c.aeads[keyID]
+	if !ok {
+		return payload.Payload{}, fmt.Errorf("payload encrypted with unknown key %q", keyID)
+	}
+
+	if len(p.Data) < aead.NonceSize() {
+		return payload.Payload{}, errors.New("encrypted payload too short")
+	}
+
+	nonce, ciphertext := p.Data[:aead.NonceSize()], p.Data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(keyID))
+	if err != nil {
+		return payload.Payload{}, fmt.Errorf("decrypting payload: %w", err)
+	}
+
+	var r payload.Payload
+	if err := json.Unmarshal(plaintext, &r); err != nil {
+		return payload.Payload{}, fmt.Errorf("decoding decrypted payload: %w", err)
+	}
+
+	return r, nil
+}
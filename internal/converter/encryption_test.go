@@ -0,0 +1,100 @@
+package converter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	key1 = []byte("0123456789abcdef0123456789abcdef")
+	key2 = []byte("fedcba9876543210")
+)
+
+func Test_AESGCMCodec(t *testing.T) {
+	codec, err := NewAESGCMCodec("k1", map[string][]byte{"k1": key1})
+	require.NoError(t, err)
+
+	c := NewCodecConverter(DefaultConverter, codec)
+
+	p, err := c.To("secret")
+	require.NoError(t, err)
+	require.Equal(t, EncodingEncrypted, p.Encoding())
+	require.Equal(t, "k1", p.Metadata[MetadataEncryptionKeyID])
+	require.False(t, bytes.Contains(p.Data, []byte("secret")))
+
+	var s string
+	require.NoError(t, c.From(p, &s))
+	require.Equal(t, "secret", s)
+
+	// Metadata of the inner payload is restored
+	inner, err := codec.Decode(p)
+	require.NoError(t, err)
+	require.Equal(t, EncodingJSON, inner.Encoding())
+}
+
+func Test_AESGCMCodec_KeyRotation(t *testing.T) {
+	old, err := NewAESGCMCodec("k1", map[string][]byte{"k1": key1})
+	require.NoError(t, err)
+
+	p, err := NewCodecConverter(DefaultConverter, old).To(42)
+	require.NoError(t, err)
+
+	rotated, err := NewAESGCMCodec("k2", map[string][]byte{"k1": key1, "k2": key2})
+	require.NoError(t, err)
+	c := NewCodecConverter(DefaultConverter, rotated)
+
+	var r int
+	require.NoError(t, c.From(p, &r))
+	require.Equal(t, 42, r)
+
+	p2, err := c.To(42)
+	require.NoError(t, err)
+	require.Equal(t, "k2", p2.Metadata[MetadataEncryptionKeyID])
+
+	// Payloads encrypted with a removed key cannot be decrypted
+	removed, err := NewAESGCMCodec("k2", map[string][]byte{"k2": key2})
+	require.NoError(t, err)
+	require.Error(t, NewCodecConverter(DefaultConverter, removed).From(p, &r))
+}
+
+func Test_AESGCMCodec_Unencrypted(t *testing.T) {
+	codec, err := NewAESGCMCodec("k1", map[string][]byte{"k1": key1})
+	require.NoError(t, err)
+	c := NewCodecConverter(DefaultConverter, codec)
+
+	// Payloads recorded before encryption was enabled are still readable
+	p, err := DefaultConverter.To(42)
+	require.NoError(t, err)
+
+	var r int
+	require.NoError(t, c.From(p, &r))
+	require.Equal(t, 42, r)
+
+	// Empty payloads are left empty
+	e, err := codec.Encode(payload.Payload{})
+	require.NoError(t, err)
+	require.True(t, e.IsEmpty())
+}
+
+func Test_AESGCMCodec_Tampered(t *testing.T) {
+	codec, err := NewAESGCMCodec("k1", map[string][]byte{"k1": key1})
+	require.NoError(t, err)
+
+	p, err := codec.Encode(payload.New([]byte("42"), nil))
+	require.NoError(t, err)
+
+	p.Data[len(p.Data)-1] ^= 0xff
+	_, err = codec.Decode(p)
+	require.Error(t, err)
+}
+
+func Test_NewAESGCMCodec_Errors(t *testing.T) {
+	_, err := NewAESGCMCodec("missing", map[string][]byte{"k1": key1})
+	require.Error(t, err)
+
+	_, err = NewAESGCMCodec("k1", map[string][]byte{"k1": []byte("short")})
+	require.Error(t, err)
+}
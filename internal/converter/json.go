@@ -2,16 +2,40 @@ package converter
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/cschleiden/go-workflows/internal/payload"
 )
 
+// EncodingJSON is the encoding of payloads produced by the JSON converter
+const EncodingJSON = "json/plain"
+
+// JSONConverter serializes values using encoding/json
+var JSONConverter EncodingConverter = &jsonConverter{}
+
 type jsonConverter struct{}
 
+func (jc *jsonConverter) Encoding() string {
+	return EncodingJSON
+}
+
 func (jc *jsonConverter) To(v interface{}) (payload.Payload, error) {
-	return json.Marshal(v)
+	data, err := json.Marshal(v)
+	if err != nil {
+		return payload.Payload{}, err
+	}
+
+	return payload.New(data, map[string]string{
+		payload.MetadataEncoding:    EncodingJSON,
+		payload.MetadataContentType: "application/json",
+	}), nil
 }
 
 func (jc *jsonConverter) From(data payload.Payload, vptr interface{}) error {
-	return json.Unmarshal(data, vptr)
+	// Payloads without an encoding were recorded before metadata was introduced, and are always JSON
+	if enc := data.Encoding(); enc != "" && enc != EncodingJSON {
+		return fmt.Errorf("cannot decode payload with encoding %q as JSON", enc)
+	}
+
+	return json.Unmarshal(data.Data, vptr)
 }
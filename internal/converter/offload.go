@@ -0,0 +1,204 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/cschleiden/go-workflows/blob"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/google/uuid"
+)
+
+// MetadataBlobSize is the metadata entry recording the size of an offloaded payload's data
+const MetadataBlobSize = "blob-size"
+
+// DefaultOffloadTimeout is the default timeout for storing and fetching a single blob
+const DefaultOffloadTimeout = 30 * time.Second
+
+type offloadCodec struct {
+	store     blob.Store
+	threshold int
+	timeout   time.Duration
+
+	// ctx is the context blobs are stored and fetched with, see WithContext
+	ctx context.Context
+}
+
+var _ ContextCodec = (*offloadCodec)(nil)
+
+type OffloadOption func(*offloadCodec)
+
+// WithOffloadTimeout sets the timeout for storing and fetching a single blob
+func WithOffloadTimeout(timeout time.Duration) OffloadOption {
+	return func(c *offloadCodec) {
+		c.timeout = timeout
+	}
+}
+
+// NewOffloadCodec returns a codec storing payloads whose data is at least threshold bytes long in
+// the given blob store. Payloads are replaced with a reference to the blob, which is fetched again
+// when the payload is decoded. Blobs are stored and fetched with the context of the client or
+// activity converting the payload, and with a background context in workflows.
+func NewOffloadCodec(store blob.Store, threshold int, opts ...OffloadOption) Codec {
+	c := &offloadCodec{
+		store:     store,
+		threshold: threshold,
+		timeout:   DefaultOffloadTimeout,
+		ctx:       context.Background(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func (c *offloadCodec) WithContext(ctx context.Context) Codec {
+	cc := *c
+	cc.ctx = ctx
+
+	return &cc
+}
+
+func (c *offloadCodec) Encode(p payload.Payload) (payload.Payload, error) {
+	if len(p.Data) < c.threshold {
+		return p, nil
+	}
+
+	// Store the complete payload, so that its metadata is restored when it's fetched
+	data, err := json.Marshal(p)
+	if err != nil {
+		return payload.Payload{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, c.timeout)
+	defer cancel()
+
+	key := uuid.NewString()
+	if err := c.store.Put(ctx, key, data); err != nil {
+		return payload.Payload{}, fmt.Errorf("storing payload: %w", err)
+	}
+
+	return payload.New([]byte{}, map[string]string{
+		payload.MetadataEncoding: blob.EncodingReference,
+		blob.MetadataKey:         key,
+		MetadataBlobSize:         strconv.Itoa(len(p.Data)),
+	}), nil
+}
+
+func (c *offloadCodec) Decode(p payload.Payload) (payload.Payload, error) {
+	key, ok := blob.Reference(p)
+	if !ok {
+		return p, nil
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, c.timeout)
+	defer cancel()
+
+	return FetchBlob(ctx, c.store, key)
+}
+
+// FetchBlob returns the payload stored in the blob with the given key by the offload codec
+func FetchBlob(ctx context.Context, store blob.Store, key string) (payload.Payload, error) {
+	data, err := store.Get(ctx, key)
+	if err != nil {
+		return payload.Payload{}, fmt.Errorf("fetching payload %v: %w", key, err)
+	}
+
+	var r payload.Payload
+	if err := json.Unmarshal(data, &r); err != nil {
+		return payload.Payload{}, fmt.Errorf("decoding fetched payload: %w", err)
+	}
+
+	return r, nil
+}
+
+// WithoutOffloading returns c without any offloading codecs. Payloads created by c are then kept
+// in the history, regardless of their size.
+func WithoutOffloading(c Converter) Converter {
+	cc, ok := c.(*codecConverter)
+	if !ok {
+		return c
+	}
+
+	codecs := make([]Codec, 0, len(cc.codecs))
+	for _, codec := range cc.codecs {
+		if _, ok := codec.(*offloadCodec); !ok {
+			codecs = append(codecs, codec)
+		}
+	}
+
+	return &codecConverter{
+		c:      WithoutOffloading(cc.c),
+		codecs: codecs,
+	}
+}
+
+// CopyBlobs returns payloads, with the blobs of payloads offloaded by c copied to new keys. This
+// allows passing the same payloads to multiple workflow instances, each owning its own blobs.
+func CopyBlobs(ctx context.Context, c Converter, payloads []payload.Payload) ([]payload.Payload, error) {
+	r := make([]payload.Payload, len(payloads))
+	for i, p := range payloads {
+		key, ok := blob.Reference(p)
+		if !ok {
+			r[i] = p
+			continue
+		}
+
+		oc := offloadCodecOf(c)
+		if oc == nil {
+			return nil, fmt.Errorf("copying payload %v: no blob store configured", key)
+		}
+
+		cp, err := oc.copy(ctx, p, key)
+		if err != nil {
+			return nil, err
+		}
+
+		r[i] = cp
+	}
+
+	return r, nil
+}
+
+func offloadCodecOf(c Converter) *offloadCodec {
+	cc, ok := c.(*codecConverter)
+	if !ok {
+		return nil
+	}
+
+	for _, codec := range cc.codecs {
+		if oc, ok := codec.(*offloadCodec); ok {
+			return oc
+		}
+	}
+
+	return offloadCodecOf(cc.c)
+}
+
+func (c *offloadCodec) copy(ctx context.Context, p payload.Payload, key string) (payload.Payload, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	data, err := c.store.Get(ctx, key)
+	if err != nil {
+		return payload.Payload{}, fmt.Errorf("fetching payload %v: %w", key, err)
+	}
+
+	newKey := uuid.NewString()
+	if err := c.store.Put(ctx, newKey, data); err != nil {
+		return payload.Payload{}, fmt.Errorf("storing payload: %w", err)
+	}
+
+	metadata := make(map[string]string, len(p.Metadata))
+	for k, v := range p.Metadata {
+		metadata[k] = v
+	}
+	metadata[blob.MetadataKey] = newKey
+
+	return payload.New(p.Data, metadata), nil
+}
@@ -0,0 +1,146 @@
+package converter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/blob"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	blobs map[string][]byte
+	gets  int
+}
+
+func (s *memoryStore) Put(ctx context.Context, key string, data []byte) error {
+	s.blobs[key] = data
+	return nil
+}
+
+func (s *memoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.gets++
+	data, ok := s.blobs[key]
+	if !ok {
+		return nil, blob.ErrNotFound
+	}
+
+	return data, nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, key string) error {
+	delete(s.blobs, key)
+	return nil
+}
+
+func Test_OffloadCodec(t *testing.T) {
+	store := &memoryStore{blobs: map[string][]byte{}}
+	c := NewCodecConverter(DefaultConverter, NewOffloadCodec(store, 128))
+
+	v := strings.Repeat("a", 1024)
+	p, err := c.To(v)
+	require.NoError(t, err)
+
+	key, ok := blob.Reference(p)
+	require.True(t, ok)
+	require.Contains(t, store.blobs, key)
+	require.Empty(t, p.Data)
+	require.Equal(t, "1026", p.Metadata[MetadataBlobSize])
+
+	// Blobs are only fetched when the payload is decoded
+	require.Equal(t, 0, store.gets)
+
+	var r string
+	require.NoError(t, AssignValue(c, p, &r))
+	require.Equal(t, v, r)
+	require.Equal(t, 1, store.gets)
+}
+
+func Test_OffloadCodec_BelowThreshold(t *testing.T) {
+	store := &memoryStore{blobs: map[string][]byte{}}
+	c := NewCodecConverter(DefaultConverter, NewOffloadCodec(store, 128))
+
+	p, err := c.To("small")
+	require.NoError(t, err)
+	require.Equal(t, EncodingJSON, p.Encoding())
+	require.Empty(t, store.blobs)
+}
+
+func Test_OffloadCodec_MissingBlob(t *testing.T) {
+	store := &memoryStore{blobs: map[string][]byte{}}
+	c := NewCodecConverter(DefaultConverter, NewOffloadCodec(store, 128))
+
+	p, err := c.To(strings.Repeat("a", 1024))
+	require.NoError(t, err)
+
+	for key := range store.blobs {
+		require.NoError(t, store.Delete(context.Background(), key))
+	}
+
+	var r string
+	require.ErrorIs(t, c.From(p, &r), blob.ErrNotFound)
+}
+
+type blockingStore struct {
+	memoryStore
+}
+
+func (s *blockingStore) Put(ctx context.Context, key string, data []byte) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func Test_OffloadCodec_Timeout(t *testing.T) {
+	store := &blockingStore{memoryStore{blobs: map[string][]byte{}}}
+	c := NewCodecConverter(DefaultConverter, NewOffloadCodec(store, 128, WithOffloadTimeout(10*time.Millisecond)))
+
+	_, err := c.To(strings.Repeat("a", 1024))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func Test_CopyBlobs(t *testing.T) {
+	store := &memoryStore{blobs: map[string][]byte{}}
+	c := NewCodecConverter(DefaultConverter, NewOffloadCodec(store, 128))
+
+	v := strings.Repeat("a", 1024)
+	p, err := c.To(v)
+	require.NoError(t, err)
+	small, err := c.To("b")
+	require.NoError(t, err)
+
+	copies, err := CopyBlobs(context.Background(), c, []payload.Payload{p, small})
+	require.NoError(t, err)
+	require.Len(t, copies, 2)
+	require.Equal(t, small, copies[1])
+
+	key, _ := blob.Reference(p)
+	copyKey, ok := blob.Reference(copies[0])
+	require.True(t, ok)
+	require.NotEqual(t, key, copyKey)
+	require.Len(t, store.blobs, 2)
+
+	// Copies are independent of the original blob
+	require.NoError(t, store.Delete(context.Background(), key))
+
+	var r string
+	require.NoError(t, AssignValue(c, copies[0], &r))
+	require.Equal(t, v, r)
+
+	// Without an offload codec, references can't be copied
+	_, err = CopyBlobs(context.Background(), DefaultConverter, []payload.Payload{copies[0]})
+	require.Error(t, err)
+}
+
+func Test_OffloadCodec_UsesCallerContext(t *testing.T) {
+	store := &blockingStore{memoryStore{blobs: map[string][]byte{}}}
+	c := NewCodecConverter(DefaultConverter, NewOffloadCodec(store, 128))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := WithContext(ctx, c).To(strings.Repeat("a", 1024))
+	require.ErrorIs(t, err, context.Canceled)
+}
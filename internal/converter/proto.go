@@ -0,0 +1,119 @@
+package converter
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Encodings of payloads produced by the protobuf converters
+const (
+	EncodingProto     = "binary/protobuf"
+	EncodingProtoJSON = "json/protobuf"
+)
+
+type protoConverter struct {
+	json bool
+}
+
+// NewProtoConverter returns a converter encoding proto.Message values in the protobuf binary
+// format. All other values are encoded as JSON.
+func NewProtoConverter() EncodingConverter {
+	return &protoConverter{}
+}
+
+// NewProtoJSONConverter returns a converter encoding proto.Message values using protojson. All
+// other values are encoded as JSON.
+func NewProtoJSONConverter() EncodingConverter {
+	return &protoConverter{json: true}
+}
+
+func (pc *protoConverter) Encoding() string {
+	if pc.json {
+		return EncodingProtoJSON
+	}
+
+	return EncodingProto
+}
+
+func (pc *protoConverter) To(v interface{}) (payload.Payload, error) {
+	m, ok := v.(proto.Message)
+	if !ok || reflect.ValueOf(v).IsNil() {
+		// Nil messages are encoded as JSON null, so that they are decoded as nil again
+		return JSONConverter.To(v)
+	}
+
+	var data []byte
+	var err error
+	contentType := "application/x-protobuf"
+	if pc.json {
+		data, err = protojson.Marshal(m)
+		contentType = "application/json"
+	} else {
+		data, err = proto.Marshal(m)
+	}
+	if err != nil {
+		return payload.Payload{}, fmt.Errorf("marshaling %v: %w", m.ProtoReflect().Descriptor().FullName(), err)
+	}
+
+	if data == nil {
+		// Messages without any fields set marshal to no data, make sure they can be told apart from no payload
+		data = []byte{}
+	}
+
+	return payload.New(data, map[string]string{
+		payload.MetadataEncoding:    pc.Encoding(),
+		payload.MetadataContentType: contentType,
+		payload.MetadataSchemaID:    string(m.ProtoReflect().Descriptor().FullName()),
+	}), nil
+}
+
+func (pc *protoConverter) From(data payload.Payload, vptr interface{}) error {
+	enc := data.Encoding()
+	if enc != EncodingProto && enc != EncodingProtoJSON {
+		return JSONConverter.From(data, vptr)
+	}
+
+	m, err := protoTarget(vptr)
+	if err != nil {
+		return err
+	}
+
+	if schemaID := data.Metadata[payload.MetadataSchemaID]; schemaID != "" {
+		if name := string(m.ProtoReflect().Descriptor().FullName()); name != schemaID {
+			return fmt.Errorf("cannot decode payload of %v into %v", schemaID, name)
+		}
+	}
+
+	if enc == EncodingProtoJSON {
+		return protojson.Unmarshal(data.Data, m)
+	}
+
+	return proto.Unmarshal(data.Data, m)
+}
+
+// protoTarget returns the message to decode into for the given pointer. This is either the pointer
+// itself if it's a message, or a newly allocated message for a pointer to a message pointer, which
+// is what AssignValue and the argument conversion pass in.
+func protoTarget(vptr interface{}) (proto.Message, error) {
+	if m, ok := vptr.(proto.Message); ok {
+		return m, nil
+	}
+
+	v := reflect.ValueOf(vptr)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		elem := v.Elem()
+		if elem.Kind() == reflect.Ptr {
+			m := reflect.New(elem.Type().Elem())
+			if pm, ok := m.Interface().(proto.Message); ok {
+				elem.Set(m)
+				return pm, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("cannot decode protobuf payload into %T, expected a pointer to a proto.Message", vptr)
+}
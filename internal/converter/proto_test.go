@@ -0,0 +1,119 @@
+package converter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func Test_ProtoConverter(t *testing.T) {
+	tests := []struct {
+		name     string
+		c        EncodingConverter
+		encoding string
+	}{
+		{"binary", NewProtoConverter(), EncodingProto},
+		{"protojson", NewProtoJSONConverter(), EncodingProtoJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// structpb.Value is a oneof, which encoding/json cannot round-trip
+			v := structpb.NewBoolValue(false)
+
+			p, err := tt.c.To(v)
+			require.NoError(t, err)
+			require.Equal(t, tt.encoding, p.Encoding())
+			require.Equal(t, "google.protobuf.Value", p.Metadata[payload.MetadataSchemaID])
+
+			// Payloads are stored as JSON in the history
+			data, err := json.Marshal(p)
+			require.NoError(t, err)
+			var stored payload.Payload
+			require.NoError(t, json.Unmarshal(data, &stored))
+
+			var r *structpb.Value
+			require.NoError(t, AssignValue(tt.c, stored, &r))
+			require.True(t, proto.Equal(v, r))
+			require.IsType(t, &structpb.Value_BoolValue{}, r.Kind)
+		})
+	}
+}
+
+func Test_ProtoConverter_EmptyMessage(t *testing.T) {
+	c := NewProtoConverter()
+
+	p, err := c.To(&wrapperspb.StringValue{})
+	require.NoError(t, err)
+	require.False(t, p.IsEmpty())
+
+	var r *wrapperspb.StringValue
+	require.NoError(t, AssignValue(c, p, &r))
+	require.NotNil(t, r)
+	require.Equal(t, "", r.Value)
+}
+
+func Test_ProtoConverter_NilMessage(t *testing.T) {
+	c := NewProtoConverter()
+
+	p, err := c.To((*wrapperspb.StringValue)(nil))
+	require.NoError(t, err)
+	require.Equal(t, EncodingJSON, p.Encoding())
+
+	r := wrapperspb.String("x")
+	require.NoError(t, AssignValue(c, p, &r))
+	require.Nil(t, r)
+}
+
+func Test_ProtoConverter_JSONFallback(t *testing.T) {
+	c := NewProtoConverter()
+
+	p, err := c.To(42)
+	require.NoError(t, err)
+	require.Equal(t, EncodingJSON, p.Encoding())
+
+	var r int
+	require.NoError(t, AssignValue(c, p, &r))
+	require.Equal(t, 42, r)
+
+	// Legacy payloads without metadata are decoded as JSON
+	require.NoError(t, c.From(payload.New([]byte("23"), nil), &r))
+	require.Equal(t, 23, r)
+}
+
+func Test_ProtoConverter_Errors(t *testing.T) {
+	c := NewProtoConverter()
+
+	p, err := c.To(wrapperspb.String("x"))
+	require.NoError(t, err)
+
+	var i int
+	require.Error(t, c.From(p, &i))
+
+	var other *wrapperspb.Int64Value
+	require.Error(t, c.From(p, &other))
+}
+
+func Test_ProtoConverter_Composite(t *testing.T) {
+	// Switching from JSON to protobuf keeps payloads recorded as JSON readable
+	c := NewCompositeConverter(NewProtoConverter(), JSONConverter)
+
+	jp, err := JSONConverter.To("hello")
+	require.NoError(t, err)
+
+	var s string
+	require.NoError(t, c.From(jp, &s))
+	require.Equal(t, "hello", s)
+
+	p, err := c.To(wrapperspb.String("hello"))
+	require.NoError(t, err)
+
+	var r *wrapperspb.StringValue
+	require.NoError(t, c.From(p, &r))
+	require.Equal(t, "hello", r.Value)
+}
@@ -0,0 +1,100 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// jsonEvent is the JSON representation of an event. Attributes are serialized based on the
+// event type, so they can be restored when reading events.
+type jsonEvent struct {
+	ID              string          `json:"id"`
+	Type            jsonEventType   `json:"type"`
+	Timestamp       time.Time       `json:"timestamp"`
+	ScheduleEventID int             `json:"schedule_event_id"`
+	Attributes      json.RawMessage `json:"attributes"`
+	VisibleAt       *time.Time      `json:"visible_at,omitempty"`
+}
+
+func (e Event) MarshalJSON() ([]byte, error) {
+	a, err := SerializeAttributes(e.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&jsonEvent{
+		ID:              e.ID,
+		Type:            jsonEventType(e.Type),
+		Timestamp:       e.Timestamp,
+		ScheduleEventID: e.ScheduleEventID,
+		Attributes:      a,
+		VisibleAt:       e.VisibleAt,
+	})
+}
+
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var je jsonEvent
+	if err := json.Unmarshal(data, &je); err != nil {
+		return err
+	}
+
+	a, err := DeserializeAttributes(EventType(je.Type), je.Attributes)
+	if err != nil {
+		return err
+	}
+
+	*e = Event{
+		ID:              je.ID,
+		Type:            EventType(je.Type),
+		Timestamp:       je.Timestamp,
+		ScheduleEventID: je.ScheduleEventID,
+		Attributes:      a,
+		VisibleAt:       je.VisibleAt,
+	}
+
+	return nil
+}
+
+// jsonEventType is written as the name of the event type. Numeric event types are accepted when
+// reading, for events written before names were used.
+type jsonEventType EventType
+
+func (t jsonEventType) MarshalJSON() ([]byte, error) {
+	et := EventType(t)
+	if et.String() == "Unknown" {
+		return nil, fmt.Errorf("unknown event type %d", et)
+	}
+
+	return json.Marshal(et.String())
+}
+
+func (t *jsonEventType) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		var et EventType
+		if err := json.Unmarshal(data, &et); err != nil {
+			return fmt.Errorf("invalid event type %s", data)
+		}
+
+		*t = jsonEventType(et)
+		return nil
+	}
+
+	et, ok := eventTypesByName[name]
+	if !ok {
+		return fmt.Errorf("unknown event type %q", name)
+	}
+
+	*t = jsonEventType(et)
+	return nil
+}
+
+var eventTypesByName = func() map[string]EventType {
+	m := make(map[string]EventType)
+	for et := EventType_WorkflowExecutionStarted; et.String() != "Unknown"; et++ {
+		m[et.String()] = et
+	}
+
+	return m
+}()
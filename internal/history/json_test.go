@@ -0,0 +1,46 @@
+package history
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Event_JSON(t *testing.T) {
+	e := NewHistoryEvent(time.Now().UTC(), EventType_ActivityScheduled, &ActivityScheduledAttributes{Name: "a"}, ScheduleEventID(2))
+
+	data, err := json.Marshal(e)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"type":"ActivityScheduled"`)
+
+	var e2 Event
+	require.NoError(t, json.Unmarshal(data, &e2))
+	require.Equal(t, e.ID, e2.ID)
+	require.Equal(t, EventType_ActivityScheduled, e2.Type)
+	require.Equal(t, 2, e2.ScheduleEventID)
+	require.Equal(t, "a", e2.Attributes.(*ActivityScheduledAttributes).Name)
+}
+
+func Test_Event_JSON_NumericType(t *testing.T) {
+	var e Event
+	require.NoError(t, json.Unmarshal([]byte(`{"id":"1","type":14,"attributes":{"At":"2022-01-01T00:00:00Z"}}`), &e))
+	require.Equal(t, EventType_TimerFired, e.Type)
+	require.IsType(t, &TimerFiredAttributes{}, e.Attributes)
+}
+
+func Test_Event_JSON_UnknownType(t *testing.T) {
+	var e Event
+	require.Error(t, json.Unmarshal([]byte(`{"id":"1","type":"Unknown","attributes":{}}`), &e))
+}
+
+func Test_Event_JSON_LegacyPayload(t *testing.T) {
+	// Payloads used to be serialized as base64 encoded byte slices without metadata
+	var e Event
+	require.NoError(t, json.Unmarshal([]byte(`{"id":"1","type":"ActivityCompleted","attributes":{"Result":"NDI="}}`), &e))
+
+	r := e.Attributes.(*ActivityCompletedAttributes).Result
+	require.Equal(t, []byte("42"), r.Data)
+	require.Empty(t, r.Encoding())
+}
@@ -0,0 +1,65 @@
+package history
+
+import "github.com/cschleiden/go-workflows/internal/payload"
+
+// Payloads returns all payloads contained in the given event's attributes
+func Payloads(e Event) []payload.Payload {
+	ptrs := payloadPointers(e)
+	if ptrs == nil {
+		return nil
+	}
+
+	payloads := make([]payload.Payload, 0, len(ptrs))
+	for _, p := range ptrs {
+		payloads = append(payloads, *p)
+	}
+
+	return payloads
+}
+
+// ReplacePayloads replaces all payloads contained in the given event's attributes with the result
+// of f. The attributes are modified in place.
+func ReplacePayloads(e Event, f func(p payload.Payload) (payload.Payload, error)) error {
+	for _, p := range payloadPointers(e) {
+		r, err := f(*p)
+		if err != nil {
+			return err
+		}
+
+		*p = r
+	}
+
+	return nil
+}
+
+func payloadPointers(e Event) []*payload.Payload {
+	switch a := e.Attributes.(type) {
+	case *ExecutionStartedAttributes:
+		return slicePointers(a.Inputs)
+	case *ExecutionCompletedAttributes:
+		return []*payload.Payload{&a.Result}
+	case *ActivityScheduledAttributes:
+		return slicePointers(a.Inputs)
+	case *ActivityCompletedAttributes:
+		return []*payload.Payload{&a.Result}
+	case *SignalReceivedAttributes:
+		return []*payload.Payload{&a.Arg}
+	case *SideEffectResultAttributes:
+		return []*payload.Payload{&a.Result}
+	case *SubWorkflowScheduledAttributes:
+		return slicePointers(a.Inputs)
+	case *SubWorkflowCompletedAttributes:
+		return []*payload.Payload{&a.Result}
+	}
+
+	return nil
+}
+
+func slicePointers(payloads []payload.Payload) []*payload.Payload {
+	ptrs := make([]*payload.Payload, 0, len(payloads))
+	for i := range payloads {
+		ptrs = append(ptrs, &payloads[i])
+	}
+
+	return ptrs
+}
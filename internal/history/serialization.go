@@ -3,10 +3,86 @@ package history
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/compression"
 )
 
-func SerializeAttributes(attributes interface{}) ([]byte, error) {
-	return json.Marshal(attributes)
+// Compressed attributes start with a zero byte, which serialized JSON never does, followed by the
+// ID of the compression algorithm. Attributes without the marker are plain JSON.
+const compressedMarker = 0x00
+
+var compressionIDs = map[compression.Algorithm]byte{
+	compression.Gzip: 1,
+	compression.Zstd: 2,
+}
+
+type serializeOptions struct {
+	algorithm compression.Algorithm
+	threshold int
+}
+
+type SerializeOption func(*serializeOptions)
+
+// WithCompression compresses serialized attributes with the given algorithm, if they are at
+// least threshold bytes long
+func WithCompression(algorithm compression.Algorithm, threshold int) SerializeOption {
+	return func(o *serializeOptions) {
+		o.algorithm = algorithm
+		o.threshold = threshold
+	}
+}
+
+func SerializeAttributes(attributes interface{}, opts ...SerializeOption) ([]byte, error) {
+	options := &serializeOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	data, err := json.Marshal(attributes)
+	if err != nil || options.algorithm == compression.None || len(data) < options.threshold {
+		return data, err
+	}
+
+	id, ok := compressionIDs[options.algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unknown compression algorithm %q", options.algorithm)
+	}
+
+	compressed, err := compression.Compress(options.algorithm, data)
+	if err != nil {
+		return nil, fmt.Errorf("compressing attributes: %w", err)
+	}
+
+	if len(compressed)+2 >= len(data) {
+		// Not worth it
+		return data, nil
+	}
+
+	return append([]byte{compressedMarker, id}, compressed...), nil
+}
+
+func decompressAttributes(attributes []byte) ([]byte, error) {
+	if len(attributes) == 0 || attributes[0] != compressedMarker {
+		return attributes, nil
+	}
+
+	if len(attributes) < 2 {
+		return nil, errors.New("invalid compressed attributes")
+	}
+
+	for algorithm, id := range compressionIDs {
+		if id == attributes[1] {
+			data, err := compression.Decompress(algorithm, attributes[2:])
+			if err != nil {
+				return nil, fmt.Errorf("decompressing attributes: %w", err)
+			}
+
+			return data, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown compression of attributes: %v", attributes[1])
 }
 
 func DeserializeAttributes(eventType EventType, attributes []byte) (attr interface{}, err error) {
@@ -52,6 +128,11 @@ func DeserializeAttributes(eventType EventType, attributes []byte) (attr interfa
 		return nil, errors.New("unknown event type when deserializing attributes")
 	}
 
-	err = json.Unmarshal([]byte(attributes), &attr)
+	attributes, err = decompressAttributes(attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(attributes, &attr)
 	return attr, err
 }
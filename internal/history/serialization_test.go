@@ -0,0 +1,53 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/cschleiden/go-workflows/compression"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SerializeAttributes_Compression(t *testing.T) {
+	attributes := &ActivityCompletedAttributes{
+		Result: payload.New([]byte(`"`+strings.Repeat("a", 1024)+`"`), nil),
+	}
+
+	plain, err := SerializeAttributes(attributes)
+	require.NoError(t, err)
+
+	for _, a := range []compression.Algorithm{compression.Gzip, compression.Zstd} {
+		t.Run(string(a), func(t *testing.T) {
+			data, err := SerializeAttributes(attributes, WithCompression(a, 512))
+			require.NoError(t, err)
+			require.Less(t, len(data), len(plain))
+
+			r, err := DeserializeAttributes(EventType_ActivityCompleted, data)
+			require.NoError(t, err)
+			require.Equal(t, attributes, r)
+		})
+	}
+}
+
+func Test_SerializeAttributes_BelowThreshold(t *testing.T) {
+	attributes := &ActivityScheduledAttributes{Name: "a"}
+
+	data, err := SerializeAttributes(attributes, WithCompression(compression.Gzip, 1024))
+	require.NoError(t, err)
+	require.True(t, json.Valid(data))
+}
+
+func Test_DeserializeAttributes_Legacy(t *testing.T) {
+	// Attributes stored before compression was introduced are plain JSON
+	r, err := DeserializeAttributes(EventType_ActivityScheduled, []byte(`{"Name":"a"}`))
+	require.NoError(t, err)
+	require.Equal(t, "a", r.(*ActivityScheduledAttributes).Name)
+}
+
+func Test_DeserializeAttributes_UnknownCompression(t *testing.T) {
+	_, err := DeserializeAttributes(EventType_ActivityScheduled, append([]byte{0x00, 0xff}, bytes.Repeat([]byte("x"), 10)...))
+	require.Error(t, err)
+}
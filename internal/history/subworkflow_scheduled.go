@@ -8,4 +8,7 @@ type SubWorkflowScheduledAttributes struct {
 	Name string
 
 	Inputs []payload.Payload
+
+	// Metadata carries context like the trace context across workflow and activity executions
+	Metadata map[string]string
 }
@@ -4,4 +4,8 @@ import "time"
 
 type TimerScheduledAttributes struct {
 	At time.Time
+
+	// Delay is the duration the timer was scheduled with. It's zero for events recorded before
+	// the delay was stored.
+	Delay time.Duration `json:",omitempty"`
 }
@@ -6,4 +6,10 @@ type ExecutionStartedAttributes struct {
 	Name string
 
 	Inputs []payload.Payload
+
+	// Metadata carries context like the trace context across workflow and activity executions
+	Metadata map[string]string
+
+	// Priority of the task, higher values are picked up first
+	Priority int
 }
@@ -0,0 +1,103 @@
+package log
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+type defaultLogger struct {
+	l      *log.Logger
+	debug  bool
+	fields []interface{}
+}
+
+var _ Logger = (*defaultLogger)(nil)
+
+// NewDefaultLogger returns a logger writing to the standard library's default logger. Debug
+// messages are discarded.
+func NewDefaultLogger() Logger {
+	return &defaultLogger{
+		l: log.Default(),
+	}
+}
+
+// NewDebugLogger returns a logger writing all messages, including debug messages, to l
+func NewDebugLogger(l *log.Logger) Logger {
+	return &defaultLogger{
+		l:     l,
+		debug: true,
+	}
+}
+
+func (dl *defaultLogger) Debug(msg string, fields ...interface{}) {
+	if dl.debug {
+		dl.write("DEBUG", msg, fields)
+	}
+}
+
+func (dl *defaultLogger) Info(msg string, fields ...interface{}) {
+	dl.write("INFO", msg, fields)
+}
+
+func (dl *defaultLogger) Warning(msg string, fields ...interface{}) {
+	dl.write("WARN", msg, fields)
+}
+
+func (dl *defaultLogger) Error(msg string, fields ...interface{}) {
+	dl.write("ERROR", msg, fields)
+}
+
+func (dl *defaultLogger) With(fields ...interface{}) Logger {
+	f := make([]interface{}, 0, len(dl.fields)+len(fields))
+	f = append(f, dl.fields...)
+	f = append(f, fields...)
+
+	return &defaultLogger{
+		l:      dl.l,
+		debug:  dl.debug,
+		fields: f,
+	}
+}
+
+func (dl *defaultLogger) write(level, msg string, fields []interface{}) {
+	var sb strings.Builder
+	sb.WriteString(level)
+	sb.WriteString(" ")
+	sb.WriteString(msg)
+
+	writeFields(&sb, dl.fields)
+	writeFields(&sb, fields)
+
+	dl.l.Println(sb.String())
+}
+
+func writeFields(sb *strings.Builder, fields []interface{}) {
+	for i := 0; i < len(fields); i += 2 {
+		if i+1 < len(fields) {
+			fmt.Fprintf(sb, " %v=%v", fields[i], fields[i+1])
+		} else {
+			// Ignore missing value for the last key
+			fmt.Fprintf(sb, " %v=", fields[i])
+		}
+	}
+}
+
+type noopLogger struct{}
+
+// NewNoopLogger returns a logger that discards all messages
+func NewNoopLogger() Logger {
+	return &noopLogger{}
+}
+
+func (*noopLogger) Debug(msg string, fields ...interface{}) {}
+
+func (*noopLogger) Info(msg string, fields ...interface{}) {}
+
+func (*noopLogger) Warning(msg string, fields ...interface{}) {}
+
+func (*noopLogger) Error(msg string, fields ...interface{}) {}
+
+func (nl *noopLogger) With(fields ...interface{}) Logger {
+	return nl
+}
@@ -0,0 +1,29 @@
+package log
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DefaultLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewDebugLogger(log.New(buf, "", 0))
+
+	l.With(InstanceIDKey, "instanceID").Info("message", ActivityNameKey, "activity1")
+	l.Debug("debug")
+
+	require.Equal(t, "INFO message instance_id=instanceID activity_name=activity1\nDEBUG debug\n", buf.String())
+}
+
+func Test_DefaultLogger_DiscardsDebug(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := &defaultLogger{l: log.New(buf, "", 0)}
+
+	l.Debug("debug")
+	l.Warning("warning")
+
+	require.Equal(t, "WARN warning\n", buf.String())
+}
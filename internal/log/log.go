@@ -1,8 +1,28 @@
 package log
 
+// Logger writes structured log messages. fields are alternating key/value pairs that are added
+// to the message.
 type Logger interface {
-	Debug(args ...interface{})
-	Info(args ...interface{})
-	Warning(args ...interface{})
-	Error(args ...interface{})
+	Debug(msg string, fields ...interface{})
+	Info(msg string, fields ...interface{})
+	Warning(msg string, fields ...interface{})
+	Error(msg string, fields ...interface{})
+
+	// With returns a logger that adds the given fields to every message
+	With(fields ...interface{}) Logger
 }
+
+// Keys of the fields added to log messages
+const (
+	InstanceIDKey      = "instance_id"
+	ExecutionIDKey     = "execution_id"
+	WorkflowNameKey    = "workflow_name"
+	EventIDKey         = "event_id"
+	EventTypeKey       = "event_type"
+	ScheduleEventIDKey = "schedule_event_id"
+	ActivityNameKey    = "activity_name"
+	ActivityIDKey      = "activity_id"
+	SignalNameKey      = "signal_name"
+	ScheduleIDKey      = "schedule_id"
+	ErrorKey           = "error"
+)
@@ -0,0 +1,49 @@
+package metrickeys
+
+// Metric names recorded by workers and backends. Durations are recorded in seconds.
+const (
+	Prefix = "workflows."
+
+	// Worker
+	PollDuration    = Prefix + "worker.poll_duration_seconds"
+	TaskDuration    = Prefix + "worker.task_duration_seconds"
+	TasksProcessed  = Prefix + "worker.tasks_total"
+	TasksInProgress = Prefix + "worker.tasks_in_progress"
+
+	// Activities
+	ActivityExecutions = Prefix + "activity.executions_total"
+	ActivityDuration   = Prefix + "activity.duration_seconds"
+
+	// Workflow executor cache
+	CacheSize      = Prefix + "cache.size"
+	CacheHits      = Prefix + "cache.hits_total"
+	CacheMisses    = Prefix + "cache.misses_total"
+	CacheEvictions = Prefix + "cache.evictions_total"
+
+	// Backend
+	LockExtensions    = Prefix + "backend.lock_extensions_total"
+	PendingEvents     = Prefix + "backend.pending_events"
+	PendingActivities = Prefix + "backend.pending_activities"
+)
+
+// Tags
+const (
+	// Type is the kind of task, either "workflow" or "activity"
+	Type = "type"
+
+	// Result is the outcome of an operation, e.g., "success", "error", or "empty"
+	Result = "result"
+
+	// ActivityName is the name of an executed activity
+	ActivityName = "activity"
+)
+
+// Tag values
+const (
+	TypeWorkflow = "workflow"
+	TypeActivity = "activity"
+
+	ResultSuccess = "success"
+	ResultError   = "error"
+	ResultEmpty   = "empty"
+)
@@ -0,0 +1,157 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Migration is a numbered change to the database schema
+type Migration struct {
+	Version int
+	Name    string
+
+	// Statements are executed in order to apply the migration
+	Statements []string
+}
+
+// Dialect contains the database specific parts of applying migrations
+type Dialect interface {
+	// Lock prevents concurrent migrations until Unlock is called on the same connection.
+	Lock(ctx context.Context, conn *sql.Conn) error
+
+	// Unlock releases the lock. If failed is true, changes made while holding the lock should be
+	// rolled back where the database supports it.
+	Unlock(ctx context.Context, conn *sql.Conn, failed bool) error
+
+	// CreateVersionTable is the statement creating the schema_version table, if it doesn't exist
+	CreateVersionTable() string
+
+	// InsertVersion is the statement recording an applied migration, with the version as its
+	// only parameter
+	InsertVersion() string
+}
+
+// Load reads the migrations in dir. Files are expected to be named <version>_<name>.sql, with
+// statements separated by semicolons at the end of a line.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read migrations")
+	}
+
+	migrations := make([]Migration, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), ".sql")
+		parts := strings.SplitN(name, "_", 2)
+		version, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid migration version in %v", entry.Name())
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read migration %v", entry.Name())
+		}
+
+		migrations = append(migrations, Migration{
+			Version:    version,
+			Name:       name,
+			Statements: statements(string(data)),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("expected migration version %d, found %v", i+1, m.Name)
+		}
+	}
+
+	return migrations, nil
+}
+
+// statements splits a migration into its statements
+func statements(migration string) []string {
+	var result []string
+	var current strings.Builder
+
+	for _, line := range strings.Split(migration, "\n") {
+		current.WriteString(line)
+		current.WriteString("\n")
+
+		if strings.HasSuffix(strings.TrimSpace(line), ";") {
+			if s := strings.TrimSpace(current.String()); s != ";" {
+				result = append(result, s)
+			}
+			current.Reset()
+		}
+	}
+
+	if s := strings.TrimSpace(current.String()); s != "" {
+		result = append(result, s)
+	}
+
+	return result
+}
+
+// Migrate applies all migrations that haven't been applied to db yet. The schema_version table
+// records applied migrations, the dialect's lock ensures that only one process migrates at a time.
+func Migrate(ctx context.Context, db *sql.DB, dialect Dialect, migrations []Migration) (err error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "could not get connection")
+	}
+	defer conn.Close()
+
+	if err := dialect.Lock(ctx, conn); err != nil {
+		return errors.Wrap(err, "could not acquire migration lock")
+	}
+
+	defer func() {
+		if uerr := dialect.Unlock(ctx, conn, err != nil); uerr != nil && err == nil {
+			err = errors.Wrap(uerr, "could not release migration lock")
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, dialect.CreateVersionTable()); err != nil {
+		return errors.Wrap(err, "could not create schema_version table")
+	}
+
+	var current int
+	if err := conn.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&current); err != nil {
+		return errors.Wrap(err, "could not get schema version")
+	}
+
+	if current > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than the latest known migration %d", current, len(migrations))
+	}
+
+	for _, m := range migrations[current:] {
+		for _, s := range m.Statements {
+			if _, err := conn.ExecContext(ctx, s); err != nil {
+				return errors.Wrapf(err, "could not apply migration %v", m.Name)
+			}
+		}
+
+		if _, err := conn.ExecContext(ctx, dialect.InsertVersion(), m.Version); err != nil {
+			return errors.Wrapf(err, "could not record migration %v", m.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Load(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/000002_second.sql":  {Data: []byte("CREATE TABLE b (id INT);\nCREATE INDEX idx_b ON b (id);\n")},
+		"migrations/000001_initial.sql": {Data: []byte("CREATE TABLE a (\n  id INT -- comment\n);\n")},
+		"migrations/README.md":          {Data: []byte("ignored")},
+	}
+
+	m, err := Load(fsys, "migrations")
+	require.NoError(t, err)
+	require.Len(t, m, 2)
+
+	require.Equal(t, 1, m[0].Version)
+	require.Equal(t, "000001_initial", m[0].Name)
+	require.Equal(t, []string{"CREATE TABLE a (\n  id INT -- comment\n);"}, m[0].Statements)
+
+	require.Equal(t, 2, m[1].Version)
+	require.Equal(t, []string{"CREATE TABLE b (id INT);", "CREATE INDEX idx_b ON b (id);"}, m[1].Statements)
+}
+
+func Test_Load_MissingVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/000001_initial.sql": {Data: []byte("CREATE TABLE a (id INT);")},
+		"migrations/000003_third.sql":   {Data: []byte("CREATE TABLE c (id INT);")},
+	}
+
+	_, err := Load(fsys, "migrations")
+	require.Error(t, err)
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Lock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE")
+	return err
+}
+
+func (sqliteDialect) Unlock(ctx context.Context, conn *sql.Conn, failed bool) error {
+	if failed {
+		_, err := conn.ExecContext(ctx, "ROLLBACK")
+		return err
+	}
+
+	_, err := conn.ExecContext(ctx, "COMMIT")
+	return err
+}
+
+func (sqliteDialect) CreateVersionTable() string {
+	return "CREATE TABLE IF NOT EXISTS schema_version (version INTEGER PRIMARY KEY)"
+}
+
+func (sqliteDialect) InsertVersion() string {
+	return "INSERT INTO schema_version (version) VALUES (?)"
+}
+
+func Test_Migrate(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	migrations := []Migration{
+		{Version: 1, Name: "000001_initial", Statements: []string{"CREATE TABLE a (id INT)"}},
+	}
+
+	require.NoError(t, Migrate(ctx, db, sqliteDialect{}, migrations))
+
+	// Applying the same migrations again is a no-op
+	require.NoError(t, Migrate(ctx, db, sqliteDialect{}, migrations))
+
+	// Only new migrations are applied
+	migrations = append(migrations, Migration{Version: 2, Name: "000002_second", Statements: []string{"ALTER TABLE a ADD COLUMN b INT"}})
+	require.NoError(t, Migrate(ctx, db, sqliteDialect{}, migrations))
+
+	var version int
+	require.NoError(t, db.QueryRow("SELECT MAX(version) FROM schema_version").Scan(&version))
+	require.Equal(t, 2, version)
+
+	_, err = db.Exec("INSERT INTO a (id, b) VALUES (1, 2)")
+	require.NoError(t, err)
+}
+
+func Test_Migrate_RollsBackFailedMigration(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	err = Migrate(ctx, db, sqliteDialect{}, []Migration{
+		{Version: 1, Name: "000001_initial", Statements: []string{"CREATE TABLE a (id INT)", "NOT VALID SQL"}},
+	})
+	require.Error(t, err)
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'a'").Scan(&count))
+	require.Equal(t, 0, count)
+}
+
+func Test_Migrate_NewerSchema(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	require.NoError(t, Migrate(ctx, db, sqliteDialect{}, []Migration{
+		{Version: 1, Name: "000001_initial", Statements: []string{"CREATE TABLE a (id INT)"}},
+		{Version: 2, Name: "000002_second", Statements: []string{"CREATE TABLE b (id INT)"}},
+	}))
+
+	err = Migrate(ctx, db, sqliteDialect{}, []Migration{
+		{Version: 1, Name: "000001_initial", Statements: []string{"CREATE TABLE a (id INT)"}},
+	})
+	require.Error(t, err)
+}
@@ -1,3 +1,91 @@
 package payload
 
-type Payload []byte
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Keys of well-known payload metadata entries
+const (
+	// MetadataEncoding is the name of the encoding used to produce the payload's data, e.g. "json/plain"
+	MetadataEncoding = "encoding"
+
+	// MetadataContentType is the MIME type of the payload's data
+	MetadataContentType = "content-type"
+
+	// MetadataSchemaID optionally identifies the schema the payload's data conforms to
+	MetadataSchemaID = "schema-id"
+)
+
+// Payload is a serialized value together with metadata describing how it was serialized.
+type Payload struct {
+	Metadata map[string]string
+	Data     []byte
+}
+
+// New returns a payload with the given data and metadata
+func New(data []byte, metadata map[string]string) Payload {
+	return Payload{
+		Metadata: metadata,
+		Data:     data,
+	}
+}
+
+// IsEmpty returns true if the payload holds no value, e.g. the result of a function that only returns an error
+func (p Payload) IsEmpty() bool {
+	return p.Data == nil && len(p.Metadata) == 0
+}
+
+// Encoding returns the name of the encoding of the payload. Payloads recorded before metadata was
+// introduced do not carry an encoding and return an empty string.
+func (p Payload) Encoding() string {
+	return p.Metadata[MetadataEncoding]
+}
+
+type jsonPayload struct {
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Data     []byte            `json:"data"`
+}
+
+func (p Payload) MarshalJSON() ([]byte, error) {
+	if p.IsEmpty() {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(&jsonPayload{
+		Metadata: p.Metadata,
+		Data:     p.Data,
+	})
+}
+
+func (p *Payload) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		*p = Payload{}
+		return nil
+
+	case len(data) > 0 && data[0] == '"':
+		// Payloads used to be serialized as plain byte slices, keep reading them as data without metadata
+		var d []byte
+		if err := json.Unmarshal(data, &d); err != nil {
+			return err
+		}
+
+		*p = Payload{Data: d}
+		return nil
+	}
+
+	var jp jsonPayload
+	if err := json.Unmarshal(data, &jp); err != nil {
+		return err
+	}
+
+	*p = Payload{
+		Metadata: jp.Metadata,
+		Data:     jp.Data,
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package payload
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Payload_JSON(t *testing.T) {
+	p := New([]byte(`{"a":1}`), map[string]string{
+		MetadataEncoding:    "json/plain",
+		MetadataContentType: "application/json",
+		MetadataSchemaID:    "a.v1",
+	})
+
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+
+	var p2 Payload
+	require.NoError(t, json.Unmarshal(data, &p2))
+	require.Equal(t, p, p2)
+	require.Equal(t, "json/plain", p2.Encoding())
+}
+
+func Test_Payload_JSON_Empty(t *testing.T) {
+	data, err := json.Marshal(Payload{})
+	require.NoError(t, err)
+	require.Equal(t, "null", string(data))
+
+	p := New([]byte("x"), nil)
+	require.NoError(t, json.Unmarshal(data, &p))
+	require.True(t, p.IsEmpty())
+}
+
+func Test_Payload_JSON_Legacy(t *testing.T) {
+	legacy, err := json.Marshal([]byte("42"))
+	require.NoError(t, err)
+
+	var p Payload
+	require.NoError(t, json.Unmarshal(legacy, &p))
+	require.Equal(t, []byte("42"), p.Data)
+	require.Nil(t, p.Metadata)
+	require.Empty(t, p.Encoding())
+}
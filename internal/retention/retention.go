@@ -0,0 +1,77 @@
+package retention
+
+import (
+	"context"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/internal/log"
+)
+
+const (
+	// Interval is the time between two runs of the cleanup job
+	Interval = time.Minute
+
+	// BatchSize is the maximum number of instances removed in a single transaction
+	BatchSize = 100
+)
+
+// PurgeFunc removes up to limit workflow instances completed before cutoff and returns the
+// number of removed instances
+type PurgeFunc func(ctx context.Context, cutoff time.Time, limit int) (int, error)
+
+// Start removes instances completed longer than period ago every interval, until the returned
+// function is called. It doesn't start anything if period is zero.
+func Start(clock clock.Clock, period, interval time.Duration, logger log.Logger, purge PurgeFunc) (stop func()) {
+	if period <= 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	t := clock.Ticker(interval)
+
+	go func() {
+		defer close(done)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				removed, err := Purge(ctx, clock.Now().Add(-period), purge)
+				if err != nil && ctx.Err() == nil {
+					logger.Error("Could not remove expired workflow instances", log.ErrorKey, err)
+				}
+
+				if removed > 0 {
+					logger.Debug("Removed expired workflow instances", "removed", removed)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// Purge removes all instances completed before cutoff, in batches of BatchSize
+func Purge(ctx context.Context, cutoff time.Time, purge PurgeFunc) (int, error) {
+	total := 0
+
+	for {
+		removed, err := purge(ctx, cutoff, BatchSize)
+		total += removed
+		if err != nil {
+			return total, err
+		}
+
+		if removed < BatchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Purge_RemovesInBatches(t *testing.T) {
+	remaining := 2*BatchSize + 10
+	calls := 0
+
+	removed, err := Purge(context.Background(), time.Now(), func(ctx context.Context, cutoff time.Time, limit int) (int, error) {
+		calls++
+
+		n := limit
+		if remaining < n {
+			n = remaining
+		}
+		remaining -= n
+
+		return n, nil
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 2*BatchSize+10, removed)
+	require.Equal(t, 3, calls)
+}
+
+func Test_Purge_StopsOnError(t *testing.T) {
+	removed, err := Purge(context.Background(), time.Now(), func(ctx context.Context, cutoff time.Time, limit int) (int, error) {
+		return 0, errors.New("failed")
+	})
+
+	require.Error(t, err)
+	require.Equal(t, 0, removed)
+}
+
+func Test_Start(t *testing.T) {
+	c := clock.NewMock()
+	cutoffs := make(chan time.Time, 1)
+
+	stop := Start(c, time.Hour, time.Minute, log.NewNoopLogger(), func(ctx context.Context, cutoff time.Time, limit int) (int, error) {
+		cutoffs <- cutoff
+		return 0, nil
+	})
+	defer stop()
+
+	c.Add(time.Minute)
+
+	select {
+	case cutoff := <-cutoffs:
+		require.Equal(t, c.Now().Add(-time.Hour), cutoff)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected purge")
+	}
+}
+
+// tickerClock counts the tickers created
+type tickerClock struct {
+	*clock.Mock
+
+	tickers int
+}
+
+func (c *tickerClock) Ticker(d time.Duration) *clock.Ticker {
+	c.tickers++
+	return c.Mock.Ticker(d)
+}
+
+func Test_Start_DisabledWithoutPeriod(t *testing.T) {
+	c := &tickerClock{Mock: clock.NewMock()}
+
+	stop := Start(c, 0, time.Minute, log.NewNoopLogger(), func(ctx context.Context, cutoff time.Time, limit int) (int, error) {
+		t.Fatal("unexpected purge")
+		return 0, nil
+	})
+	stop()
+
+	require.Equal(t, 0, c.tickers)
+}
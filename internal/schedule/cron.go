@@ -0,0 +1,221 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cron is a parsed cron expression in the standard five field format:
+//
+//	minute hour day-of-month month day-of-week
+//
+// Fields support lists, ranges, steps, and names for months and days of the week. The
+// descriptors @yearly, @annually, @monthly, @weekly, @daily, @midnight, and @hourly are
+// supported, too.
+type cron struct {
+	minute, hour, dom, month, dow uint64
+
+	// Whether the day of month or day of week fields were unrestricted, which determines how they
+	// are combined
+	domAny, dowAny bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = cronField{"minute", 0, 59, nil}
+	hourField   = cronField{"hour", 0, 23, nil}
+	domField    = cronField{"day of month", 1, 31, nil}
+	monthField  = cronField{"month", 1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = cronField{"day of week", 0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+func parseCron(expr string) (*cron, error) {
+	expr = strings.TrimSpace(expr)
+	if d, ok := cronDescriptors[strings.ToLower(expr)]; ok {
+		expr = d
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	c := &cron{}
+
+	var err error
+	if c.minute, _, err = minuteField.parse(fields[0]); err != nil {
+		return nil, err
+	}
+
+	if c.hour, _, err = hourField.parse(fields[1]); err != nil {
+		return nil, err
+	}
+
+	if c.dom, c.domAny, err = domField.parse(fields[2]); err != nil {
+		return nil, err
+	}
+
+	if c.month, _, err = monthField.parse(fields[3]); err != nil {
+		return nil, err
+	}
+
+	if c.dow, c.dowAny, err = dowField.parse(fields[4]); err != nil {
+		return nil, err
+	}
+
+	// Sunday can be given as 0 or 7
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+
+	return c, nil
+}
+
+// parse returns the bit set of values matching the field, and whether the field is unrestricted
+func (f cronField) parse(field string) (uint64, bool, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangePart = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step < 1 {
+				return 0, false, fmt.Errorf("invalid step in %v field: %q", f.name, part)
+			}
+		}
+
+		var start, end int
+		switch {
+		case rangePart == "*":
+			start, end = f.min, f.max
+
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+
+			var err error
+			if start, err = f.value(bounds[0]); err != nil {
+				return 0, false, err
+			}
+
+			if end, err = f.value(bounds[1]); err != nil {
+				return 0, false, err
+			}
+
+			if end < start {
+				return 0, false, fmt.Errorf("invalid range in %v field: %q", f.name, part)
+			}
+
+		default:
+			var err error
+			if start, err = f.value(rangePart); err != nil {
+				return 0, false, err
+			}
+
+			end = start
+			if step > 1 {
+				// a/n is shorthand for a-max/n
+				end = f.max
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, field == "*", nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value in %v field: %q", f.name, s)
+	}
+
+	return v, nil
+}
+
+// next returns the first time matching the expression strictly after t, in t's location. Returns
+// the zero time if there is no match within the next five years, e.g. for February 30th.
+func (c *cron) next(t time.Time) time.Time {
+	loc := t.Location()
+
+	// Start at the next full minute
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	yearLimit := t.Year() + 5
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for c.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+
+	for !c.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+
+	for c.hour&(1<<uint(t.Hour())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+
+	for c.minute&(1<<uint(t.Minute())) == 0 {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+
+	return t
+}
+
+func (c *cron) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+
+	// If both fields are restricted, either of them has to match
+	if c.domAny || c.dowAny {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
@@ -0,0 +1,69 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Cron_Next(t *testing.T) {
+	start := time.Date(2022, time.October, 18, 10, 30, 15, 0, time.UTC) // Tuesday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2022, time.October, 18, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2022, time.October, 18, 10, 45, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2022, time.October, 18, 11, 0, 0, 0, time.UTC)},
+		{"30 9 * * *", time.Date(2022, time.October, 19, 9, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2022, time.October, 18, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * mon,fri", time.Date(2022, time.October, 21, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2022, time.October, 23, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2022, time.October, 31, 0, 0, 0, 0, time.UTC)},
+		// Either day of month or day of week matches if both are restricted
+		{"0 0 1 * fri", time.Date(2022, time.October, 21, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2022, time.October, 19, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2022, time.October, 18, 11, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c, err := parseCron(tt.expr)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, c.next(start))
+		})
+	}
+}
+
+func Test_Cron_Next_Location(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+
+	c, err := parseCron("0 9 * * *")
+	require.NoError(t, err)
+
+	next := c.next(time.Date(2022, time.October, 18, 8, 0, 0, 0, time.UTC).In(loc))
+	require.Equal(t, time.Date(2022, time.October, 19, 7, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func Test_Cron_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		_, err := parseCron(expr)
+		require.Error(t, err, expr)
+	}
+}
@@ -0,0 +1,444 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/google/uuid"
+)
+
+// OverlapPolicy determines what happens if a run is due while the previous run is still running
+type OverlapPolicy string
+
+const (
+	// OverlapSkip skips runs while the previous run is still running. This is the default.
+	OverlapSkip OverlapPolicy = "skip"
+
+	// OverlapBuffer starts runs after the previous run completed. Up to MaxBufferedRuns runs are
+	// buffered, further runs are skipped.
+	OverlapBuffer OverlapPolicy = "buffer"
+
+	// OverlapCancelOther cancels the previous run and starts a new one
+	OverlapCancelOther OverlapPolicy = "cancel_other"
+)
+
+// MaxBufferedRuns is the maximum number of runs buffered by OverlapBuffer
+const MaxBufferedRuns = 100
+
+// MaxBackfillRuns is the maximum number of runs requested by a single backfill
+const MaxBackfillRuns = 1000
+
+// StartRetryDelay is the time after which pending runs are started again, if the worker that
+// processed the schedule didn't record them as started
+const StartRetryDelay = 10 * time.Second
+
+// Spec describes when a schedule runs. Either Cron or Interval has to be set.
+type Spec struct {
+	// Cron is a cron expression in the standard five field format, e.g. "0 9 * * mon-fri"
+	Cron string `json:"cron,omitempty"`
+
+	// TimeZone is the name of the location cron expressions are evaluated in. Defaults to UTC.
+	TimeZone string `json:"time_zone,omitempty"`
+
+	// Interval runs the schedule periodically, starting one interval after it was created
+	Interval time.Duration `json:"interval,omitempty"`
+
+	// Jitter delays every run by a random duration up to this value, to spread out the load of
+	// schedules running at the same time
+	Jitter time.Duration `json:"jitter,omitempty"`
+
+	// OverlapPolicy determines what happens if a run is due while the previous run is still
+	// running. Defaults to OverlapSkip.
+	OverlapPolicy OverlapPolicy `json:"overlap_policy,omitempty"`
+
+	// Priority of the workflow instances started by the schedule. Default is 0.
+	Priority int `json:"priority,omitempty"`
+}
+
+// Validate returns an error if the spec is invalid
+func (s Spec) Validate() error {
+	switch {
+	case s.Cron == "" && s.Interval == 0:
+		return errors.New("either cron or interval is required")
+	case s.Cron != "" && s.Interval != 0:
+		return errors.New("only one of cron or interval can be set")
+	case s.Interval < 0:
+		return errors.New("interval must be positive")
+	case s.Jitter < 0:
+		return errors.New("jitter must not be negative")
+	}
+
+	if s.Cron != "" {
+		if _, err := parseCron(s.Cron); err != nil {
+			return err
+		}
+
+		if _, err := time.LoadLocation(s.TimeZone); err != nil {
+			return fmt.Errorf("invalid time zone: %w", err)
+		}
+	}
+
+	switch s.OverlapPolicy {
+	case "", OverlapSkip, OverlapBuffer, OverlapCancelOther:
+	default:
+		return fmt.Errorf("unknown overlap policy %q", s.OverlapPolicy)
+	}
+
+	return nil
+}
+
+// next returns the first nominal time of the spec strictly after t. Intervals are counted from anchor.
+func (s Spec) next(anchor, t time.Time) (time.Time, error) {
+	if s.Interval > 0 {
+		d := t.Sub(anchor)
+		n := d / s.Interval
+		if d < 0 && d%s.Interval != 0 {
+			// Round towards negative infinity for times before the anchor, e.g. when backfilling
+			n--
+		}
+
+		return anchor.Add((n + 1) * s.Interval), nil
+	}
+
+	c, err := parseCron(s.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	next := c.next(t.In(loc))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron expression %q never matches", s.Cron)
+	}
+
+	return next.UTC(), nil
+}
+
+// latest returns the most recent nominal time of the spec at or before t, starting from the nominal
+// time from.
+func (s Spec) latest(anchor, from, t time.Time) (time.Time, error) {
+	if s.Interval > 0 {
+		if t.Before(from) {
+			return from, nil
+		}
+
+		return from.Add(t.Sub(from) / s.Interval * s.Interval), nil
+	}
+
+	for {
+		next, err := s.next(anchor, from)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if next.After(t) {
+			return from, nil
+		}
+
+		from = next
+	}
+}
+
+// Times returns the nominal times of the spec between start and end, inclusive. Returns an error if
+// there are more than limit times.
+func (s Spec) Times(anchor, start, end time.Time, limit int) ([]time.Time, error) {
+	times := make([]time.Time, 0)
+
+	t := start.Add(-time.Nanosecond)
+	for {
+		next, err := s.next(anchor, t)
+		if err != nil {
+			return nil, err
+		}
+
+		if next.After(end) {
+			return times, nil
+		}
+
+		if len(times) == limit {
+			return nil, fmt.Errorf("more than %d runs", limit)
+		}
+
+		times = append(times, next)
+		t = next
+	}
+}
+
+// Schedule is a workflow started periodically according to a spec, together with its state
+type Schedule struct {
+	ID string `json:"id"`
+
+	Spec Spec `json:"spec"`
+
+	WorkflowName string `json:"workflow_name"`
+
+	Inputs []payload.Payload `json:"inputs"`
+
+	Paused bool `json:"paused"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// NextNominalAt is the time the next run is scheduled for, NextRunAt additionally includes the jitter
+	NextNominalAt time.Time `json:"next_nominal_at"`
+	NextRunAt     time.Time `json:"next_run_at"`
+
+	// LastRunAt is the time the most recent run was started
+	LastRunAt time.Time `json:"last_run_at"`
+
+	// Running is the most recently started run, until it completed
+	Running *Run `json:"running,omitempty"`
+
+	// BufferedRuns are the nominal times of runs delayed by OverlapBuffer
+	BufferedRuns []time.Time `json:"buffered_runs,omitempty"`
+
+	// BackfillRuns are the nominal times of runs requested by a backfill, that haven't been started yet
+	BackfillRuns []time.Time `json:"backfill_runs,omitempty"`
+
+	// PendingRuns are runs that are due, but haven't been recorded as started yet
+	PendingRuns []Run `json:"pending_runs,omitempty"`
+
+	// DueAt is the time the schedule needs to be processed next, or nil if it's paused without
+	// any pending runs
+	DueAt *time.Time `json:"due_at,omitempty"`
+
+	// Version is incremented by backends on every update, to detect concurrent modifications
+	Version int64 `json:"-"`
+}
+
+// Run identifies the workflow instance started for a schedule
+type Run struct {
+	InstanceID  string `json:"instance_id"`
+	ExecutionID string `json:"execution_id"`
+
+	// NominalAt is the time the run was scheduled for
+	NominalAt time.Time `json:"nominal_at"`
+}
+
+func (r *Run) Instance() core.WorkflowInstance {
+	return core.NewWorkflowInstance(r.InstanceID, r.ExecutionID)
+}
+
+// New creates a schedule running the given workflow
+func New(id string, spec Spec, workflowName string, inputs []payload.Payload, now time.Time) (*Schedule, error) {
+	if id == "" {
+		return nil, errors.New("schedule id is required")
+	}
+
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	if spec.OverlapPolicy == "" {
+		spec.OverlapPolicy = OverlapSkip
+	}
+
+	s := &Schedule{
+		ID:           id,
+		Spec:         spec,
+		WorkflowName: workflowName,
+		Inputs:       inputs,
+		CreatedAt:    now.UTC(),
+	}
+
+	if err := s.scheduleNext(now); err != nil {
+		return nil, err
+	}
+
+	s.updateDueAt(now)
+
+	return s, nil
+}
+
+// Pause stops starting new runs. Pending backfills are still started.
+func (s *Schedule) Pause(now time.Time) {
+	s.Paused = true
+	s.updateDueAt(now)
+}
+
+// Resume starts scheduling runs again. Runs missed while the schedule was paused are skipped.
+func (s *Schedule) Resume(now time.Time) error {
+	s.Paused = false
+
+	if err := s.scheduleNext(now); err != nil {
+		return err
+	}
+
+	s.updateDueAt(now)
+
+	return nil
+}
+
+// Backfill requests runs for the given nominal times, which are started regardless of the overlap policy
+func (s *Schedule) Backfill(times []time.Time, now time.Time) {
+	s.BackfillRuns = append(s.BackfillRuns, times...)
+	s.updateDueAt(now)
+}
+
+// Process advances the schedule to now. running is whether the most recently started run is still
+// running. Runs that are due are added to the pending runs, which are kept until they are recorded
+// as started. It returns the runs to cancel, and whether the schedule was modified and needs to be
+// stored.
+func (s *Schedule) Process(now time.Time, running bool) ([]core.WorkflowInstance, bool, error) {
+	cancel := make([]core.WorkflowInstance, 0)
+	changed := false
+
+	// A run that hasn't been started yet is still considered running
+	if s.Running != nil && !running && !s.isPending(s.Running.InstanceID) {
+		s.Running = nil
+		changed = true
+	}
+
+	// Backfills are started regardless of the overlap policy, and are not tracked as running. A
+	// nominal time might already have run, so backfilled runs use their own instance IDs.
+	for _, t := range s.BackfillRuns {
+		s.newRun(BackfillInstanceID(s.ID, t, uuid.NewString()), t)
+		changed = true
+	}
+	s.BackfillRuns = nil
+
+	// Buffered runs are started once the previous run completed
+	if s.Running == nil && len(s.BufferedRuns) > 0 {
+		s.start(s.BufferedRuns[0], now)
+		s.BufferedRuns = s.BufferedRuns[1:]
+		changed = true
+	}
+
+	if !s.Paused && !now.Before(s.NextRunAt) {
+		// Runs missed while no worker was processing the schedule are skipped, only the most recent one is started
+		nominal, err := s.Spec.latest(s.CreatedAt, s.NextNominalAt, now)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if err := s.scheduleNext(now); err != nil {
+			return nil, false, err
+		}
+		changed = true
+
+		switch {
+		case s.Running == nil:
+			s.start(nominal, now)
+
+		case s.Spec.OverlapPolicy == OverlapBuffer:
+			if len(s.BufferedRuns) < MaxBufferedRuns {
+				s.BufferedRuns = append(s.BufferedRuns, nominal)
+			}
+
+		case s.Spec.OverlapPolicy == OverlapCancelOther:
+			cancel = append(cancel, s.Running.Instance())
+			s.start(nominal, now)
+		}
+	}
+
+	// Pending runs are retried until they are recorded as started. Storing the schedule again
+	// pushes back the time it's due, so that only one worker starts them at a time.
+	if len(s.PendingRuns) > 0 {
+		changed = true
+	}
+
+	if changed {
+		s.updateDueAt(now)
+	}
+
+	return cancel, changed, nil
+}
+
+// Started records that the given pending run was started
+func (s *Schedule) Started(run Run, now time.Time) {
+	for i, r := range s.PendingRuns {
+		if r.InstanceID == run.InstanceID {
+			s.PendingRuns = append(s.PendingRuns[:i:i], s.PendingRuns[i+1:]...)
+			break
+		}
+	}
+
+	s.updateDueAt(now)
+}
+
+func (s *Schedule) isPending(instanceID string) bool {
+	for _, r := range s.PendingRuns {
+		if r.InstanceID == instanceID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RunInstanceID returns the ID of the workflow instance started for the given nominal time
+func RunInstanceID(scheduleID string, nominal time.Time) string {
+	return scheduleID + "-" + nominal.UTC().Format(time.RFC3339Nano)
+}
+
+// BackfillInstanceID returns the ID of the workflow instance started for the given nominal time by a backfill
+func BackfillInstanceID(scheduleID string, nominal time.Time, backfillID string) string {
+	return RunInstanceID(scheduleID, nominal) + "-backfill-" + backfillID
+}
+
+func (s *Schedule) newRun(instanceID string, nominal time.Time) Run {
+	r := Run{
+		InstanceID:  instanceID,
+		ExecutionID: uuid.NewString(),
+		NominalAt:   nominal,
+	}
+	s.PendingRuns = append(s.PendingRuns, r)
+	return r
+}
+
+func (s *Schedule) start(nominal, now time.Time) {
+	r := s.newRun(RunInstanceID(s.ID, nominal), nominal)
+	s.Running = &r
+	s.LastRunAt = now.UTC()
+}
+
+func (s *Schedule) scheduleNext(now time.Time) error {
+	next, err := s.Spec.next(s.CreatedAt, now)
+	if err != nil {
+		return err
+	}
+
+	s.NextNominalAt = next
+	s.NextRunAt = next
+	if s.Spec.Jitter > 0 {
+		s.NextRunAt = next.Add(time.Duration(rand.Int63n(int64(s.Spec.Jitter))))
+	}
+
+	return nil
+}
+
+func (s *Schedule) updateDueAt(now time.Time) {
+	var dueAt *time.Time
+
+	switch {
+	case len(s.PendingRuns) > 0:
+		// Pending runs are being started by the worker that stored the schedule, retry them
+		// if they weren't recorded as started after a while
+		retryAt := now.Add(StartRetryDelay)
+		dueAt = &retryAt
+
+	case len(s.BackfillRuns) > 0:
+		dueAt = &now
+
+	case len(s.BufferedRuns) > 0:
+		// Check on every poll whether the running instance completed
+		dueAt = &now
+
+	case !s.Paused:
+		dueAt = &s.NextRunAt
+	}
+
+	if dueAt != nil {
+		t := dueAt.UTC()
+		dueAt = &t
+	}
+
+	s.DueAt = dueAt
+}
@@ -0,0 +1,215 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2022, time.October, 18, 10, 30, 0, 0, time.UTC)
+
+func Test_Spec_Validate(t *testing.T) {
+	require.NoError(t, Spec{Cron: "0 * * * *"}.Validate())
+	require.NoError(t, Spec{Interval: time.Minute, OverlapPolicy: OverlapBuffer}.Validate())
+
+	require.Error(t, Spec{}.Validate())
+	require.Error(t, Spec{Cron: "0 * * * *", Interval: time.Minute}.Validate())
+	require.Error(t, Spec{Cron: "0 * *"}.Validate())
+	require.Error(t, Spec{Cron: "0 * * * *", TimeZone: "Mars/Olympus"}.Validate())
+	require.Error(t, Spec{Interval: time.Minute, OverlapPolicy: "unknown"}.Validate())
+}
+
+func Test_Spec_Times(t *testing.T) {
+	spec := Spec{Interval: 10 * time.Minute}
+
+	times, err := spec.Times(now, now, now.Add(30*time.Minute), 10)
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{
+		now,
+		now.Add(10 * time.Minute),
+		now.Add(20 * time.Minute),
+		now.Add(30 * time.Minute),
+	}, times)
+
+	// Times before the anchor are aligned to the interval, too
+	times, err = spec.Times(now, now.Add(-25*time.Minute), now.Add(-5*time.Minute), 10)
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{
+		now.Add(-20 * time.Minute),
+		now.Add(-10 * time.Minute),
+	}, times)
+
+	_, err = spec.Times(now, now, now.Add(time.Hour), 2)
+	require.Error(t, err)
+}
+
+func Test_Spec_TimeZone(t *testing.T) {
+	s, err := New("s", Spec{Cron: "0 9 * * *", TimeZone: "Europe/Berlin"}, "wf", nil, now)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2022, time.October, 19, 7, 0, 0, 0, time.UTC), s.NextRunAt)
+}
+
+func Test_Schedule_Process(t *testing.T) {
+	s, err := New("s", Spec{Interval: time.Minute}, "wf", nil, now)
+	require.NoError(t, err)
+	require.Equal(t, now.Add(time.Minute), *s.DueAt)
+
+	cancel, changed, err := s.Process(now.Add(30*time.Second), false)
+	require.NoError(t, err)
+	require.False(t, changed)
+	require.Empty(t, cancel)
+	require.Empty(t, s.PendingRuns)
+
+	cancel, changed, err = s.Process(now.Add(time.Minute), false)
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Empty(t, cancel)
+	require.Len(t, s.PendingRuns, 1)
+	run := s.PendingRuns[0]
+	require.Equal(t, RunInstanceID("s", now.Add(time.Minute)), run.InstanceID)
+	require.Equal(t, now.Add(time.Minute), run.NominalAt)
+	require.Equal(t, run, *s.Running)
+	require.Equal(t, now.Add(time.Minute+StartRetryDelay), *s.DueAt)
+
+	s.Started(run, now.Add(time.Minute))
+	require.Empty(t, s.PendingRuns)
+	require.Equal(t, now.Add(2*time.Minute), *s.DueAt)
+}
+
+func Test_Schedule_Process_RetriesPendingRuns(t *testing.T) {
+	s, err := New("s", Spec{Interval: time.Minute}, "wf", nil, now)
+	require.NoError(t, err)
+
+	_, _, err = s.Process(now.Add(time.Minute), false)
+	require.NoError(t, err)
+	require.Len(t, s.PendingRuns, 1)
+	run := s.PendingRuns[0]
+
+	// The run wasn't recorded as started, it's still considered running and is retried with the same instance
+	retryAt := now.Add(time.Minute + StartRetryDelay)
+	_, changed, err := s.Process(retryAt, false)
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Equal(t, []Run{run}, s.PendingRuns)
+	require.Equal(t, run, *s.Running)
+	require.Equal(t, retryAt.Add(StartRetryDelay), *s.DueAt)
+}
+
+func Test_Schedule_Process_SkipsMissedRuns(t *testing.T) {
+	s, err := New("s", Spec{Interval: time.Minute}, "wf", nil, now)
+	require.NoError(t, err)
+
+	_, _, err = s.Process(now.Add(10*time.Minute+time.Second), false)
+	require.NoError(t, err)
+	require.Len(t, s.PendingRuns, 1)
+	require.Equal(t, now.Add(10*time.Minute), s.PendingRuns[0].NominalAt)
+	require.Equal(t, now.Add(11*time.Minute), s.NextRunAt)
+
+	c, err := New("c", Spec{Cron: "0 * * * *"}, "wf", nil, now)
+	require.NoError(t, err)
+
+	_, _, err = c.Process(now.Add(5*time.Hour), false)
+	require.NoError(t, err)
+	require.Len(t, c.PendingRuns, 1)
+	require.Equal(t, time.Date(2022, time.October, 18, 15, 0, 0, 0, time.UTC), c.PendingRuns[0].NominalAt)
+	require.Equal(t, time.Date(2022, time.October, 18, 16, 0, 0, 0, time.UTC), c.NextRunAt)
+}
+
+// startPending records all pending runs as started
+func startPending(s *Schedule, now time.Time) []Run {
+	runs := s.PendingRuns
+	for _, r := range runs {
+		s.Started(r, now)
+	}
+
+	return runs
+}
+
+func Test_Schedule_Process_Overlap(t *testing.T) {
+	tests := []struct {
+		policy  OverlapPolicy
+		cancel  bool
+		started int
+	}{
+		{OverlapSkip, false, 0},
+		{OverlapBuffer, false, 0},
+		{OverlapCancelOther, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			s, err := New("s", Spec{Interval: time.Minute, OverlapPolicy: tt.policy}, "wf", nil, now)
+			require.NoError(t, err)
+
+			_, _, err = s.Process(now.Add(time.Minute), false)
+			require.NoError(t, err)
+			runs := startPending(s, now.Add(time.Minute))
+			require.Len(t, runs, 1)
+			first := runs[0].Instance()
+
+			cancel, changed, err := s.Process(now.Add(2*time.Minute), true)
+			require.NoError(t, err)
+			require.True(t, changed)
+			require.Len(t, s.PendingRuns, tt.started)
+
+			if tt.cancel {
+				require.Equal(t, []core.WorkflowInstance{first}, cancel)
+			} else {
+				require.Empty(t, cancel)
+			}
+
+			if tt.policy == OverlapBuffer {
+				require.Equal(t, []time.Time{now.Add(2 * time.Minute)}, s.BufferedRuns)
+
+				// Buffered run is started once the previous run completed
+				_, _, err = s.Process(now.Add(2*time.Minute+time.Second), false)
+				require.NoError(t, err)
+				require.Len(t, s.PendingRuns, 1)
+				require.Equal(t, now.Add(2*time.Minute), s.PendingRuns[0].NominalAt)
+				require.Empty(t, s.BufferedRuns)
+			}
+		})
+	}
+}
+
+func Test_Schedule_PauseResume(t *testing.T) {
+	s, err := New("s", Spec{Interval: time.Minute}, "wf", nil, now)
+	require.NoError(t, err)
+
+	s.Pause(now)
+	require.Nil(t, s.DueAt)
+
+	_, _, err = s.Process(now.Add(5*time.Minute), false)
+	require.NoError(t, err)
+	require.Empty(t, s.PendingRuns)
+
+	require.NoError(t, s.Resume(now.Add(5*time.Minute+time.Second)))
+	require.Equal(t, now.Add(6*time.Minute), *s.DueAt)
+}
+
+func Test_Schedule_Backfill(t *testing.T) {
+	s, err := New("s", Spec{Interval: time.Minute}, "wf", nil, now)
+	require.NoError(t, err)
+	s.Pause(now)
+
+	times := []time.Time{now.Add(-2 * time.Minute), now.Add(-time.Minute)}
+	s.Backfill(times, now)
+	require.Equal(t, now, *s.DueAt)
+
+	_, changed, err := s.Process(now, true)
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Len(t, s.PendingRuns, 2)
+	require.Equal(t, times[0], s.PendingRuns[0].NominalAt)
+	require.Equal(t, times[1], s.PendingRuns[1].NominalAt)
+
+	// Backfilled runs don't collide with scheduled runs for the same time
+	require.NotEqual(t, RunInstanceID("s", times[0]), s.PendingRuns[0].InstanceID)
+	require.True(t, strings.HasPrefix(s.PendingRuns[0].InstanceID, RunInstanceID("s", times[0])+"-backfill-"))
+
+	startPending(s, now)
+	require.Nil(t, s.DueAt)
+}
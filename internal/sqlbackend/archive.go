@@ -0,0 +1,87 @@
+package sqlbackend
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/archiving"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/pkg/errors"
+)
+
+// archiveInstances stores the given completed instances using the configured archiver. Histories
+// are read in a transaction, but only stored once it has completed, so that a slow archiver does
+// not block other workers.
+func (st *Store) archiveInstances(ctx context.Context, instanceIDs []string) error {
+	if st.options.Archiver == nil {
+		return nil
+	}
+
+	instances, err := st.readArchivedInstances(ctx, instanceIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, instance := range instances {
+		a, err := archiving.New(ctx, st.options.BlobStore, instance)
+		if err != nil {
+			return err
+		}
+
+		if err := st.options.Archiver.Store(ctx, a); err != nil {
+			return errors.Wrap(err, "could not archive workflow instance")
+		}
+	}
+
+	return nil
+}
+
+func (st *Store) readArchivedInstances(ctx context.Context, instanceIDs []string) ([]*archiving.Instance, error) {
+	tx, err := st.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	instances := make([]*archiving.Instance, 0, len(instanceIDs))
+
+	for _, instanceID := range instanceIDs {
+		row := tx.QueryRowContext(ctx, st.queries.ArchivedInstance, instanceID)
+
+		var executionID string
+		var parentInstanceID *string
+		var completedAt time.Time
+		if err := row.Scan(&executionID, &parentInstanceID, &completedAt); err != nil {
+			return nil, errors.Wrap(err, "could not get workflow instance")
+		}
+
+		events, err := st.queries.GetHistory(ctx, tx, instanceID)
+		if err != nil {
+			return nil, err
+		}
+
+		var parent string
+		if parentInstanceID != nil {
+			parent = *parentInstanceID
+		}
+
+		instances = append(instances, &archiving.Instance{
+			InstanceID:       instanceID,
+			ExecutionID:      executionID,
+			ParentInstanceID: parent,
+			CompletedAt:      completedAt,
+			History:          events,
+		})
+	}
+
+	return instances, nil
+}
+
+// ArchiveCompletedInstance archives an instance right after it completed. Errors are only
+// logged, the instance is archived again before it's removed.
+func (st *Store) ArchiveCompletedInstance(ctx context.Context, instanceID string) {
+	if err := st.archiveInstances(ctx, []string{instanceID}); err != nil {
+		st.options.Logger.Error("Could not archive workflow instance", log.InstanceIDKey, instanceID, "error", err)
+	}
+}
@@ -0,0 +1,46 @@
+package sqlbackend
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/cschleiden/go-workflows/blob"
+	"github.com/pkg/errors"
+)
+
+// ownedBlobs returns the keys of the offloaded payloads owned by the given instances
+func (st *Store) ownedBlobs(ctx context.Context, tx *sql.Tx, instanceIDs []string) ([]string, error) {
+	if st.options.BlobStore == nil {
+		return nil, nil
+	}
+
+	keys := make([]string, 0)
+
+	for _, instanceID := range instanceIDs {
+		row := tx.QueryRowContext(ctx, st.queries.ParentInstance, instanceID)
+
+		var parentInstanceID *string
+		if err := row.Scan(&parentInstanceID); err != nil {
+			return nil, errors.Wrap(err, "could not get workflow instance")
+		}
+
+		events, err := st.queries.GetHistory(ctx, tx, instanceID)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, blob.OwnedReferences(events, parentInstanceID != nil)...)
+	}
+
+	return keys, nil
+}
+
+// deleteBlobs removes the given blobs from the blob store. The instances referencing them have
+// already been removed at this point, so errors are only logged.
+func (st *Store) deleteBlobs(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := st.options.BlobStore.Delete(ctx, key); err != nil {
+			st.options.Logger.Error("Could not delete blob", "key", key, "error", err)
+		}
+	}
+}
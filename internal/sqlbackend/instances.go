@@ -0,0 +1,109 @@
+package sqlbackend
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/pkg/errors"
+)
+
+func (st *Store) DescribeWorkflowInstance(ctx context.Context, instance workflow.Instance) (*backend.WorkflowInstanceInfo, error) {
+	row := st.db.QueryRowContext(
+		ctx,
+		st.queries.DescribeInstance,
+		history.EventType_WorkflowExecutionStarted,
+		instance.GetInstanceID(),
+		instance.GetExecutionID(),
+	)
+
+	info, err := scanInstanceInfo(row, time.Now())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, backend.ErrInstanceNotFound
+		}
+
+		return nil, errors.Wrap(err, "could not get workflow instance")
+	}
+
+	return info, nil
+}
+
+func (st *Store) ListWorkflowInstances(ctx context.Context, afterInstanceID string, limit int) ([]*backend.WorkflowInstanceInfo, error) {
+	rows, err := st.db.QueryContext(
+		ctx,
+		st.queries.ListInstances,
+		history.EventType_WorkflowExecutionStarted,
+		afterInstanceID,
+		limit,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list workflow instances")
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	infos := make([]*backend.WorkflowInstanceInfo, 0)
+	for rows.Next() {
+		info, err := scanInstanceInfo(rows, now)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan workflow instance")
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, rows.Err()
+}
+
+func scanInstanceInfo(row interface{ Scan(...interface{}) error }, now time.Time) (*backend.WorkflowInstanceInfo, error) {
+	var instanceID, executionID string
+	var parentInstanceID, parentExecutionID *string
+	var parentEventID *int
+	var createdAt time.Time
+	var completedAt, startAt *time.Time
+	if err := row.Scan(&instanceID, &executionID, &parentInstanceID, &parentExecutionID, &parentEventID, &createdAt, &completedAt, &startAt); err != nil {
+		return nil, err
+	}
+
+	var wfi workflow.Instance
+	if parentInstanceID != nil {
+		// The parent might already have been removed
+		var parentExecution string
+		if parentExecutionID != nil {
+			parentExecution = *parentExecutionID
+		}
+
+		wfi = core.NewSubWorkflowInstance(instanceID, executionID, core.NewWorkflowInstance(*parentInstanceID, parentExecution), *parentEventID)
+	} else {
+		wfi = core.NewWorkflowInstance(instanceID, executionID)
+	}
+
+	return backend.NewWorkflowInstanceInfo(wfi, createdAt, startAt, completedAt, now), nil
+}
+
+// ScheduledStart returns when the given instance is scheduled to start, or nil if it already started
+func (st *Store) ScheduledStart(ctx context.Context, tx *sql.Tx, instanceID string) (*time.Time, error) {
+	row := tx.QueryRowContext(
+		ctx,
+		st.queries.ScheduledStart,
+		instanceID,
+		history.EventType_WorkflowExecutionStarted,
+		time.Now(),
+	)
+
+	var startAt *time.Time
+	if err := row.Scan(&startAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrap(err, "could not get scheduled start")
+	}
+
+	return startAt, nil
+}
@@ -0,0 +1,136 @@
+package sqlbackend
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/pkg/errors"
+)
+
+// RemoveWorkflowInstance removes a completed workflow instance including its history
+func (st *Store) RemoveWorkflowInstance(ctx context.Context, instance workflow.Instance) error {
+	row := st.db.QueryRowContext(ctx, st.queries.CompletedAt, instance.GetInstanceID(), instance.GetExecutionID())
+
+	var completedAt *time.Time
+	if err := row.Scan(&completedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return backend.ErrInstanceNotFound
+		}
+
+		return errors.Wrap(err, "could not get workflow instance")
+	}
+
+	if completedAt == nil {
+		return backend.ErrInstanceNotCompleted
+	}
+
+	removed, err := st.removeCompletedInstances(ctx, []string{instance.GetInstanceID()})
+	if err != nil {
+		return err
+	}
+
+	if removed == 0 {
+		// Removed concurrently
+		return backend.ErrInstanceNotFound
+	}
+
+	return nil
+}
+
+// PurgeCompletedInstances removes up to limit instances completed before cutoff
+func (st *Store) PurgeCompletedInstances(ctx context.Context, cutoff time.Time, limit int) (int, error) {
+	rows, err := st.db.QueryContext(ctx, st.queries.ExpiredInstances, cutoff, limit)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not get expired workflow instances")
+	}
+
+	instanceIDs, err := scanInstanceIDs(rows, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(instanceIDs) == 0 {
+		return 0, nil
+	}
+
+	return st.removeCompletedInstances(ctx, instanceIDs)
+}
+
+// removeCompletedInstances archives the given instances and then removes them. Instances are only
+// removed once they have been archived, instances removed in the meantime are skipped.
+func (st *Store) removeCompletedInstances(ctx context.Context, instanceIDs []string) (int, error) {
+	if err := st.archiveInstances(ctx, instanceIDs); err != nil {
+		return 0, err
+	}
+
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	in, args := st.queries.In(instanceIDs)
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(st.queries.CompletedInstances, in), args...)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not get completed workflow instances")
+	}
+
+	instanceIDs, err = scanInstanceIDs(rows, len(instanceIDs))
+	if err != nil {
+		return 0, err
+	}
+
+	if len(instanceIDs) == 0 {
+		return 0, nil
+	}
+
+	blobs, err := st.ownedBlobs(ctx, tx, instanceIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := st.removeInstances(ctx, tx, instanceIDs); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	// Only delete blobs once the instances referencing them are gone
+	st.deleteBlobs(ctx, blobs)
+
+	return len(instanceIDs), nil
+}
+
+func scanInstanceIDs(rows *sql.Rows, limit int) ([]string, error) {
+	defer rows.Close()
+
+	instanceIDs := make([]string, 0, limit)
+	for rows.Next() {
+		var instanceID string
+		if err := rows.Scan(&instanceID); err != nil {
+			return nil, errors.Wrap(err, "could not scan workflow instance")
+		}
+
+		instanceIDs = append(instanceIDs, instanceID)
+	}
+
+	return instanceIDs, rows.Err()
+}
+
+func (st *Store) removeInstances(ctx context.Context, tx *sql.Tx, instanceIDs []string) error {
+	in, args := st.queries.In(instanceIDs)
+
+	for _, query := range st.queries.RemoveInstances {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(query, in), args...); err != nil {
+			return errors.Wrap(err, "could not remove workflow instances")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package sqlbackend
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/blob"
+	"github.com/cschleiden/go-workflows/internal/schedule"
+	"github.com/pkg/errors"
+)
+
+func (st *Store) CreateSchedule(ctx context.Context, s *schedule.Schedule) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal schedule")
+	}
+
+	res, err := st.db.ExecContext(ctx, st.queries.InsertSchedule, s.ID, 1, s.DueAt, data)
+	if err != nil {
+		return errors.Wrap(err, "could not insert schedule")
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return errors.Wrap(err, "could not insert schedule")
+	} else if n == 0 {
+		return backend.ErrScheduleAlreadyExists
+	}
+
+	s.Version = 1
+
+	st.notifications.NotifySchedules()
+
+	return nil
+}
+
+func (st *Store) GetSchedule(ctx context.Context, scheduleID string) (*schedule.Schedule, error) {
+	row := st.db.QueryRowContext(ctx, st.queries.GetSchedule, scheduleID)
+
+	s, err := scanSchedule(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, backend.ErrScheduleNotFound
+		}
+
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (st *Store) ListSchedules(ctx context.Context) ([]*schedule.Schedule, error) {
+	rows, err := st.db.QueryContext(ctx, st.queries.ListSchedules)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list schedules")
+	}
+
+	return scanSchedules(rows)
+}
+
+func (st *Store) GetDueSchedules(ctx context.Context, now time.Time, limit int) ([]*schedule.Schedule, error) {
+	rows, err := st.db.QueryContext(
+		ctx,
+		st.queries.DueSchedules,
+		// SQLite compares times as strings, due times are always stored in UTC
+		now.UTC(), limit,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get due schedules")
+	}
+
+	return scanSchedules(rows)
+}
+
+func (st *Store) UpdateSchedule(ctx context.Context, s *schedule.Schedule) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal schedule")
+	}
+
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, st.queries.UpdateSchedule, s.DueAt, data, s.ID, s.Version)
+	if err != nil {
+		return errors.Wrap(err, "could not update schedule")
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return errors.Wrap(err, "could not update schedule")
+	} else if n == 0 {
+		var exists int
+		if err := tx.QueryRowContext(ctx, st.queries.ScheduleExists, s.ID).Scan(&exists); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return backend.ErrScheduleNotFound
+			}
+
+			return errors.Wrap(err, "could not get schedule")
+		}
+
+		return backend.ErrScheduleModified
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.Version++
+
+	st.notifications.NotifySchedules()
+
+	return nil
+}
+
+// DeleteSchedule removes a schedule and deletes the blobs of its offloaded inputs. Runs that
+// were already started own copies of these blobs, so they are not affected.
+func (st *Store) DeleteSchedule(ctx context.Context, scheduleID string) error {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	s, err := scanSchedule(tx.QueryRowContext(ctx, st.queries.GetSchedule, scheduleID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return backend.ErrScheduleNotFound
+		}
+
+		return errors.Wrap(err, "could not get schedule")
+	}
+
+	res, err := tx.ExecContext(ctx, st.queries.DeleteSchedule, scheduleID)
+	if err != nil {
+		return errors.Wrap(err, "could not delete schedule")
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return errors.Wrap(err, "could not delete schedule")
+	} else if n == 0 {
+		return backend.ErrScheduleNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// Only delete blobs once the schedule referencing them is gone
+	if st.options.BlobStore != nil {
+		st.deleteBlobs(ctx, blob.References(s.Inputs))
+	}
+
+	return nil
+}
+
+func scanSchedule(row interface{ Scan(...interface{}) error }) (*schedule.Schedule, error) {
+	var version int64
+	var data []byte
+	if err := row.Scan(&version, &data); err != nil {
+		return nil, err
+	}
+
+	var s schedule.Schedule
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal schedule")
+	}
+
+	s.Version = version
+
+	return &s, nil
+}
+
+func scanSchedules(rows *sql.Rows) ([]*schedule.Schedule, error) {
+	defer rows.Close()
+
+	schedules := make([]*schedule.Schedule, 0)
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan schedule")
+		}
+
+		schedules = append(schedules, s)
+	}
+
+	return schedules, rows.Err()
+}
@@ -0,0 +1,106 @@
+// Package sqlbackend implements the parts of the SQL backends that only differ in their queries:
+// schedules, describing and listing instances, removing completed instances, archiving, and
+// deleting offloaded payloads.
+package sqlbackend
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+// Queries are the statements of a SQL dialect used by Store. Queries containing %s are
+// formatted with the expression returned by In.
+type Queries struct {
+	// In returns an expression matching a column against the given values, and its arguments.
+	// It's only used in queries without any other arguments.
+	In func(values []string) (string, []interface{})
+
+	// GetHistory returns the history of an instance
+	GetHistory func(ctx context.Context, tx *sql.Tx, instanceID string) ([]history.Event, error)
+
+	// DescribeInstance selects the info of an instance. Arguments: event type of
+	// WorkflowExecutionStarted, instance ID, execution ID
+	DescribeInstance string
+
+	// ListInstances selects the info of instances ordered by ID. Arguments: event type of
+	// WorkflowExecutionStarted, instance ID to list after, limit
+	ListInstances string
+
+	// ScheduledStart selects the visible_at of a pending start event. Arguments: instance ID,
+	// event type of WorkflowExecutionStarted, now
+	ScheduledStart string
+
+	// CompletedAt selects when an instance completed. Arguments: instance ID, execution ID
+	CompletedAt string
+
+	// ExpiredInstances selects the IDs of instances completed before a cutoff. Arguments: cutoff,
+	// limit
+	ExpiredInstances string
+
+	// CompletedInstances selects and locks the IDs of the given instances that are completed
+	CompletedInstances string
+
+	// RemoveInstances delete the history, pending events, activities, and the given instances
+	RemoveInstances []string
+
+	// ArchivedInstance selects the execution ID, parent instance ID, and completion time of an
+	// instance. Arguments: instance ID
+	ArchivedInstance string
+
+	// ParentInstance selects the parent instance ID of an instance. Arguments: instance ID
+	ParentInstance string
+
+	// InsertSchedule inserts a schedule unless it already exists. Arguments: ID, version, due at,
+	// data
+	InsertSchedule string
+
+	// GetSchedule selects the version and data of a schedule. Arguments: ID
+	GetSchedule string
+
+	// ListSchedules selects the version and data of all schedules ordered by ID
+	ListSchedules string
+
+	// DueSchedules selects the version and data of due schedules. Arguments: now, limit
+	DueSchedules string
+
+	// UpdateSchedule updates a schedule with the given version and increments the version.
+	// Arguments: due at, data, ID, version
+	UpdateSchedule string
+
+	// ScheduleExists selects 1 if a schedule exists. Arguments: ID
+	ScheduleExists string
+
+	// DeleteSchedule deletes a schedule. Arguments: ID
+	DeleteSchedule string
+}
+
+// Store implements the shared parts of a SQL backend. Backends embed it to provide its methods.
+type Store struct {
+	db            *sql.DB
+	queries       Queries
+	options       backend.Options
+	notifications *backend.Notifications
+}
+
+func New(db *sql.DB, queries Queries, options backend.Options, notifications *backend.Notifications) *Store {
+	return &Store{
+		db:            db,
+		queries:       queries,
+		options:       options,
+		notifications: notifications,
+	}
+}
+
+// InList returns an IN expression with ? placeholders for the given values
+func InList(values []string) (string, []interface{}) {
+	args := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		args = append(args, v)
+	}
+
+	return "IN (?" + strings.Repeat(",?", len(values)-1) + ")", args
+}
@@ -0,0 +1,14 @@
+package sqlbackend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_InList(t *testing.T) {
+	in, args := InList([]string{"a", "b", "c"})
+
+	require.Equal(t, "IN (?,?,?)", in)
+	require.Equal(t, []interface{}{"a", "b", "c"}, args)
+}
@@ -27,16 +27,14 @@ type ChannelInternal interface {
 
 func NewChannel() Channel {
 	return &channel{
-		c:         make([]interface{}, 0),
-		converter: converter.DefaultConverter,
+		c: make([]interface{}, 0),
 	}
 }
 
 func NewBufferedChannel(size int) Channel {
 	return &channel{
-		c:         make([]interface{}, 0, size),
-		size:      size,
-		converter: converter.DefaultConverter,
+		c:    make([]interface{}, 0, size),
+		size: size,
 	}
 }
 
@@ -46,7 +44,6 @@ type channel struct {
 	senders   []func() interface{}
 	closed    bool
 	size      int
-	converter converter.Converter
 }
 
 var _ Channel = (*channel)(nil)
@@ -113,7 +110,7 @@ func (c *channel) Receive(ctx Context, vptr interface{}) (more bool) {
 
 	for {
 		// Try to receive from buffered channel or blocked sender
-		if c.tryReceive(vptr) {
+		if c.tryReceive(Converter(ctx), vptr) {
 			cr.MadeProgress()
 			return !c.closed
 		}
@@ -124,7 +121,7 @@ func (c *channel) Receive(ctx Context, vptr interface{}) (more bool) {
 				receivedValue = true
 
 				if vptr != nil {
-					if err := converter.AssignValue(c.converter, v, vptr); err != nil {
+					if err := converter.AssignValue(Converter(ctx), v, vptr); err != nil {
 						panic(err)
 					}
 				}
@@ -145,7 +142,7 @@ func (c *channel) Receive(ctx Context, vptr interface{}) (more bool) {
 }
 
 func (c *channel) ReceiveNonblocking(ctx Context, vptr interface{}) (ok bool) {
-	return c.tryReceive(vptr)
+	return c.tryReceive(Converter(ctx), vptr)
 }
 
 func (c *channel) hasValue() bool {
@@ -182,14 +179,14 @@ func (c *channel) trySend(v interface{}) bool {
 	return false
 }
 
-func (c *channel) tryReceive(vptr interface{}) bool {
+func (c *channel) tryReceive(conv converter.Converter, vptr interface{}) bool {
 	// If channel is buffered, return value if available
 	if c.hasValue() {
 		v := c.c[0]
 		c.c = c.c[1:]
 
 		if vptr != nil {
-			if err := converter.AssignValue(c.converter, v, vptr); err != nil {
+			if err := converter.AssignValue(conv, v, vptr); err != nil {
 				panic(errors.Wrap(err, "could not assign value when receiving from channel"))
 			}
 		}
@@ -201,7 +198,7 @@ func (c *channel) tryReceive(vptr interface{}) bool {
 	// element
 	if c.closed {
 		if vptr != nil {
-			if err := converter.AssignValue(c.converter, nil, vptr); err != nil {
+			if err := converter.AssignValue(conv, nil, vptr); err != nil {
 				panic(err)
 			}
 		}
@@ -217,7 +214,7 @@ func (c *channel) tryReceive(vptr interface{}) bool {
 		v := s()
 
 		if vptr != nil {
-			if err := converter.AssignValue(c.converter, v, vptr); err != nil {
+			if err := converter.AssignValue(conv, v, vptr); err != nil {
 				panic(err)
 			}
 		}
@@ -238,7 +235,7 @@ func (c *channel) AddReceiveCallback(cb func(v interface{})) {
 
 func (c *channel) ReceiveNonBlocking(ctx Context, cb func(v interface{})) (ok bool) {
 	var vptr interface{}
-	if c.tryReceive(vptr) {
+	if c.tryReceive(Converter(ctx), vptr) {
 		cb(vptr)
 		return true
 	}
@@ -0,0 +1,20 @@
+package sync
+
+import "github.com/cschleiden/go-workflows/internal/converter"
+
+type converterKey struct{}
+
+// WithConverter returns a copy of ctx with the converter used to convert values received from
+// futures and channels, and values passed to activities and sub-workflows.
+func WithConverter(ctx Context, c converter.Converter) Context {
+	return WithValue(ctx, converterKey{}, c)
+}
+
+// Converter returns the converter of ctx, or the default converter if none has been set
+func Converter(ctx Context) converter.Converter {
+	if c, ok := ctx.Value(converterKey{}).(converter.Converter); ok {
+		return c
+	}
+
+	return converter.DefaultConverter
+}
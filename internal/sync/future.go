@@ -11,16 +11,13 @@ type Future interface {
 }
 
 func NewFuture() Future {
-	return &futureImpl{
-		converter: converter.DefaultConverter,
-	}
+	return &futureImpl{}
 }
 
 type futureImpl struct {
-	hasValue  bool
-	v         interface{}
-	err       error
-	converter converter.Converter
+	hasValue bool
+	v        interface{}
+	err      error
 }
 
 func (f *futureImpl) Set(v interface{}, err error) {
@@ -45,7 +42,7 @@ func (f *futureImpl) Get(ctx Context, vptr interface{}) error {
 			}
 
 			if vptr != nil {
-				return converter.AssignValue(f.converter, f.v, vptr)
+				return converter.AssignValue(Converter(ctx), f.v, vptr)
 			}
 
 			return nil
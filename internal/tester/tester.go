@@ -72,6 +72,16 @@ type testWorkflow struct {
 
 type options struct {
 	TestTimeout time.Duration
+	Converter   converter.Converter
+}
+
+type WorkflowTesterOption func(*options)
+
+// WithConverter sets the converter used for workflow, activity, and signal payloads
+func WithConverter(c converter.DataConverter) WorkflowTesterOption {
+	return func(o *options) {
+		o.Converter = converter.FromDataConverter(c)
+	}
 }
 
 type workflowTester struct {
@@ -107,7 +117,7 @@ type workflowTester struct {
 	runningActivities int32
 }
 
-func NewWorkflowTester(wf interface{}) WorkflowTester {
+func NewWorkflowTester(wf interface{}, opts ...WorkflowTesterOption) WorkflowTester {
 	// Start with the current wall-clock tiem
 	clock := clock.NewMock()
 	clock.Set(time.Now())
@@ -115,10 +125,16 @@ func NewWorkflowTester(wf interface{}) WorkflowTester {
 	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
 	registry := workflow.NewRegistry()
 
+	options := &options{
+		TestTimeout: time.Second * 10,
+		Converter:   converter.DefaultConverter,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	wt := &workflowTester{
-		options: &options{
-			TestTimeout: time.Second * 10,
-		},
+		options: options,
 
 		wf:       wf,
 		wfi:      wfi,
@@ -205,7 +221,7 @@ func (wt *workflowTester) Execute(args ...interface{}) {
 			tw.pendingEvents = tw.pendingEvents[:0]
 
 			// Execute task
-			e, err := workflow.NewExecutor(wt.registry, tw.instance, wt.clock)
+			e, err := workflow.NewExecutor(wt.registry, tw.instance, wt.clock, workflow.WithConverter(wt.options.Converter))
 			if err != nil {
 				panic("could not create workflow executor" + err.Error())
 			}
@@ -328,7 +344,7 @@ func (wt *workflowTester) SignalWorkflow(name string, value interface{}) {
 }
 
 func (wt *workflowTester) SignalWorkflowInstance(wfi core.WorkflowInstance, name string, value interface{}) {
-	arg, err := converter.DefaultConverter.To(value)
+	arg, err := wt.options.Converter.To(value)
 	if err != nil {
 		panic("Could not convert signal value to string" + err.Error())
 	}
@@ -356,7 +372,7 @@ func (wt *workflowTester) WorkflowFinished() bool {
 
 func (wt *workflowTester) WorkflowResult(vtpr interface{}, err *string) {
 	if wt.workflowErr == "" {
-		if err := converter.AssignValue(converter.DefaultConverter, wt.workflowResult, vtpr); err != nil {
+		if err := converter.AssignValue(wt.options.Converter, wt.workflowResult, vtpr); err != nil {
 			panic("Could not convert result to provided type" + err.Error())
 		}
 	}
@@ -387,7 +403,7 @@ func (wt *workflowTester) scheduleActivity(wfi core.WorkflowInstance, event hist
 				panic("Could not find activity " + e.Name + " in registry")
 			}
 
-			argValues, addContext, err := margs.InputsToArgs(converter.DefaultConverter, reflect.ValueOf(afn), e.Inputs)
+			argValues, addContext, err := margs.InputsToArgs(wt.options.Converter, reflect.ValueOf(afn), e.Inputs)
 			if err != nil {
 				panic("Could not convert activity inputs to args: " + err.Error())
 			}
@@ -408,10 +424,10 @@ func (wt *workflowTester) scheduleActivity(wfi core.WorkflowInstance, event hist
 			case 1:
 				// Expect only error
 				activityErr = results.Error(0)
-				activityResult = nil
+				activityResult = payload.Payload{}
 			case 2:
 				result := results.Get(0)
-				activityResult, err = converter.DefaultConverter.To(result)
+				activityResult, err = wt.options.Converter.To(result)
 				if err != nil {
 					panic("Could not convert result for activity " + e.Name + ": " + err.Error())
 				}
@@ -428,7 +444,7 @@ func (wt *workflowTester) scheduleActivity(wfi core.WorkflowInstance, event hist
 			}
 
 		} else {
-			executor := activity.NewExecutor(wt.registry)
+			executor := activity.NewExecutor(wt.registry, activity.WithConverter(wt.options.Converter))
 			activityResult, activityErr = executor.ExecuteActivity(context.Background(), &task.Activity{
 				ID:               uuid.NewString(),
 				WorkflowInstance: wfi,
@@ -493,7 +509,7 @@ func (wt *workflowTester) scheduleSubWorkflow(event history.WorkflowEvent) {
 		panic("Could not find workflow " + a.Name + " in registry")
 	}
 
-	argValues, addContext, err := margs.InputsToArgs(converter.DefaultConverter, reflect.ValueOf(wfn), a.Inputs)
+	argValues, addContext, err := margs.InputsToArgs(wt.options.Converter, reflect.ValueOf(wfn), a.Inputs)
 	if err != nil {
 		panic("Could not convert workflow inputs to args: " + err.Error())
 	}
@@ -523,10 +539,10 @@ func (wt *workflowTester) scheduleSubWorkflow(event history.WorkflowEvent) {
 	case 1:
 		// Expect only error
 		workflowErr = results.Error(0)
-		workflowResult = nil
+		workflowResult = payload.Payload{}
 	case 2:
 		result := results.Get(0)
-		workflowResult, err = converter.DefaultConverter.To(result)
+		workflowResult, err = wt.options.Converter.To(result)
 		if err != nil {
 			panic("Could not convert result for mocked workflow " + a.Name + ": " + err.Error())
 		}
@@ -576,7 +592,7 @@ func (wt *workflowTester) scheduleSubWorkflow(event history.WorkflowEvent) {
 func (wt *workflowTester) getInitialEvent(wf interface{}, args []interface{}) history.Event {
 	name := fn.Name(wf)
 
-	inputs, err := margs.ArgsToInputs(converter.DefaultConverter, args...)
+	inputs, err := margs.ArgsToInputs(wt.options.Converter, args...)
 	if err != nil {
 		panic(err)
 	}
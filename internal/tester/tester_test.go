@@ -7,9 +7,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/payload"
 	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 func Test_Workflow(t *testing.T) {
@@ -252,3 +255,69 @@ func workflowSignal(ctx workflow.Context) (string, error) {
 
 	return val, nil
 }
+
+type countingConverter struct {
+	to, from int
+}
+
+func (c *countingConverter) To(v interface{}) ([]byte, error) {
+	c.to++
+	p, err := converter.DefaultConverter.To(v)
+	return p.Data, err
+}
+
+func (c *countingConverter) From(data []byte, v interface{}) error {
+	c.from++
+	return converter.DefaultConverter.From(payload.New(data, nil), v)
+}
+
+func Test_Converter(t *testing.T) {
+	c := &countingConverter{}
+	tester := NewWorkflowTester(workflowWithActivity, WithConverter(c))
+	tester.Registry().RegisterActivity(activity1)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	var r int
+	tester.WorkflowResult(&r, nil)
+	require.Equal(t, 23, r)
+
+	// Activity result and workflow result are converted to payloads, and read back by the
+	// workflow and the test
+	require.Equal(t, 2, c.to)
+	require.Equal(t, 2, c.from)
+}
+
+func Test_Converter_Proto(t *testing.T) {
+	tester := NewWorkflowTester(workflowProto, WithConverter(converter.ToEncodingDataConverter(converter.NewProtoConverter())))
+	tester.Registry().RegisterActivity(activityProto)
+	tester.ScheduleCallback(time.Second, func() {
+		tester.SignalWorkflow("name", wrapperspb.String("world"))
+	})
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	var r *wrapperspb.StringValue
+	var errStr string
+	tester.WorkflowResult(&r, &errStr)
+	require.Empty(t, errStr)
+	require.Equal(t, "hello world", r.GetValue())
+}
+
+func workflowProto(ctx workflow.Context) (*wrapperspb.StringValue, error) {
+	var name *wrapperspb.StringValue
+	workflow.NewSignalChannel(ctx, "name").Receive(ctx, &name)
+
+	var r *wrapperspb.StringValue
+	if err := workflow.ExecuteActivity(ctx, workflow.DefaultActivityOptions, activityProto, name).Get(ctx, &r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func activityProto(ctx context.Context, name *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	return wrapperspb.String("hello " + name.GetValue()), nil
+}
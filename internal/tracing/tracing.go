@@ -0,0 +1,53 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const TracerName = "github.com/cschleiden/go-workflows"
+
+var propagator = propagation.TraceContext{}
+
+// Tracer returns the tracer to use for the given provider. If no provider is given, spans are
+// not recorded.
+func Tracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = trace.NewNoopTracerProvider()
+	}
+
+	return tp.Tracer(TracerName)
+}
+
+// Inject serializes the span context of ctx so that it can be stored in event attributes
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+
+	if len(carrier) == 0 {
+		return nil
+	}
+
+	return carrier
+}
+
+// Extract returns a copy of ctx with the span context stored in metadata as the remote parent
+func Extract(ctx context.Context, metadata map[string]string) context.Context {
+	if len(metadata) == 0 {
+		return ctx
+	}
+
+	return propagator.Extract(ctx, propagation.MapCarrier(metadata))
+}
+
+const (
+	AttributeInstanceID     = "workflow.instance.id"
+	AttributeExecutionID    = "workflow.execution.id"
+	AttributeWorkflowName   = "workflow.name"
+	AttributeActivityName   = "workflow.activity.name"
+	AttributeActivityID     = "workflow.activity.id"
+	AttributeContinuation   = "workflow.task.continuation"
+	AttributeReplayedEvents = "workflow.task.replayed_events"
+)
@@ -2,16 +2,23 @@ package worker
 
 import (
 	"context"
-	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/internal/activity"
 	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/internal/metrickeys"
 	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/cschleiden/go-workflows/internal/tracing"
 	"github.com/cschleiden/go-workflows/internal/workflow"
+	"github.com/cschleiden/go-workflows/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ActivityWorker interface {
@@ -27,35 +34,61 @@ type activityWorker struct {
 	activityTaskQueue    chan *task.Activity
 	activityTaskExecutor activity.Executor
 
-	logger *log.Logger
+	logger log.Logger
 
 	wg *sync.WaitGroup
 
 	clock clock.Clock
+
+	tracer trace.Tracer
+
+	metrics    metrics.Client
+	inProgress int64
 }
 
 func NewActivityWorker(backend backend.Backend, registry *workflow.Registry, clock clock.Clock, options *Options) ActivityWorker {
+	mc := metricsClient(options)
+
 	return &activityWorker{
 		backend: backend,
 
 		options: options,
 
-		activityTaskQueue:    make(chan *task.Activity),
-		activityTaskExecutor: activity.NewExecutor(registry),
+		activityTaskQueue: make(chan *task.Activity),
+		activityTaskExecutor: activity.NewExecutor(
+			registry,
+			activity.WithInterceptors(options.ActivityInterceptors...),
+			activity.WithMetrics(mc),
+			activity.WithConverter(payloadConverter(options)),
+		),
 
-		logger: log.Default(),
+		logger: logger(options),
 
 		wg: &sync.WaitGroup{},
 
 		clock: clock,
+
+		tracer: tracing.Tracer(options.TracerProvider),
+
+		metrics: mc,
 	}
 }
 
 func (aw *activityWorker) Start(ctx context.Context) error {
-	for i := 0; i <= aw.options.ActivityPollers; i++ {
-		go aw.runPoll(ctx)
+	var notifications func() <-chan struct{}
+	if n, ok := aw.backend.(backend.Notifier); ok {
+		notifications = n.ActivityTasksNotification
 	}
 
+	newPoller(
+		aw.options.ActivityPollers,
+		aw.options.MaxActivityPollers,
+		newPollInterval(aw.options),
+		aw.pollTask,
+		notifications,
+		aw.logger.With("task_type", "activity"),
+	).Start(ctx)
+
 	go aw.runDispatcher(ctx)
 
 	return nil
@@ -67,20 +100,19 @@ func (aw *activityWorker) Stop() error {
 	return nil
 }
 
-func (aw *activityWorker) runPoll(ctx context.Context) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			task, err := aw.poll(ctx, 30*time.Second)
-			if err != nil {
-				log.Println("error while polling for activity task:", err)
-			} else if task != nil {
-				aw.activityTaskQueue <- task
-			}
-		}
+// pollTask polls for a single activity task and queues it for the dispatcher
+func (aw *activityWorker) pollTask(ctx context.Context) (bool, error) {
+	task, err := aw.poll(ctx, 30*time.Second)
+	if err != nil || task == nil {
+		return false, err
+	}
+
+	select {
+	case aw.activityTaskQueue <- task:
+	case <-ctx.Done():
 	}
+
+	return true, nil
 }
 
 func (aw *activityWorker) runDispatcher(ctx context.Context) {
@@ -102,10 +134,14 @@ func (aw *activityWorker) runDispatcher(ctx context.Context) {
 			go func() {
 				defer aw.wg.Done()
 
+				aw.metrics.Gauge(metrickeys.TasksInProgress, activityTags, atomic.AddInt64(&aw.inProgress, 1))
+
 				// Create new context to allow activities to complete when root context is canceled
 				taskCtx := context.Background()
 				aw.handleTask(taskCtx, task)
 
+				aw.metrics.Gauge(metrickeys.TasksInProgress, activityTags, atomic.AddInt64(&aw.inProgress, -1))
+
 				if sem != nil {
 					<-sem
 				}
@@ -115,6 +151,18 @@ func (aw *activityWorker) runDispatcher(ctx context.Context) {
 }
 
 func (aw *activityWorker) handleTask(ctx context.Context, task *task.Activity) {
+	start := time.Now()
+
+	a := task.Event.Attributes.(*history.ActivityScheduledAttributes)
+
+	logger := aw.logger.With(
+		log.InstanceIDKey, task.WorkflowInstance.GetInstanceID(),
+		log.ExecutionIDKey, task.WorkflowInstance.GetExecutionID(),
+		log.ActivityNameKey, a.Name,
+		log.ActivityIDKey, task.ID,
+		log.ScheduleEventIDKey, task.Event.ScheduleEventID,
+	)
+
 	heartbeatCtx, cancelHeartbeat := context.WithCancel(ctx)
 
 	go func(ctx context.Context) {
@@ -127,16 +175,34 @@ func (aw *activityWorker) handleTask(ctx context.Context, task *task.Activity) {
 				return
 			case <-t.C:
 				if err := aw.backend.ExtendActivityTask(ctx, task.ID); err != nil {
-					aw.logger.Panic(err)
+					logger.Error("could not extend activity task", log.ErrorKey, err)
+					panic(err)
 				}
 			}
 		}
 	}(heartbeatCtx)
 
-	result, err := aw.activityTaskExecutor.ExecuteActivity(ctx, task)
+	activityCtx, span := aw.tracer.Start(tracing.Extract(ctx, a.Metadata), "ActivityTask", trace.WithAttributes(
+		attribute.String(tracing.AttributeInstanceID, task.WorkflowInstance.GetInstanceID()),
+		attribute.String(tracing.AttributeExecutionID, task.WorkflowInstance.GetExecutionID()),
+		attribute.String(tracing.AttributeActivityName, a.Name),
+		attribute.String(tracing.AttributeActivityID, task.ID),
+	))
+
+	logger.Debug("Executing activity")
+
+	result, err := aw.activityTaskExecutor.ExecuteActivity(activityCtx, task)
 
 	cancelHeartbeat()
 
+	if err != nil {
+		logger.Warning("Activity failed", log.ErrorKey, err)
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+
 	var event history.Event
 
 	if err != nil {
@@ -159,8 +225,20 @@ func (aw *activityWorker) handleTask(ctx context.Context, task *task.Activity) {
 	}
 
 	if err := aw.backend.CompleteActivityTask(ctx, task.WorkflowInstance, task.ID, event); err != nil {
-		aw.logger.Panic(err)
+		aw.recordTask(start, err)
+		logger.Error("could not complete activity task", log.ErrorKey, err)
+		panic(err)
 	}
+
+	aw.recordTask(start, nil)
+}
+
+func (aw *activityWorker) recordTask(start time.Time, err error) {
+	aw.metrics.Distribution(metrickeys.TaskDuration, activityTags, time.Since(start).Seconds())
+	aw.metrics.Counter(metrickeys.TasksProcessed, metrics.Tags{
+		metrickeys.Type:   metrickeys.TypeActivity,
+		metrickeys.Result: result(err),
+	}, 1)
 }
 
 func (aw *activityWorker) poll(ctx context.Context, timeout time.Duration) (*task.Activity, error) {
@@ -176,6 +254,8 @@ func (aw *activityWorker) poll(ctx context.Context, timeout time.Duration) (*tas
 
 	done := make(chan struct{})
 
+	start := time.Now()
+
 	go func() {
 		task, err = aw.backend.GetActivityTask(ctx)
 		close(done)
@@ -183,8 +263,10 @@ func (aw *activityWorker) poll(ctx context.Context, timeout time.Duration) (*tas
 
 	select {
 	case <-ctx.Done():
+		recordPoll(aw.metrics, metrickeys.TypeActivity, start, false, nil)
 		return nil, nil
 	case <-done:
+		recordPoll(aw.metrics, metrickeys.TypeActivity, start, task != nil, err)
 		return task, err
 	}
 }
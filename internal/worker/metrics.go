@@ -0,0 +1,36 @@
+package worker
+
+import (
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/metrickeys"
+	"github.com/cschleiden/go-workflows/metrics"
+)
+
+var (
+	workflowTags = metrics.Tags{metrickeys.Type: metrickeys.TypeWorkflow}
+	activityTags = metrics.Tags{metrickeys.Type: metrickeys.TypeActivity}
+)
+
+func result(err error) string {
+	if err != nil {
+		return metrickeys.ResultError
+	}
+
+	return metrickeys.ResultSuccess
+}
+
+// recordPoll records the duration and outcome of a single poll for a task of the given type
+func recordPoll(mc metrics.Client, taskType string, start time.Time, found bool, err error) {
+	r := metrickeys.ResultEmpty
+	if err != nil {
+		r = metrickeys.ResultError
+	} else if found {
+		r = metrickeys.ResultSuccess
+	}
+
+	mc.Distribution(metrickeys.PollDuration, metrics.Tags{
+		metrickeys.Type:   taskType,
+		metrickeys.Result: r,
+	}, time.Since(start).Seconds())
+}
@@ -1,9 +1,25 @@
 package worker
 
+import (
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/activity"
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/cschleiden/go-workflows/workflow"
+	"go.opentelemetry.io/otel/trace"
+)
+
 type Options struct {
 	// WorkflowsPollers is the number of pollers to start. Defaults to 2.
 	WorkflowPollers int
 
+	// MaxWorkflowPollers is the maximum number of workflow pollers. While polls keep returning
+	// tasks, additional pollers are started up to this number. They are stopped again once
+	// there are no more pending tasks. The default is 0 which disables scaling.
+	MaxWorkflowPollers int
+
 	// MaxParallelWorkflowTasks determines the maximum number of concurrent workflow tasks processed
 	// by the worker. The default is 0 which is no limit.
 	MaxParallelWorkflowTasks int
@@ -11,19 +27,106 @@ type Options struct {
 	// ActivityPollers is the number of pollers to start. Defaults to 2.
 	ActivityPollers int
 
+	// MaxActivityPollers is the maximum number of activity pollers. While polls keep returning
+	// tasks, additional pollers are started up to this number. They are stopped again once
+	// there are no more pending tasks. The default is 0 which disables scaling.
+	MaxActivityPollers int
+
 	// MaxParallelActivityTasks determines the maximum number of concurrent activity tasks processed
 	// by the worker. The default is 0 which is no limit.
 	MaxParallelActivityTasks int
 
+	// MinPollInterval is the time a poller waits after a poll that did not return a task.
+	// Defaults to 100ms.
+	MinPollInterval time.Duration
+
+	// MaxPollInterval is the maximum time a poller waits between polls. For consecutive polls
+	// that don't return a task, the interval is increased up to this value. Defaults to 5s.
+	MaxPollInterval time.Duration
+
+	// PollBackoffCoefficient is the factor by which the poll interval is increased after every
+	// poll that did not return a task. Defaults to 2.
+	PollBackoffCoefficient float64
+
+	// PollJitter is the fraction by which poll intervals are randomly varied, so that pollers
+	// don't query the backend at the same time. Set it to 0 to disable jitter. If nil, defaults
+	// to DefaultPollJitter.
+	PollJitter *float64
+
+	// ProcessSchedules determines if the worker processes schedules, starting their workflow
+	// instances when they are due. Schedules are not processed by default, so that workers of
+	// applications not using them don't poll for due schedules.
+	ProcessSchedules bool
+
+	// SchedulePollInterval is the maximum time between checks for schedules that are due to start
+	// a workflow instance. Like for tasks, checks back off from MinPollInterval to this value
+	// while no schedules are due. Defaults to 1s.
+	SchedulePollInterval time.Duration
+
 	// HeartbeatWorkflowTasks determines if the lock on workflow tasks should be periodically
 	// extended while they are being processed. Given that workflow executions should be
 	// very quick, this is usually not necessary.
 	HeartbeatWorkflowTasks bool
+
+	// WorkflowInterceptors are invoked for every workflow executed by the worker and for the
+	// activities, sub-workflows, and timers scheduled by it.
+	WorkflowInterceptors []workflow.Interceptor
+
+	// ActivityInterceptors are invoked for every activity executed by the worker.
+	ActivityInterceptors []activity.Interceptor
+
+	// TracerProvider is used to record spans for workflow tasks, activity executions, and
+	// sub-workflows. If not set, no spans are recorded.
+	TracerProvider trace.TracerProvider
+
+	// Metrics is used to record metrics like poll and task latencies, processed tasks, and
+	// activity executions. If not set, no metrics are recorded.
+	Metrics metrics.Client
+
+	// Logger is used for log messages of the worker and returned by workflow.Logger. Defaults to
+	// a logger writing to the standard library's default logger.
+	Logger log.Logger
+
+	// Converter is used to convert workflow and activity inputs and results to and from
+	// payloads. It has to match the converter used by clients starting workflows on the same
+	// backend. Defaults to converter.DefaultConverter.
+	Converter converter.DataConverter
 }
 
+// DefaultPollJitter is the default fraction by which poll intervals are randomly varied
+const DefaultPollJitter = 0.2
+
 var DefaultOptions = Options{
 	WorkflowPollers:          2,
 	ActivityPollers:          2,
 	MaxParallelWorkflowTasks: 0,
 	MaxParallelActivityTasks: 0,
+	MinPollInterval:          100 * time.Millisecond,
+	MaxPollInterval:          5 * time.Second,
+	PollBackoffCoefficient:   2,
+	SchedulePollInterval:     time.Second,
+}
+
+func metricsClient(options *Options) metrics.Client {
+	if options.Metrics == nil {
+		return metrics.NewNoopMetricsClient()
+	}
+
+	return options.Metrics
+}
+
+func logger(options *Options) log.Logger {
+	if options.Logger == nil {
+		return log.NewDefaultLogger()
+	}
+
+	return options.Logger
+}
+
+func payloadConverter(options *Options) converter.Converter {
+	if options.Converter == nil {
+		return converter.DefaultConverter
+	}
+
+	return converter.FromDataConverter(options.Converter)
 }
@@ -0,0 +1,203 @@
+package worker
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/log"
+)
+
+// pollFunc polls the backend for a single task and hands it off for processing. It returns
+// whether a task was found.
+type pollFunc func(ctx context.Context) (bool, error)
+
+// poller runs concurrent poll loops. Empty polls are followed by an exponentially increasing,
+// jittered delay. While polls keep returning tasks, additional poll loops are started up to
+// maxPollers, and they are stopped again once polls come back empty.
+type poller struct {
+	minPollers int64
+	maxPollers int64
+	active     int64
+
+	interval pollInterval
+
+	poll pollFunc
+
+	// notifications returns a channel that is closed when new tasks might be available. Can be nil.
+	notifications func() <-chan struct{}
+
+	logger log.Logger
+}
+
+func newPoller(pollers, maxPollers int, interval pollInterval, poll pollFunc, notifications func() <-chan struct{}, logger log.Logger) *poller {
+	if pollers < 1 {
+		pollers = 1
+	}
+
+	if maxPollers < pollers {
+		maxPollers = pollers
+	}
+
+	return &poller{
+		minPollers:    int64(pollers),
+		maxPollers:    int64(maxPollers),
+		interval:      interval,
+		poll:          poll,
+		notifications: notifications,
+		logger:        logger,
+	}
+}
+
+func (p *poller) Start(ctx context.Context) {
+	atomic.StoreInt64(&p.active, p.minPollers)
+
+	for i := int64(0); i < p.minPollers; i++ {
+		go p.run(ctx)
+	}
+}
+
+func (p *poller) run(ctx context.Context) {
+	delay := p.interval.Min
+
+	for {
+		if ctx.Err() != nil {
+			atomic.AddInt64(&p.active, -1)
+			return
+		}
+
+		// Get the notification channel before polling, so that no notification is missed
+		var notification <-chan struct{}
+		if p.notifications != nil {
+			notification = p.notifications()
+		}
+
+		found, err := p.poll(ctx)
+		if err != nil {
+			p.logger.Error("error while polling for task", log.ErrorKey, err)
+		} else if found {
+			delay = p.interval.Min
+			p.scaleUp(ctx)
+			continue
+		} else if p.scaleDown() {
+			// Backlog has been processed, stop additional poller
+			return
+		}
+
+		wait(ctx, p.interval.jitter(delay), notification)
+		delay = p.interval.next(delay)
+	}
+}
+
+// scaleUp starts an additional poll loop, unless the maximum number of pollers is reached
+func (p *poller) scaleUp(ctx context.Context) {
+	for {
+		active := atomic.LoadInt64(&p.active)
+		if active >= p.maxPollers {
+			return
+		}
+
+		if atomic.CompareAndSwapInt64(&p.active, active, active+1) {
+			go p.run(ctx)
+			return
+		}
+	}
+}
+
+// scaleDown returns true if the calling poll loop should stop, because more than the minimum
+// number of pollers are running
+func (p *poller) scaleDown() bool {
+	for {
+		active := atomic.LoadInt64(&p.active)
+		if active <= p.minPollers {
+			return false
+		}
+
+		if atomic.CompareAndSwapInt64(&p.active, active, active-1) {
+			return true
+		}
+	}
+}
+
+// wait blocks until the delay has expired, a notification is received, or ctx is canceled
+func wait(ctx context.Context, delay time.Duration, notification <-chan struct{}) {
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	case <-notification:
+	}
+}
+
+type pollInterval struct {
+	Min         time.Duration
+	Max         time.Duration
+	Coefficient float64
+	Jitter      float64
+}
+
+func newPollInterval(options *Options) pollInterval {
+	i := pollInterval{
+		Min:         options.MinPollInterval,
+		Max:         options.MaxPollInterval,
+		Coefficient: options.PollBackoffCoefficient,
+		Jitter:      DefaultPollJitter,
+	}
+
+	if i.Min <= 0 {
+		i.Min = DefaultOptions.MinPollInterval
+	}
+
+	if i.Max < i.Min {
+		i.Max = DefaultOptions.MaxPollInterval
+		if i.Max < i.Min {
+			i.Max = i.Min
+		}
+	}
+
+	if i.Coefficient < 1 {
+		i.Coefficient = DefaultOptions.PollBackoffCoefficient
+	}
+
+	if options.PollJitter != nil && *options.PollJitter >= 0 && *options.PollJitter <= 1 {
+		i.Jitter = *options.PollJitter
+	}
+
+	return i
+}
+
+// newSchedulePollInterval returns the interval for polling due schedules, backing off up to
+// SchedulePollInterval
+func newSchedulePollInterval(options *Options) pollInterval {
+	i := newPollInterval(options)
+
+	i.Max = options.SchedulePollInterval
+	if i.Max <= 0 {
+		i.Max = DefaultOptions.SchedulePollInterval
+	}
+
+	if i.Min > i.Max {
+		i.Min = i.Max
+	}
+
+	return i
+}
+
+// next returns the delay following the given delay
+func (i pollInterval) next(delay time.Duration) time.Duration {
+	next := time.Duration(math.Min(float64(delay)*i.Coefficient, float64(i.Max)))
+	if next < i.Min {
+		return i.Min
+	}
+
+	return next
+}
+
+// jitter randomly varies the given delay by up to the configured fraction
+func (i pollInterval) jitter(delay time.Duration) time.Duration {
+	return time.Duration(float64(delay) * (1 + i.Jitter*(2*rand.Float64()-1)))
+}
@@ -0,0 +1,134 @@
+package worker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PollInterval_Backoff(t *testing.T) {
+	i := newPollInterval(&Options{
+		MinPollInterval:        10 * time.Millisecond,
+		MaxPollInterval:        50 * time.Millisecond,
+		PollBackoffCoefficient: 2,
+	})
+
+	delay := i.Min
+	delays := []time.Duration{}
+	for j := 0; j < 4; j++ {
+		delay = i.next(delay)
+		delays = append(delays, delay)
+	}
+
+	require.Equal(t, []time.Duration{
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		50 * time.Millisecond,
+		50 * time.Millisecond,
+	}, delays)
+
+	for j := 0; j < 100; j++ {
+		d := i.jitter(10 * time.Millisecond)
+		require.GreaterOrEqual(t, d, 8*time.Millisecond)
+		require.LessOrEqual(t, d, 12*time.Millisecond)
+	}
+}
+
+func Test_PollInterval_Defaults(t *testing.T) {
+	i := newPollInterval(&Options{})
+
+	require.Equal(t, DefaultOptions.MinPollInterval, i.Min)
+	require.Equal(t, DefaultOptions.MaxPollInterval, i.Max)
+	require.Equal(t, DefaultOptions.PollBackoffCoefficient, i.Coefficient)
+	require.Equal(t, DefaultPollJitter, i.Jitter)
+}
+
+func Test_PollInterval_NoJitter(t *testing.T) {
+	jitter := 0.0
+	i := newPollInterval(&Options{PollJitter: &jitter})
+
+	require.Equal(t, 0.0, i.Jitter)
+	require.Equal(t, 10*time.Millisecond, i.jitter(10*time.Millisecond))
+}
+
+func Test_SchedulePollInterval(t *testing.T) {
+	i := newSchedulePollInterval(&Options{
+		MinPollInterval:      10 * time.Millisecond,
+		SchedulePollInterval: 40 * time.Millisecond,
+	})
+
+	require.Equal(t, 10*time.Millisecond, i.Min)
+	require.Equal(t, 40*time.Millisecond, i.Max)
+
+	i = newSchedulePollInterval(&Options{})
+
+	require.Equal(t, DefaultOptions.MinPollInterval, i.Min)
+	require.Equal(t, DefaultOptions.SchedulePollInterval, i.Max)
+}
+
+func Test_Poller_ScalesWithBacklog(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	backlog := int64(100)
+	var concurrent, maxConcurrent int64
+
+	poll := func(ctx context.Context) (bool, error) {
+		c := atomic.AddInt64(&concurrent, 1)
+		defer atomic.AddInt64(&concurrent, -1)
+
+		for {
+			m := atomic.LoadInt64(&maxConcurrent)
+			if c <= m || atomic.CompareAndSwapInt64(&maxConcurrent, m, c) {
+				break
+			}
+		}
+
+		time.Sleep(time.Millisecond)
+
+		return atomic.AddInt64(&backlog, -1) >= 0, nil
+	}
+
+	p := newPoller(1, 4, newPollInterval(&Options{}), poll, nil, log.NewNoopLogger())
+	p.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt64(&backlog) < 0 && atomic.LoadInt64(&p.active) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Greater(t, atomic.LoadInt64(&maxConcurrent), int64(1))
+	require.LessOrEqual(t, atomic.LoadInt64(&maxConcurrent), int64(4))
+}
+
+func Test_Poller_Notification(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var polls int64
+	poll := func(ctx context.Context) (bool, error) {
+		atomic.AddInt64(&polls, 1)
+		return false, nil
+	}
+
+	notification := make(chan struct{})
+	notifications := func() <-chan struct{} {
+		return notification
+	}
+
+	p := newPoller(1, 1, newPollInterval(&Options{
+		MinPollInterval: time.Hour,
+		MaxPollInterval: time.Hour,
+	}), poll, notifications, log.NewNoopLogger())
+	p.Start(ctx)
+
+	require.Eventually(t, func() bool { return atomic.LoadInt64(&polls) == 1 }, time.Second, time.Millisecond)
+
+	// Poller is waiting for the poll interval to expire, the notification wakes it up
+	close(notification)
+
+	require.Eventually(t, func() bool { return atomic.LoadInt64(&polls) > 1 }, time.Second, time.Millisecond)
+}
@@ -0,0 +1,210 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/internal/schedule"
+	"github.com/pkg/errors"
+)
+
+// maxDueSchedules is the maximum number of schedules processed per poll
+const maxDueSchedules = 100
+
+type ScheduleWorker interface {
+	Start(context.Context) error
+	Stop() error
+}
+
+// scheduleWorker polls for due schedules, starting and canceling their workflow instances.
+// Multiple workers can process schedules concurrently, each schedule update is only applied by one
+// of them.
+type scheduleWorker struct {
+	backend backend.Backend
+
+	options *Options
+
+	converter converter.Converter
+
+	logger log.Logger
+}
+
+func NewScheduleWorker(backend backend.Backend, options *Options) ScheduleWorker {
+	return &scheduleWorker{
+		backend:   backend,
+		options:   options,
+		converter: payloadConverter(options),
+		logger:    logger(options),
+	}
+}
+
+func (sw *scheduleWorker) Start(ctx context.Context) error {
+	if !sw.options.ProcessSchedules {
+		return nil
+	}
+
+	var notifications func() <-chan struct{}
+	if n, ok := sw.backend.(backend.ScheduleNotifier); ok {
+		notifications = n.SchedulesNotification
+	}
+
+	newPoller(
+		1,
+		1,
+		newSchedulePollInterval(sw.options),
+		sw.processDueSchedules,
+		notifications,
+		sw.logger.With("task_type", "schedule"),
+	).Start(ctx)
+
+	return nil
+}
+
+func (sw *scheduleWorker) Stop() error {
+	return nil
+}
+
+// processDueSchedules processes the schedules that are currently due. It returns whether any of
+// them was processed, so that more due schedules are polled for right away.
+func (sw *scheduleWorker) processDueSchedules(ctx context.Context) (bool, error) {
+	schedules, err := sw.backend.GetDueSchedules(ctx, time.Now(), maxDueSchedules)
+	if err != nil {
+		return false, err
+	}
+
+	processed := false
+	for _, s := range schedules {
+		if err := sw.processSchedule(ctx, s); err != nil {
+			sw.logger.Error("could not process schedule", log.ScheduleIDKey, s.ID, log.ErrorKey, err)
+			continue
+		}
+
+		processed = true
+	}
+
+	return processed, nil
+}
+
+func (sw *scheduleWorker) processSchedule(ctx context.Context, s *schedule.Schedule) error {
+	running := false
+	if s.Running != nil {
+		var err error
+		if running, err = sw.isRunning(ctx, s.Running.Instance()); err != nil {
+			return err
+		}
+	}
+
+	cancel, changed, err := s.Process(time.Now(), running)
+	if err != nil {
+		return err
+	}
+
+	if !changed {
+		return nil
+	}
+
+	// Store the schedule before taking any action, so that runs are started by only one worker at
+	// a time even if multiple workers process the same schedule
+	if err := sw.backend.UpdateSchedule(ctx, s); err != nil {
+		if isConcurrentUpdate(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	for _, instance := range cancel {
+		if err := sw.backend.CancelWorkflowInstance(ctx, instance); err != nil {
+			sw.logger.Error("could not cancel scheduled workflow instance",
+				log.ScheduleIDKey, s.ID,
+				log.InstanceIDKey, instance.GetInstanceID(),
+				log.ExecutionIDKey, instance.GetExecutionID(),
+				log.ErrorKey, err)
+		}
+	}
+
+	started := false
+	for _, r := range append([]schedule.Run{}, s.PendingRuns...) {
+		logger := sw.logger.With(
+			log.ScheduleIDKey, s.ID,
+			log.InstanceIDKey, r.InstanceID,
+			log.ExecutionIDKey, r.ExecutionID,
+		)
+
+		// Runs stay pending until they are started, and are retried by the next worker processing
+		// the schedule
+		if err := sw.startRun(ctx, s, r); err != nil {
+			logger.Error("could not start scheduled workflow instance", log.ErrorKey, err)
+			continue
+		}
+
+		logger.Debug("Started scheduled workflow instance", log.WorkflowNameKey, s.WorkflowName)
+
+		s.Started(r, time.Now())
+		started = true
+	}
+
+	if started {
+		// Runs not recorded as started here are found to exist when they are retried
+		if err := sw.backend.UpdateSchedule(ctx, s); err != nil && !isConcurrentUpdate(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isConcurrentUpdate returns whether the schedule was modified or deleted since it was read
+func isConcurrentUpdate(err error) bool {
+	return errors.Is(err, backend.ErrScheduleModified) || errors.Is(err, backend.ErrScheduleNotFound)
+}
+
+// startRun creates the workflow instance of the given run, unless it was already created by an
+// earlier attempt
+func (sw *scheduleWorker) startRun(ctx context.Context, s *schedule.Schedule, r schedule.Run) error {
+	instance := core.NewWorkflowInstance(r.InstanceID, r.ExecutionID)
+
+	if _, err := sw.backend.DescribeWorkflowInstance(ctx, instance); err == nil {
+		return nil
+	} else if !errors.Is(err, backend.ErrInstanceNotFound) {
+		return err
+	}
+
+	// Every run owns the blobs of its inputs, which are deleted together with the instance
+	inputs, err := converter.CopyBlobs(ctx, sw.converter, s.Inputs)
+	if err != nil {
+		return errors.Wrap(err, "could not copy inputs")
+	}
+
+	return sw.backend.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: instance,
+		HistoryEvent: history.NewHistoryEvent(
+			time.Now(),
+			history.EventType_WorkflowExecutionStarted,
+			&history.ExecutionStartedAttributes{
+				Name:     s.WorkflowName,
+				Inputs:   inputs,
+				Priority: s.Spec.Priority,
+			},
+		),
+	})
+}
+
+// isRunning returns whether the given instance has not finished yet
+func (sw *scheduleWorker) isRunning(ctx context.Context, instance core.WorkflowInstance) (bool, error) {
+	info, err := sw.backend.DescribeWorkflowInstance(ctx, instance)
+	if err != nil {
+		if errors.Is(err, backend.ErrInstanceNotFound) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return info.State != backend.WorkflowInstanceStateFinished, nil
+}
@@ -2,15 +2,19 @@ package worker
 
 import (
 	"context"
-	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/internal/metrickeys"
 	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/cschleiden/go-workflows/internal/tracing"
 	"github.com/cschleiden/go-workflows/internal/workflow"
+	"github.com/cschleiden/go-workflows/metrics"
 	"github.com/pkg/errors"
 )
 
@@ -31,12 +35,17 @@ type workflowWorker struct {
 
 	workflowTaskQueue chan *task.Workflow
 
-	logger *log.Logger
+	logger log.Logger
 
 	wg *sync.WaitGroup
+
+	metrics    metrics.Client
+	inProgress int64
 }
 
 func NewWorkflowWorker(backend backend.Backend, registry *workflow.Registry, options *Options) WorkflowWorker {
+	mc := metricsClient(options)
+
 	return &workflowWorker{
 		backend: backend,
 
@@ -45,21 +54,33 @@ func NewWorkflowWorker(backend backend.Backend, registry *workflow.Registry, opt
 		registry:          registry,
 		workflowTaskQueue: make(chan *task.Workflow),
 
-		cache: workflow.NewWorkflowExecutorCache(workflow.DefaultWorkflowExecutorCacheOptions),
+		cache: workflow.NewWorkflowExecutorCache(workflow.DefaultWorkflowExecutorCacheOptions, mc),
 
-		logger: log.Default(),
+		logger: logger(options),
 
 		wg: &sync.WaitGroup{},
+
+		metrics: mc,
 	}
 }
 
 func (ww *workflowWorker) Start(ctx context.Context) error {
 	go ww.cache.StartEviction(ctx)
 
-	for i := 0; i <= ww.options.WorkflowPollers; i++ {
-		go ww.runPoll(ctx)
+	var notifications func() <-chan struct{}
+	if n, ok := ww.backend.(backend.Notifier); ok {
+		notifications = n.WorkflowTasksNotification
 	}
 
+	newPoller(
+		ww.options.WorkflowPollers,
+		ww.options.MaxWorkflowPollers,
+		newPollInterval(ww.options),
+		ww.pollTask,
+		notifications,
+		ww.logger.With("task_type", "workflow"),
+	).Start(ctx)
+
 	go ww.runDispatcher(ctx)
 
 	return nil
@@ -71,20 +92,19 @@ func (ww *workflowWorker) Stop() error {
 	return nil
 }
 
-func (ww *workflowWorker) runPoll(ctx context.Context) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			task, err := ww.poll(ctx, 30*time.Second)
-			if err != nil {
-				log.Println("error while polling for workflow task:", err)
-			} else if task != nil {
-				ww.workflowTaskQueue <- task
-			}
-		}
+// pollTask polls for a single workflow task and queues it for the dispatcher
+func (ww *workflowWorker) pollTask(ctx context.Context) (bool, error) {
+	task, err := ww.poll(ctx, 30*time.Second)
+	if err != nil || task == nil {
+		return false, err
+	}
+
+	select {
+	case ww.workflowTaskQueue <- task:
+	case <-ctx.Done():
 	}
+
+	return true, nil
 }
 
 func (ww *workflowWorker) runDispatcher(ctx context.Context) {
@@ -107,8 +127,12 @@ func (ww *workflowWorker) runDispatcher(ctx context.Context) {
 			go func() {
 				defer ww.wg.Done()
 
+				ww.metrics.Gauge(metrickeys.TasksInProgress, workflowTags, atomic.AddInt64(&ww.inProgress, 1))
+
 				ww.handle(ctx, t)
 
+				ww.metrics.Gauge(metrickeys.TasksInProgress, workflowTags, atomic.AddInt64(&ww.inProgress, -1))
+
 				if sem != nil {
 					<-sem
 				}
@@ -118,14 +142,37 @@ func (ww *workflowWorker) runDispatcher(ctx context.Context) {
 }
 
 func (ww *workflowWorker) handle(ctx context.Context, t *task.Workflow) {
+	start := time.Now()
+
+	logger := ww.logger.With(
+		log.InstanceIDKey, t.WorkflowInstance.GetInstanceID(),
+		log.ExecutionIDKey, t.WorkflowInstance.GetExecutionID(),
+	)
+
+	logger.Debug("Executing workflow task", "new_events", len(t.NewEvents), "continuation", t.Kind == task.Continuation)
+
 	executedEvents, workflowEvents, err := ww.handleTask(ctx, t)
 	if err != nil {
-		ww.logger.Panic(err)
+		ww.recordTask(start, err)
+		logger.Error("could not execute workflow task", log.ErrorKey, err)
+		panic(err)
 	}
 
 	if err := ww.backend.CompleteWorkflowTask(ctx, t.WorkflowInstance, executedEvents, workflowEvents); err != nil {
-		ww.logger.Panic(err)
+		ww.recordTask(start, err)
+		logger.Error("could not complete workflow task", log.ErrorKey, err)
+		panic(err)
 	}
+
+	ww.recordTask(start, nil)
+}
+
+func (ww *workflowWorker) recordTask(start time.Time, err error) {
+	ww.metrics.Distribution(metrickeys.TaskDuration, workflowTags, time.Since(start).Seconds())
+	ww.metrics.Counter(metrickeys.TasksProcessed, metrics.Tags{
+		metrickeys.Type:   metrickeys.TypeWorkflow,
+		metrickeys.Result: result(err),
+	}, 1)
 }
 
 func (ww *workflowWorker) handleTask(ctx context.Context, t *task.Workflow) ([]history.Event, []history.WorkflowEvent, error) {
@@ -162,14 +209,26 @@ func (ww *workflowWorker) getExecutor(ctx context.Context, t *task.Workflow) (wo
 		return executor, nil
 	}
 
-	executor, err := workflow.NewExecutor(ww.registry, t.WorkflowInstance, clock.New())
+	executor, err := workflow.NewExecutor(
+		ww.registry,
+		t.WorkflowInstance,
+		clock.New(),
+		workflow.WithInterceptors(ww.options.WorkflowInterceptors...),
+		workflow.WithTracer(tracing.Tracer(ww.options.TracerProvider)),
+		workflow.WithLogger(ww.logger),
+		workflow.WithConverter(payloadConverter(ww.options)),
+	)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not create workflow executor")
 	}
 
 	// Cache executor instance for future continuation tasks
 	if err := ww.cache.Store(ctx, t.WorkflowInstance, executor); err != nil {
-		ww.logger.Println("error while caching workflow task executor:", err)
+		ww.logger.Warning("error while caching workflow task executor",
+			log.InstanceIDKey, t.WorkflowInstance.GetInstanceID(),
+			log.ExecutionIDKey, t.WorkflowInstance.GetExecutionID(),
+			log.ErrorKey, err,
+		)
 	}
 
 	return executor, nil
@@ -185,7 +244,12 @@ func (ww *workflowWorker) heartbeatTask(ctx context.Context, task *task.Workflow
 			return
 		case <-t.C:
 			if err := ww.backend.ExtendWorkflowTask(ctx, task.WorkflowInstance); err != nil {
-				ww.logger.Panic(err)
+				ww.logger.Error("could not extend workflow task",
+					log.InstanceIDKey, task.WorkflowInstance.GetInstanceID(),
+					log.ExecutionIDKey, task.WorkflowInstance.GetExecutionID(),
+					log.ErrorKey, err,
+				)
+				panic(err)
 			}
 		}
 	}
@@ -204,6 +268,8 @@ func (ww *workflowWorker) poll(ctx context.Context, timeout time.Duration) (*tas
 	var task *task.Workflow
 	var err error
 
+	start := time.Now()
+
 	go func() {
 		task, err = ww.backend.GetWorkflowTask(ctx)
 		close(done)
@@ -211,9 +277,11 @@ func (ww *workflowWorker) poll(ctx context.Context, timeout time.Duration) (*tas
 
 	select {
 	case <-ctx.Done():
+		recordPoll(ww.metrics, metrickeys.TypeWorkflow, start, false, nil)
 		return nil, nil
 
 	case <-done:
+		recordPoll(ww.metrics, metrickeys.TypeWorkflow, start, task != nil, err)
 		return task, err
 	}
 }
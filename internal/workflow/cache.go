@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/metrickeys"
+	"github.com/cschleiden/go-workflows/metrics"
 )
 
 type WorkflowExecutorCache interface {
@@ -17,6 +19,7 @@ type WorkflowExecutorCache interface {
 
 type workflowExecutorCache struct {
 	options WorkflowExecutorCacheOptions
+	metrics metrics.Client
 	t       *time.Ticker
 	mu      *sync.Mutex
 	cache   map[string]*workflowExecutorCacheEntry
@@ -36,9 +39,10 @@ var DefaultWorkflowExecutorCacheOptions = WorkflowExecutorCacheOptions{
 	CacheDuration: 30 * time.Second,
 }
 
-func NewWorkflowExecutorCache(options WorkflowExecutorCacheOptions) WorkflowExecutorCache {
+func NewWorkflowExecutorCache(options WorkflowExecutorCacheOptions, mc metrics.Client) WorkflowExecutorCache {
 	c := workflowExecutorCache{
 		options: options,
+		metrics: mc,
 		t:       time.NewTicker(options.CacheDuration),
 		mu:      &sync.Mutex{},
 		cache:   make(map[string]*workflowExecutorCacheEntry),
@@ -56,6 +60,8 @@ func (c *workflowExecutorCache) Store(ctx context.Context, instance core.Workflo
 		lastAccess: time.Now(),
 	}
 
+	c.metrics.Gauge(metrickeys.CacheSize, nil, int64(len(c.cache)))
+
 	return nil
 }
 
@@ -65,9 +71,12 @@ func (c *workflowExecutorCache) Get(ctx context.Context, instance core.WorkflowI
 
 	if entry, ok := c.cache[getKey(instance)]; ok {
 		entry.lastAccess = time.Now()
+		c.metrics.Counter(metrickeys.CacheHits, nil, 1)
 		return entry.executor, true, nil
 	}
 
+	c.metrics.Counter(metrickeys.CacheMisses, nil, 1)
+
 	return nil, false, nil
 }
 
@@ -85,9 +94,12 @@ func (c *workflowExecutorCache) StartEviction(ctx context.Context) {
 					entry.executor.Close()
 
 					delete(c.cache, instance)
+					c.metrics.Counter(metrickeys.CacheEvictions, nil, 1)
 				}
 			}
 
+			c.metrics.Gauge(metrickeys.CacheSize, nil, int64(len(c.cache)))
+
 			c.mu.Unlock()
 
 		case <-ctx.Done():
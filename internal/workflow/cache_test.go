@@ -8,11 +8,13 @@ import (
 
 	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/metrickeys"
+	"github.com/cschleiden/go-workflows/metrics"
 	"github.com/stretchr/testify/require"
 )
 
 func Test_Cache_StoreAndGet(t *testing.T) {
-	c := NewWorkflowExecutorCache(DefaultWorkflowExecutorCacheOptions)
+	c := NewWorkflowExecutorCache(DefaultWorkflowExecutorCacheOptions, metrics.NewNoopMetricsClient())
 
 	i := core.NewWorkflowInstance("instanceID", "executionID")
 
@@ -34,7 +36,7 @@ func Test_Cache_StoreAndGet(t *testing.T) {
 func Test_Cache_Evic(t *testing.T) {
 	c := NewWorkflowExecutorCache(WorkflowExecutorCacheOptions{
 		CacheDuration: 1, // Should evict immediately
-	})
+	}, metrics.NewNoopMetricsClient())
 
 	i := core.NewWorkflowInstance("instanceID", "executionID")
 	r := NewRegistry()
@@ -54,3 +56,45 @@ func Test_Cache_Evic(t *testing.T) {
 	require.False(t, ok)
 	require.Nil(t, e2)
 }
+
+type countingMetrics struct {
+	metrics.Client
+
+	counters map[string]int64
+	gauges   map[string]int64
+}
+
+func (m *countingMetrics) Counter(name string, tags metrics.Tags, value int64) {
+	m.counters[name] += value
+}
+
+func (m *countingMetrics) Gauge(name string, tags metrics.Tags, value int64) {
+	m.gauges[name] = value
+}
+
+func Test_Cache_Metrics(t *testing.T) {
+	m := &countingMetrics{
+		Client:   metrics.NewNoopMetricsClient(),
+		counters: map[string]int64{},
+		gauges:   map[string]int64{},
+	}
+	c := NewWorkflowExecutorCache(DefaultWorkflowExecutorCacheOptions, m)
+
+	i := core.NewWorkflowInstance("instanceID", "executionID")
+	r := NewRegistry()
+	r.RegisterWorkflow(workflowWithActivity)
+	e, err := NewExecutor(r, i, clock.New())
+	require.NoError(t, err)
+
+	_, _, err = c.Get(context.Background(), i)
+	require.NoError(t, err)
+
+	require.NoError(t, c.Store(context.Background(), i, e))
+
+	_, _, err = c.Get(context.Background(), i)
+	require.NoError(t, err)
+
+	require.Equal(t, int64(1), m.gauges[metrickeys.CacheSize])
+	require.Equal(t, int64(1), m.counters[metrickeys.CacheHits])
+	require.Equal(t, int64(1), m.counters[metrickeys.CacheMisses])
+}
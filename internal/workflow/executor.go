@@ -4,20 +4,25 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
-	"log"
 	"reflect"
 
 	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/internal/command"
+	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/log"
 	"github.com/cschleiden/go-workflows/internal/payload"
 	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/cschleiden/go-workflows/internal/tracing"
 	"github.com/cschleiden/go-workflows/internal/workflowstate"
+	wf "github.com/cschleiden/go-workflows/workflow"
 	"github.com/google/uuid"
 	errs "github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type WorkflowExecutor interface {
@@ -33,13 +38,70 @@ type executor struct {
 	workflowCtx       sync.Context
 	workflowCtxCancel sync.CancelFunc
 	clock             clock.Clock
-	logger            *log.Logger
+	tracer            trace.Tracer
+	logger            log.Logger
 	lastEventID       string // TODO: Not the same as the sequence number Event ID
+	workflowName      string
+
+	// metadata of the workflow instance, recorded when the workflow is started
+	metadata map[string]string
+}
+
+type ExecutorOption func(*executorOptions)
+
+type executorOptions struct {
+	interceptors []wf.Interceptor
+	tracer       trace.Tracer
+	logger       log.Logger
+	converter    converter.Converter
+}
+
+// WithInterceptors registers interceptors for all workflows executed by the executor
+func WithInterceptors(interceptors ...wf.Interceptor) ExecutorOption {
+	return func(o *executorOptions) {
+		o.interceptors = append(o.interceptors, interceptors...)
+	}
+}
+
+// WithTracer sets the tracer used to record spans for workflow tasks and scheduled sub-workflows
+func WithTracer(tracer trace.Tracer) ExecutorOption {
+	return func(o *executorOptions) {
+		o.tracer = tracer
+	}
 }
 
-func NewExecutor(registry *Registry, instance core.WorkflowInstance, clock clock.Clock) (WorkflowExecutor, error) {
-	s := workflowstate.NewWorkflowState(instance, clock)
-	wfCtx, cancel := sync.WithCancel(workflowstate.WithWorkflowState(sync.Background(), s))
+// WithLogger sets the logger used by the executor and returned by workflow.Logger
+func WithLogger(logger log.Logger) ExecutorOption {
+	return func(o *executorOptions) {
+		o.logger = logger
+	}
+}
+
+// WithConverter sets the converter used for payloads produced and consumed by workflows
+func WithConverter(c converter.Converter) ExecutorOption {
+	return func(o *executorOptions) {
+		o.converter = c
+	}
+}
+
+func NewExecutor(registry *Registry, instance core.WorkflowInstance, clock clock.Clock, opts ...ExecutorOption) (WorkflowExecutor, error) {
+	options := &executorOptions{
+		tracer: tracing.Tracer(nil),
+		logger: log.NewDefaultLogger(),
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	s := workflowstate.NewWorkflowState(instance, options.logger, clock)
+	wfCtx := workflowstate.WithWorkflowState(sync.Background(), s)
+	if len(options.interceptors) > 0 {
+		wfCtx = wf.WithInterceptors(wfCtx, options.interceptors...)
+	}
+	if options.converter != nil {
+		wfCtx = sync.WithConverter(wfCtx, options.converter)
+	}
+	wfCtx, cancel := sync.WithCancel(wfCtx)
 
 	return &executor{
 		registry:          registry,
@@ -47,12 +109,39 @@ func NewExecutor(registry *Registry, instance core.WorkflowInstance, clock clock
 		workflowCtx:       wfCtx,
 		workflowCtxCancel: cancel,
 		clock:             clock,
-		logger:            log.New(io.Discard, "", log.LstdFlags),
-		//logger: log.Default(),
+		tracer:            options.tracer,
+		logger: options.logger.With(
+			log.InstanceIDKey, instance.GetInstanceID(),
+			log.ExecutionIDKey, instance.GetExecutionID(),
+		),
 	}, nil
 }
 
 func (e *executor) ExecuteTask(ctx context.Context, t *task.Workflow) ([]history.Event, []history.WorkflowEvent, error) {
+	if e.metadata == nil {
+		e.metadata = workflowMetadata(t)
+	}
+
+	// Record a span for every task. Tasks are only executed once, so replaying the history does
+	// not create duplicate spans.
+	ctx, span := e.tracer.Start(tracing.Extract(ctx, e.metadata), "WorkflowTask", trace.WithAttributes(
+		attribute.String(tracing.AttributeInstanceID, t.WorkflowInstance.GetInstanceID()),
+		attribute.String(tracing.AttributeExecutionID, t.WorkflowInstance.GetExecutionID()),
+		attribute.Bool(tracing.AttributeContinuation, t.Kind == task.Continuation),
+		attribute.Int(tracing.AttributeReplayedEvents, len(t.History)),
+	))
+	defer span.End()
+
+	events, workflowEvents, err := e.executeTask(ctx, t)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return events, workflowEvents, err
+}
+
+func (e *executor) executeTask(ctx context.Context, t *task.Workflow) ([]history.Event, []history.WorkflowEvent, error) {
 	if t.Kind == task.Continuation {
 		// Check if the current state matches the backend's history state
 		newestHistoryEvent := t.History[len(t.History)-1]
@@ -64,11 +153,8 @@ func (e *executor) ExecuteTask(ctx context.Context, t *task.Workflow) ([]history
 		e.workflowState.ClearCommands()
 	} else {
 		// Replay history
-		e.workflowState.SetReplaying(true)
-		for _, event := range t.History {
-			if err := e.executeEvent(event); err != nil {
-				return nil, nil, errs.Wrap(err, "error while replaying event")
-			}
+		if err := e.replay(t.History); err != nil {
+			return nil, nil, err
 		}
 	}
 
@@ -124,7 +210,11 @@ func (e *executor) Close() {
 }
 
 func (e *executor) executeEvent(event history.Event) error {
-	e.logger.Println("Handling:", event.Type)
+	e.logger.Debug("Handling event",
+		log.EventIDKey, event.ID,
+		log.EventTypeKey, event.Type,
+		log.ScheduleEventIDKey, event.ScheduleEventID,
+	)
 
 	var err error
 
@@ -133,7 +223,7 @@ func (e *executor) executeEvent(event history.Event) error {
 		err = e.handleWorkflowExecutionStarted(event.Attributes.(*history.ExecutionStartedAttributes))
 
 	case history.EventType_WorkflowExecutionFinished:
-	// Ignore
+		err = e.handleWorkflowExecutionFinished()
 
 	case history.EventType_WorkflowExecutionCanceled:
 		err = e.handleWorkflowCanceled()
@@ -188,8 +278,18 @@ func (e *executor) handleWorkflowExecutionStarted(a *history.ExecutionStartedAtt
 	}
 
 	e.workflow = NewWorkflow(reflect.ValueOf(wfFn))
+	e.workflowName = a.Name
 
-	return e.workflow.Execute(e.workflowCtx, a.Inputs)
+	return e.workflow.Execute(e.workflowCtx, a.Name, a.Inputs)
+}
+
+func (e *executor) handleWorkflowExecutionFinished() error {
+	// The workflow has to complete during the same task in which its completion was recorded
+	if !e.workflow.Completed() {
+		return &NonDeterminismError{Field: "completed", Expected: true, Actual: false}
+	}
+
+	return nil
 }
 
 func (e *executor) handleWorkflowCanceled() error {
@@ -204,14 +304,38 @@ func (e *executor) handleWorkflowTaskStarted(event history.Event, a *history.Wor
 	return nil
 }
 
-func (e *executor) handleActivityScheduled(event history.Event, a *history.ActivityScheduledAttributes) error {
+// matchCommand removes the command for a replayed schedule event from the workflow state. The
+// workflow has to have generated a command of the given type with the event's schedule event id.
+func (e *executor) matchCommand(event history.Event, commandType command.CommandType) (*command.Command, error) {
 	c := e.workflowState.RemoveCommandByEventID(event.ScheduleEventID)
-	if c != nil {
-		// Ensure the same activity is scheduled again
-		ca := c.Attr.(*command.ScheduleActivityTaskCommandAttr)
-		if a.Name != ca.Name {
-			return fmt.Errorf("previous workflow execution scheduled different type of activity: %s, %s", a.Name, ca.Name)
-		}
+	if c == nil {
+		return nil, &NonDeterminismError{Field: "command", Expected: commandType, Actual: "none"}
+	}
+
+	if c.Type != commandType {
+		return nil, &NonDeterminismError{Field: "command", Expected: commandType, Actual: c.Type}
+	}
+
+	// The command was committed in a previous execution
+	c.State = command.CommandState_Committed
+
+	return c, nil
+}
+
+func (e *executor) handleActivityScheduled(event history.Event, a *history.ActivityScheduledAttributes) error {
+	c, err := e.matchCommand(event, command.CommandType_ScheduleActivityTask)
+	if err != nil {
+		return err
+	}
+
+	// Ensure the same activity is scheduled again
+	ca := c.Attr.(*command.ScheduleActivityTaskCommandAttr)
+	if a.Name != ca.Name {
+		return &NonDeterminismError{Field: "name", Expected: a.Name, Actual: ca.Name}
+	}
+
+	if len(a.Inputs) != len(ca.Inputs) {
+		return &NonDeterminismError{Field: "inputs", Expected: len(a.Inputs), Actual: len(ca.Inputs)}
 	}
 
 	return nil
@@ -243,7 +367,16 @@ func (e *executor) handleActivityFailed(event history.Event, a *history.Activity
 }
 
 func (e *executor) handleTimerScheduled(event history.Event, a *history.TimerScheduledAttributes) error {
-	e.workflowState.RemoveCommandByEventID(event.ScheduleEventID)
+	c, err := e.matchCommand(event, command.CommandType_ScheduleTimer)
+	if err != nil {
+		return err
+	}
+
+	// Events recorded by older versions don't contain the delay
+	ca := c.Attr.(*command.ScheduleTimerCommandAttr)
+	if a.Delay != 0 && a.Delay != ca.Delay {
+		return &NonDeterminismError{Field: "delay", Expected: a.Delay, Actual: ca.Delay}
+	}
 
 	return nil
 }
@@ -263,12 +396,18 @@ func (e *executor) handleTimerFired(event history.Event, a *history.TimerFiredAt
 }
 
 func (e *executor) handleSubWorkflowScheduled(event history.Event, a *history.SubWorkflowScheduledAttributes) error {
-	c := e.workflowState.RemoveCommandByEventID(event.ScheduleEventID)
-	if c != nil {
-		ca := c.Attr.(*command.ScheduleSubWorkflowCommandAttr)
-		if a.Name != ca.Name {
-			return errors.New("previous workflow execution scheduled a different sub workflow")
-		}
+	c, err := e.matchCommand(event, command.CommandType_ScheduleSubWorkflow)
+	if err != nil {
+		return err
+	}
+
+	ca := c.Attr.(*command.ScheduleSubWorkflowCommandAttr)
+	if a.Name != ca.Name {
+		return &NonDeterminismError{Field: "name", Expected: a.Name, Actual: ca.Name}
+	}
+
+	if len(a.Inputs) != len(ca.Inputs) {
+		return &NonDeterminismError{Field: "inputs", Expected: len(a.Inputs), Actual: len(ca.Inputs)}
 	}
 
 	return nil
@@ -311,9 +450,15 @@ func (e *executor) handleSignalReceived(event history.Event, a *history.SignalRe
 }
 
 func (e *executor) handleSideEffectResult(event history.Event, a *history.SideEffectResultAttributes) error {
+	// Side effects don't generate commands during replay, any command means the workflow did
+	// something else at this point
+	if c := e.workflowState.RemoveCommandByEventID(event.ScheduleEventID); c != nil {
+		return &NonDeterminismError{Field: "command", Expected: command.CommandType_SideEffect, Actual: c.Type}
+	}
+
 	f, ok := e.workflowState.FutureByScheduleEventID(event.ScheduleEventID)
 	if !ok {
-		return errors.New("no pending future found for side effect result event")
+		return &NonDeterminismError{Field: "command", Expected: command.CommandType_SideEffect, Actual: "none"}
 	}
 
 	f.Set(a.Result, nil)
@@ -350,8 +495,10 @@ func (e *executor) processCommands(ctx context.Context, t *task.Workflow) ([]his
 				e.clock.Now(),
 				history.EventType_ActivityScheduled,
 				&history.ActivityScheduledAttributes{
-					Name:   a.Name,
-					Inputs: a.Inputs,
+					Name:     a.Name,
+					Inputs:   a.Inputs,
+					Metadata: tracing.Inject(ctx),
+					Priority: a.Priority,
 				},
 				history.ScheduleEventID(c.ID),
 			))
@@ -361,6 +508,15 @@ func (e *executor) processCommands(ctx context.Context, t *task.Workflow) ([]his
 
 			subWorkflowInstance := core.NewSubWorkflowInstance(a.InstanceID, uuid.NewString(), instance, c.ID)
 
+			// Record the creation of the sub-workflow, its tasks will be children of this span
+			_, span := e.tracer.Start(ctx, "CreateSubWorkflowInstance", trace.WithAttributes(
+				attribute.String(tracing.AttributeInstanceID, subWorkflowInstance.GetInstanceID()),
+				attribute.String(tracing.AttributeExecutionID, subWorkflowInstance.GetExecutionID()),
+				attribute.String(tracing.AttributeWorkflowName, a.Name),
+			))
+			metadata := tracing.Inject(trace.ContextWithSpan(ctx, span))
+			span.End()
+
 			newEvents = append(newEvents, history.NewHistoryEvent(
 				e.clock.Now(),
 				history.EventType_SubWorkflowScheduled,
@@ -368,6 +524,7 @@ func (e *executor) processCommands(ctx context.Context, t *task.Workflow) ([]his
 					InstanceID: subWorkflowInstance.GetInstanceID(),
 					Name:       a.Name,
 					Inputs:     a.Inputs,
+					Metadata:   metadata,
 				},
 				history.ScheduleEventID(c.ID),
 			))
@@ -379,8 +536,10 @@ func (e *executor) processCommands(ctx context.Context, t *task.Workflow) ([]his
 					e.clock.Now(),
 					history.EventType_WorkflowExecutionStarted,
 					&history.ExecutionStartedAttributes{
-						Name:   a.Name,
-						Inputs: a.Inputs,
+						Name:     a.Name,
+						Inputs:   a.Inputs,
+						Metadata: metadata,
+						Priority: a.Priority,
 					},
 					history.ScheduleEventID(c.ID),
 				),
@@ -404,7 +563,8 @@ func (e *executor) processCommands(ctx context.Context, t *task.Workflow) ([]his
 				e.clock.Now(),
 				history.EventType_TimerScheduled,
 				&history.TimerScheduledAttributes{
-					At: a.At,
+					At:    a.At,
+					Delay: a.Delay,
 				},
 				history.ScheduleEventID(c.ID),
 			))
@@ -476,3 +636,16 @@ func (e *executor) processCommands(ctx context.Context, t *task.Workflow) ([]his
 
 	return newEvents, workflowEvents, nil
 }
+
+// workflowMetadata returns the metadata the workflow instance was started with
+func workflowMetadata(t *task.Workflow) map[string]string {
+	for _, events := range [][]history.Event{t.History, t.NewEvents} {
+		for _, event := range events {
+			if event.Type == history.EventType_WorkflowExecutionStarted {
+				return event.Attributes.(*history.ExecutionStartedAttributes).Metadata
+			}
+		}
+	}
+
+	return nil
+}
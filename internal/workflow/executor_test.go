@@ -3,26 +3,31 @@ package workflow
 import (
 	"context"
 	"fmt"
-	"log"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/blob"
 	"github.com/cschleiden/go-workflows/internal/command"
 	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/log"
 	"github.com/cschleiden/go-workflows/internal/payload"
 	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/cschleiden/go-workflows/internal/tracing"
 	"github.com/cschleiden/go-workflows/internal/workflowstate"
 	wf "github.com/cschleiden/go-workflows/workflow"
 	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func newExecutor(r *Registry, i core.WorkflowInstance) *executor {
-	s := workflowstate.NewWorkflowState(i, clock.New())
+	s := workflowstate.NewWorkflowState(i, log.NewNoopLogger(), clock.New())
 	wfCtx, cancel := sync.WithCancel(workflowstate.WithWorkflowState(sync.Background(), s))
 
 	return &executor{
@@ -31,8 +36,9 @@ func newExecutor(r *Registry, i core.WorkflowInstance) *executor {
 		workflowState:     s,
 		workflowCtx:       wfCtx,
 		workflowCtxCancel: cancel,
-		logger:            log.Default(),
+		logger:            log.NewNoopLogger(),
 		clock:             clock.New(),
+		tracer:            tracing.Tracer(nil),
 	}
 }
 
@@ -413,3 +419,336 @@ func Test_ExecuteWorkflowWithSignal(t *testing.T) {
 	require.True(t, e.workflow.Completed())
 	require.Len(t, e.workflowState.Commands(), 1)
 }
+
+type recordingInterceptor struct {
+	wf.NoopInterceptor
+
+	calls []string
+}
+
+func (i *recordingInterceptor) ExecuteWorkflow(ctx wf.Context, name string, args []interface{}, next wf.ExecuteWorkflowFunc) (interface{}, error) {
+	i.calls = append(i.calls, "workflow:"+name)
+
+	return next(ctx, args)
+}
+
+func (i *recordingInterceptor) ExecuteActivity(ctx wf.Context, options wf.ActivityOptions, activity wf.Activity, args []interface{}, next wf.ExecuteActivityFunc) wf.Future {
+	i.calls = append(i.calls, fmt.Sprintf("activity:%v", args))
+
+	return next(ctx, options, activity, []interface{}{23})
+}
+
+func Test_ExecuteWorkflowWithInterceptors(t *testing.T) {
+	r := NewRegistry()
+
+	workflowActivityHit = 0
+
+	r.RegisterWorkflow(workflowWithActivity)
+	r.RegisterActivity(activity1)
+
+	task := &task.Workflow{
+		WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+		History: []history.Event{
+			history.NewHistoryEvent(
+				time.Now(),
+				history.EventType_WorkflowExecutionStarted,
+				&history.ExecutionStartedAttributes{
+					Name:   "workflowWithActivity",
+					Inputs: []payload.Payload{},
+				},
+			),
+		},
+	}
+
+	i := &recordingInterceptor{}
+
+	e, err := NewExecutor(r, task.WorkflowInstance, clock.New(), WithInterceptors(i))
+	require.NoError(t, err)
+
+	_, _, err = e.ExecuteTask(context.Background(), task)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"workflow:workflowWithActivity", "activity:[42]"}, i.calls)
+
+	// Arguments modified by the interceptor are used for the scheduled activity
+	inputs, _ := converter.DefaultConverter.To(23)
+	commands := e.(*executor).workflowState.Commands()
+	require.Len(t, commands, 1)
+	require.Equal(t, []payload.Payload{inputs}, commands[0].Attr.(*command.ScheduleActivityTaskCommandAttr).Inputs)
+}
+
+func Test_ExecuteWorkflow_Tracing(t *testing.T) {
+	r := NewRegistry()
+
+	r.RegisterWorkflow(workflowWithActivity)
+	r.RegisterActivity(activity1)
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	// Simulate the span of the client creating the workflow instance
+	ctx, parentSpan := tp.Tracer("test").Start(context.Background(), "CreateWorkflowInstance")
+	metadata := tracing.Inject(ctx)
+	parentSpan.End()
+
+	instance := core.NewWorkflowInstance("instanceID", "executionID")
+	startedEvent := history.NewHistoryEvent(
+		time.Now(),
+		history.EventType_WorkflowExecutionStarted,
+		&history.ExecutionStartedAttributes{
+			Name:     "workflowWithActivity",
+			Inputs:   []payload.Payload{},
+			Metadata: metadata,
+		},
+	)
+
+	e, err := NewExecutor(r, instance, clock.New(), WithTracer(tracing.Tracer(tp)))
+	require.NoError(t, err)
+
+	executedEvents, _, err := e.ExecuteTask(context.Background(), &task.Workflow{
+		WorkflowInstance: instance,
+		NewEvents:        []history.Event{startedEvent},
+	})
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	taskSpan := spans[1]
+	require.Equal(t, "WorkflowTask", taskSpan.Name)
+	require.Equal(t, parentSpan.SpanContext().TraceID(), taskSpan.SpanContext.TraceID())
+	require.Equal(t, parentSpan.SpanContext().SpanID(), taskSpan.Parent.SpanID())
+
+	// Scheduled activity carries the trace context of the workflow task
+	var activityScheduled *history.ActivityScheduledAttributes
+	for _, event := range executedEvents {
+		if event.Type == history.EventType_ActivityScheduled {
+			activityScheduled = event.Attributes.(*history.ActivityScheduledAttributes)
+		}
+	}
+	require.NotNil(t, activityScheduled)
+	require.Contains(t, activityScheduled.Metadata["traceparent"], taskSpan.SpanContext.SpanID().String())
+
+	// Replaying the history on a new executor records only a single span for the new task
+	exporter.Reset()
+
+	result, _ := converter.DefaultConverter.To(42)
+	e2, err := NewExecutor(r, instance, clock.New(), WithTracer(tracing.Tracer(tp)))
+	require.NoError(t, err)
+
+	_, _, err = e2.ExecuteTask(context.Background(), &task.Workflow{
+		WorkflowInstance: instance,
+		History:          executedEvents,
+		NewEvents: []history.Event{
+			history.NewHistoryEvent(
+				time.Now(),
+				history.EventType_ActivityCompleted,
+				&history.ActivityCompletedAttributes{Result: result},
+				history.ScheduleEventID(1),
+			),
+		},
+	})
+	require.NoError(t, err)
+
+	spans = exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, "WorkflowTask", spans[0].Name)
+	require.Equal(t, parentSpan.SpanContext().TraceID(), spans[0].SpanContext.TraceID())
+}
+
+func workflowWithActivityPriority(ctx sync.Context) error {
+	var r int
+	return wf.ExecuteActivity(ctx, wf.ActivityOptions{Priority: 5}, activity1, 42).Get(ctx, &r)
+}
+
+func Test_ExecuteWorkflow_ActivityPriority(t *testing.T) {
+	r := NewRegistry()
+
+	r.RegisterWorkflow(workflowWithActivityPriority)
+	r.RegisterActivity(activity1)
+
+	instance := core.NewWorkflowInstance("instanceID", "executionID")
+	e := newExecutor(r, instance)
+
+	executedEvents, _, err := e.ExecuteTask(context.Background(), &task.Workflow{
+		WorkflowInstance: instance,
+		NewEvents: []history.Event{
+			history.NewHistoryEvent(
+				time.Now(),
+				history.EventType_WorkflowExecutionStarted,
+				&history.ExecutionStartedAttributes{
+					Name:   "workflowWithActivityPriority",
+					Inputs: []payload.Payload{},
+				},
+			),
+		},
+	})
+	require.NoError(t, err)
+
+	var activityScheduled *history.ActivityScheduledAttributes
+	for _, event := range executedEvents {
+		if event.Type == history.EventType_ActivityScheduled {
+			activityScheduled = event.Attributes.(*history.ActivityScheduledAttributes)
+		}
+	}
+	require.NotNil(t, activityScheduled)
+	require.Equal(t, 5, activityScheduled.Priority)
+}
+
+type recordingLogger struct {
+	log.Logger
+
+	messages []string
+}
+
+func (l *recordingLogger) Info(msg string, fields ...interface{}) {
+	l.messages = append(l.messages, msg)
+}
+
+func (l *recordingLogger) With(fields ...interface{}) log.Logger {
+	return l
+}
+
+func workflowWithLogger(ctx sync.Context) error {
+	wf.Logger(ctx).Info("before activity")
+
+	var r int
+	if err := wf.ExecuteActivity(ctx, wf.DefaultActivityOptions, activity1, 42).Get(ctx, &r); err != nil {
+		return err
+	}
+
+	wf.Logger(ctx).Info("after activity")
+
+	return nil
+}
+
+func Test_ExecuteWorkflow_LoggerDuringReplay(t *testing.T) {
+	r := NewRegistry()
+
+	r.RegisterWorkflow(workflowWithLogger)
+	r.RegisterActivity(activity1)
+
+	instance := core.NewWorkflowInstance("instanceID", "executionID")
+	startedEvent := history.NewHistoryEvent(
+		time.Now(),
+		history.EventType_WorkflowExecutionStarted,
+		&history.ExecutionStartedAttributes{
+			Name:   "workflowWithLogger",
+			Inputs: []payload.Payload{},
+		},
+	)
+
+	logger := &recordingLogger{Logger: log.NewNoopLogger()}
+
+	e, err := NewExecutor(r, instance, clock.New(), WithLogger(logger))
+	require.NoError(t, err)
+
+	executedEvents, _, err := e.ExecuteTask(context.Background(), &task.Workflow{
+		WorkflowInstance: instance,
+		NewEvents:        []history.Event{startedEvent},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"before activity"}, logger.messages)
+
+	// Replaying the history does not log the first message again
+	result, _ := converter.DefaultConverter.To(42)
+	e2, err := NewExecutor(r, instance, clock.New(), WithLogger(logger))
+	require.NoError(t, err)
+
+	_, _, err = e2.ExecuteTask(context.Background(), &task.Workflow{
+		WorkflowInstance: instance,
+		History:          executedEvents,
+		NewEvents: []history.Event{
+			history.NewHistoryEvent(
+				time.Now(),
+				history.EventType_ActivityCompleted,
+				&history.ActivityCompletedAttributes{Result: result},
+				history.ScheduleEventID(1),
+			),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"before activity", "after activity"}, logger.messages)
+}
+
+type countingBlobStore struct {
+	blobs map[string][]byte
+}
+
+func (s *countingBlobStore) Put(ctx context.Context, key string, data []byte) error {
+	s.blobs[key] = data
+	return nil
+}
+
+func (s *countingBlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, ok := s.blobs[key]
+	if !ok {
+		return nil, blob.ErrNotFound
+	}
+
+	return data, nil
+}
+
+func (s *countingBlobStore) Delete(ctx context.Context, key string) error {
+	delete(s.blobs, key)
+	return nil
+}
+
+func activityWithInput(ctx context.Context, s string) (int, error) {
+	return len(s), nil
+}
+
+func workflowWithLargeInput(ctx sync.Context) error {
+	var r int
+	return wf.ExecuteActivity(ctx, wf.DefaultActivityOptions, activityWithInput, strings.Repeat("a", 1024)).Get(ctx, &r)
+}
+
+func Test_ExecuteWorkflow_DoesNotOffloadDuringReplay(t *testing.T) {
+	r := NewRegistry()
+
+	r.RegisterWorkflow(workflowWithLargeInput)
+	r.RegisterActivity(activityWithInput)
+
+	store := &countingBlobStore{blobs: map[string][]byte{}}
+	conv := converter.NewCodecConverter(converter.DefaultConverter, converter.NewOffloadCodec(store, 512))
+
+	instance := core.NewWorkflowInstance("instanceID", "executionID")
+	startedEvent := history.NewHistoryEvent(
+		time.Now(),
+		history.EventType_WorkflowExecutionStarted,
+		&history.ExecutionStartedAttributes{
+			Name:   "workflowWithLargeInput",
+			Inputs: []payload.Payload{},
+		},
+	)
+
+	e, err := NewExecutor(r, instance, clock.New(), WithConverter(conv))
+	require.NoError(t, err)
+
+	executedEvents, _, err := e.ExecuteTask(context.Background(), &task.Workflow{
+		WorkflowInstance: instance,
+		NewEvents:        []history.Event{startedEvent},
+	})
+	require.NoError(t, err)
+	require.Len(t, store.blobs, 1)
+
+	// Replaying the history in a new executor schedules the activity again, its input is not stored again
+	result, _ := conv.To(1024)
+	e2, err := NewExecutor(r, instance, clock.New(), WithConverter(conv))
+	require.NoError(t, err)
+
+	executedEvents, _, err = e2.ExecuteTask(context.Background(), &task.Workflow{
+		WorkflowInstance: instance,
+		History:          executedEvents,
+		NewEvents: []history.Event{
+			history.NewHistoryEvent(
+				time.Now(),
+				history.EventType_ActivityCompleted,
+				&history.ActivityCompletedAttributes{Result: result},
+				history.ScheduleEventID(1),
+			),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, history.EventType_WorkflowExecutionFinished, executedEvents[len(executedEvents)-2].Type)
+	require.Len(t, store.blobs, 1)
+}
@@ -0,0 +1,173 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/internal/command"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/task"
+)
+
+// ReplayError is returned when a recorded history can't be replayed with the registered
+// workflows, for example because the workflow code has changed in a non-deterministic way.
+type ReplayError struct {
+	// Index is the position of the offending event in the history
+	Index int
+
+	// Event is the history event that could not be replayed
+	Event history.Event
+
+	Err error
+}
+
+func (e *ReplayError) Error() string {
+	return fmt.Sprintf("replaying event %d (%v, schedule event id %d) failed: %v", e.Index, e.Event.Type, e.Event.ScheduleEventID, e.Err)
+}
+
+func (e *ReplayError) Unwrap() error {
+	return e.Err
+}
+
+// NonDeterminismError is returned when the workflow doesn't generate the same commands as
+// recorded in its history, usually because the workflow code has changed.
+type NonDeterminismError struct {
+	WorkflowName string
+
+	// Index is the position of the recorded event in the history. It's equal to the length of the
+	// history if the workflow generated a command after the last recorded event.
+	Index int
+
+	// EventType and ScheduleEventID identify the recorded event, or the generated command if
+	// there is no recorded event.
+	EventType       history.EventType
+	ScheduleEventID int
+
+	// Field is the part of the event that differs, e.g. "command", "name", "inputs", or "delay"
+	Field string
+
+	// Expected is the recorded value
+	Expected interface{}
+
+	// Actual is the value generated by the workflow
+	Actual interface{}
+}
+
+func (e *NonDeterminismError) Error() string {
+	return fmt.Sprintf("non-deterministic workflow %v: event %d (%v, schedule event id %d) recorded %v %v, workflow generated %v",
+		e.WorkflowName, e.Index, e.EventType, e.ScheduleEventID, e.Field, e.Expected, e.Actual)
+}
+
+func (e *executor) replay(events []history.Event) error {
+	e.workflowState.SetReplaying(true)
+
+	finished := false
+
+	for i, event := range events {
+		if e.workflow == nil && event.Type != history.EventType_WorkflowTaskStarted && event.Type != history.EventType_WorkflowExecutionStarted {
+			return &ReplayError{Index: i, Event: event, Err: errors.New("workflow has not been started")}
+		}
+
+		if err := e.executeEvent(event); err != nil {
+			var nonDeterminismErr *NonDeterminismError
+			if errors.As(err, &nonDeterminismErr) {
+				nonDeterminismErr.WorkflowName = e.workflowName
+				nonDeterminismErr.Index = i
+				nonDeterminismErr.EventType = event.Type
+				nonDeterminismErr.ScheduleEventID = event.ScheduleEventID
+				return nonDeterminismErr
+			}
+
+			return &ReplayError{Index: i, Event: event, Err: err}
+		}
+
+		if event.Type == history.EventType_WorkflowExecutionFinished {
+			finished = true
+		}
+	}
+
+	// Histories of complete workflow tasks contain events for all generated commands, and the
+	// completion of the workflow if it completed while replaying.
+	if len(events) == 0 || events[len(events)-1].Type != history.EventType_WorkflowTaskFinished {
+		return nil
+	}
+
+	for _, c := range e.workflowState.Commands() {
+		if c.State == command.CommandState_Pending {
+			return &NonDeterminismError{
+				WorkflowName:    e.workflowName,
+				Index:           len(events),
+				EventType:       commandEventType(c.Type),
+				ScheduleEventID: c.ID,
+				Field:           "command",
+				Expected:        "none",
+				Actual:          describeCommand(c),
+			}
+		}
+	}
+
+	if e.workflow != nil && e.workflow.Completed() && !finished {
+		return &NonDeterminismError{
+			WorkflowName: e.workflowName,
+			Index:        len(events),
+			EventType:    history.EventType_WorkflowExecutionFinished,
+			Field:        "completed",
+			Expected:     false,
+			Actual:       true,
+		}
+	}
+
+	return nil
+}
+
+// commandEventType returns the type of the event recorded for a command
+func commandEventType(ct command.CommandType) history.EventType {
+	switch ct {
+	case command.CommandType_ScheduleActivityTask:
+		return history.EventType_ActivityScheduled
+	case command.CommandType_ScheduleSubWorkflow:
+		return history.EventType_SubWorkflowScheduled
+	case command.CommandType_ScheduleTimer:
+		return history.EventType_TimerScheduled
+	case command.CommandType_SideEffect:
+		return history.EventType_SideEffectResult
+	case command.CommandType_CompleteWorkflow:
+		return history.EventType_WorkflowExecutionFinished
+	default:
+		return 0
+	}
+}
+
+// describeCommand returns a short description of a command generated by the workflow
+func describeCommand(c *command.Command) string {
+	switch a := c.Attr.(type) {
+	case *command.ScheduleActivityTaskCommandAttr:
+		return fmt.Sprintf("%v %v with %d inputs", c.Type, a.Name, len(a.Inputs))
+	case *command.ScheduleSubWorkflowCommandAttr:
+		return fmt.Sprintf("%v %v with %d inputs", c.Type, a.Name, len(a.Inputs))
+	case *command.ScheduleTimerCommandAttr:
+		return fmt.Sprintf("%v with delay %v", c.Type, a.Delay)
+	default:
+		return c.Type.String()
+	}
+}
+
+// Replay executes a workflow task for the given history without any new events. It returns a
+// *NonDeterminismError if the workflow doesn't match the recorded events.
+func Replay(ctx context.Context, registry *Registry, instance core.WorkflowInstance, events []history.Event, opts ...ExecutorOption) error {
+	e, err := NewExecutor(registry, instance, clock.New(), opts...)
+	if err != nil {
+		return err
+	}
+	defer e.Close()
+
+	_, _, err = e.ExecuteTask(ctx, &task.Workflow{
+		WorkflowInstance: instance,
+		History:          events,
+	})
+
+	return err
+}
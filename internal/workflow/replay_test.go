@@ -0,0 +1,207 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/command"
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/cschleiden/go-workflows/internal/sync"
+	wf "github.com/cschleiden/go-workflows/workflow"
+	"github.com/stretchr/testify/require"
+)
+
+func activityHistory(activityName string) []history.Event {
+	inputs, _ := converter.DefaultConverter.To(42)
+	result, _ := converter.DefaultConverter.To(42)
+
+	return []history.Event{
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowTaskStarted, &history.WorkflowTaskStartedAttributes{}),
+		history.NewHistoryEvent(
+			time.Now(),
+			history.EventType_WorkflowExecutionStarted,
+			&history.ExecutionStartedAttributes{
+				Name:   "workflowWithActivity",
+				Inputs: []payload.Payload{inputs},
+			},
+		),
+		history.NewHistoryEvent(
+			time.Now(),
+			history.EventType_ActivityScheduled,
+			&history.ActivityScheduledAttributes{
+				Name:   activityName,
+				Inputs: []payload.Payload{inputs},
+			},
+			history.ScheduleEventID(1),
+		),
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowTaskFinished, &history.WorkflowTaskFinishedAttributes{}),
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowTaskStarted, &history.WorkflowTaskStartedAttributes{}),
+		history.NewHistoryEvent(
+			time.Now(),
+			history.EventType_ActivityCompleted,
+			&history.ActivityCompletedAttributes{
+				Result: result,
+			},
+			history.ScheduleEventID(1),
+		),
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{}, history.ScheduleEventID(2)),
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowTaskFinished, &history.WorkflowTaskFinishedAttributes{}),
+	}
+}
+
+func Test_Replay(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterWorkflow(workflowWithActivity)
+	r.RegisterActivity(activity1)
+
+	err := Replay(context.Background(), r, core.NewWorkflowInstance("instanceID", "executionID"), activityHistory("activity1"))
+	require.NoError(t, err)
+}
+
+func Test_Replay_UnknownWorkflow(t *testing.T) {
+	err := Replay(context.Background(), NewRegistry(), core.NewWorkflowInstance("instanceID", "executionID"), activityHistory("activity1"))
+
+	var replayErr *ReplayError
+	require.True(t, errors.As(err, &replayErr))
+	require.Equal(t, history.EventType_WorkflowExecutionStarted, replayErr.Event.Type)
+}
+
+func Test_Replay_NonDeterminism(t *testing.T) {
+	inputs, _ := converter.DefaultConverter.To(42)
+
+	started := func() []history.Event {
+		return []history.Event{
+			history.NewHistoryEvent(time.Now(), history.EventType_WorkflowTaskStarted, &history.WorkflowTaskStartedAttributes{}),
+			history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+				Name:   "workflowWithActivity",
+				Inputs: []payload.Payload{inputs},
+			}),
+		}
+	}
+
+	tests := []struct {
+		name     string
+		events   []history.Event
+		index    int
+		field    string
+		expected interface{}
+		actual   interface{}
+	}{
+		{
+			name:     "different activity",
+			events:   activityHistory("activity2"),
+			index:    2,
+			field:    "name",
+			expected: "activity2",
+			actual:   "activity1",
+		},
+		{
+			name: "different number of inputs",
+			events: append(started(), history.NewHistoryEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{
+				Name: "activity1",
+			}, history.ScheduleEventID(1))),
+			index:    2,
+			field:    "inputs",
+			expected: 0,
+			actual:   1,
+		},
+		{
+			name: "timer instead of activity",
+			events: append(started(), history.NewHistoryEvent(time.Now(), history.EventType_TimerScheduled, &history.TimerScheduledAttributes{
+				At: time.Now(),
+			}, history.ScheduleEventID(1))),
+			index:    2,
+			field:    "command",
+			expected: command.CommandType_ScheduleTimer,
+			actual:   command.CommandType_ScheduleActivityTask,
+		},
+		{
+			name: "side effect instead of activity",
+			events: append(started(), history.NewHistoryEvent(time.Now(), history.EventType_SideEffectResult, &history.SideEffectResultAttributes{
+				Result: inputs,
+			}, history.ScheduleEventID(1))),
+			index:    2,
+			field:    "command",
+			expected: command.CommandType_SideEffect,
+			actual:   command.CommandType_ScheduleActivityTask,
+		},
+		{
+			name: "completed too early",
+			events: append(started(), history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{},
+				history.ScheduleEventID(1))),
+			index:    2,
+			field:    "completed",
+			expected: true,
+			actual:   false,
+		},
+		{
+			name:     "command not in history",
+			events:   append(started(), history.NewHistoryEvent(time.Now(), history.EventType_WorkflowTaskFinished, &history.WorkflowTaskFinishedAttributes{})),
+			index:    3,
+			field:    "command",
+			expected: "none",
+			actual:   "ScheduleActivityTask activity1 with 1 inputs",
+		},
+		{
+			name:     "completion not in history",
+			events:   append(activityHistory("activity1")[:6], history.NewHistoryEvent(time.Now(), history.EventType_WorkflowTaskFinished, &history.WorkflowTaskFinishedAttributes{})),
+			index:    7,
+			field:    "completed",
+			expected: false,
+			actual:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			r.RegisterWorkflow(workflowWithActivity)
+			r.RegisterActivity(activity1)
+
+			err := Replay(context.Background(), r, core.NewWorkflowInstance("instanceID", "executionID"), tt.events)
+
+			var nonDeterminismErr *NonDeterminismError
+			require.True(t, errors.As(err, &nonDeterminismErr), "expected non-determinism error, got %v", err)
+			require.Equal(t, "workflowWithActivity", nonDeterminismErr.WorkflowName)
+			require.Equal(t, tt.index, nonDeterminismErr.Index)
+			require.Equal(t, tt.field, nonDeterminismErr.Field)
+			require.Equal(t, tt.expected, nonDeterminismErr.Expected)
+			require.Equal(t, tt.actual, nonDeterminismErr.Actual)
+		})
+	}
+}
+
+func workflowWithDelay(ctx sync.Context) error {
+	return wf.ScheduleTimer(ctx, time.Second).Get(ctx, nil)
+}
+
+func Test_Replay_TimerDelay(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterWorkflow(workflowWithDelay)
+
+	events := []history.Event{
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowTaskStarted, &history.WorkflowTaskStartedAttributes{}),
+		history.NewHistoryEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Name: "workflowWithDelay"}),
+		history.NewHistoryEvent(time.Now(), history.EventType_TimerScheduled, &history.TimerScheduledAttributes{
+			At:    time.Now().Add(time.Minute),
+			Delay: time.Minute,
+		}, history.ScheduleEventID(1)),
+	}
+
+	err := Replay(context.Background(), r, core.NewWorkflowInstance("instanceID", "executionID"), events)
+
+	var nonDeterminismErr *NonDeterminismError
+	require.True(t, errors.As(err, &nonDeterminismErr))
+	require.Equal(t, "delay", nonDeterminismErr.Field)
+	require.Equal(t, time.Minute, nonDeterminismErr.Expected)
+	require.Equal(t, time.Second, nonDeterminismErr.Actual)
+
+	// Timers recorded without a delay are not compared
+	events[2].Attributes.(*history.TimerScheduledAttributes).Delay = 0
+	require.NoError(t, Replay(context.Background(), r, core.NewWorkflowInstance("instanceID", "executionID"), events))
+}
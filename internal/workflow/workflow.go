@@ -5,9 +5,10 @@ import (
 	"reflect"
 
 	"github.com/cschleiden/go-workflows/internal/args"
-	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/payload"
 	"github.com/cschleiden/go-workflows/internal/sync"
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+	wf "github.com/cschleiden/go-workflows/workflow"
 	"github.com/pkg/errors"
 )
 
@@ -29,9 +30,9 @@ func NewWorkflow(workflowFn reflect.Value) *workflow {
 	}
 }
 
-func (w *workflow) Execute(ctx sync.Context, inputs []payload.Payload) error {
+func (w *workflow) Execute(ctx sync.Context, name string, inputs []payload.Payload) error {
 	w.s.NewCoroutine(ctx, func(ctx sync.Context) error {
-		args, addContext, err := args.InputsToArgs(converter.DefaultConverter, w.fn, inputs)
+		args, addContext, err := args.InputsToArgs(sync.Converter(ctx), w.fn, inputs)
 		if err != nil {
 			return errors.Wrap(err, "could not convert workflow inputs")
 		}
@@ -40,45 +41,74 @@ func (w *workflow) Execute(ctx sync.Context, inputs []payload.Payload) error {
 			return errors.New("workflow must accept context as first argument")
 		}
 
-		args[0] = reflect.ValueOf(ctx)
-
-		// Call workflow function
-		r := w.fn.Call(args)
-
-		// Process result
-		if len(r) < 1 || len(r) > 2 {
+		// Validate the signature before invoking any interceptors
+		numOut := w.fn.Type().NumOut()
+		if numOut < 1 || numOut > 2 {
 			return errors.New("workflow has to return either (error) or (result, error)")
 		}
 
-		var result payload.Payload
+		rawArgs := make([]interface{}, len(args)-1)
+		for i, arg := range args[1:] {
+			rawArgs[i] = arg.Interface()
+		}
+
+		r, err := wf.InterceptWorkflow(ctx, name, rawArgs, w.call)
 
-		if len(r) > 1 {
-			var err error
-			result, err = converter.DefaultConverter.To(r[0].Interface())
+		if err != nil {
+			w.err = err
+			return nil
+		}
+
+		if numOut > 1 {
+			result, err := workflowstate.Converter(ctx).To(r)
 			if err != nil {
 				return errors.Wrap(err, "could not convert workflow result")
 			}
-		}
 
-		errResult := r[len(r)-1]
-		if errResult.IsNil() {
 			w.result = result
-			return nil
 		}
 
-		errInterface, ok := errResult.Interface().(error)
-		if !ok {
-			return fmt.Errorf("activity error result does not satisfy error interface (%T): %v", errResult, errResult)
-		}
-
-		w.err = errInterface
-
 		return nil
 	})
 
 	return w.s.Execute(ctx)
 }
 
+// call invokes the workflow function with the given context and arguments
+func (w *workflow) call(ctx sync.Context, rawArgs []interface{}) (interface{}, error) {
+	args := make([]reflect.Value, len(rawArgs)+1)
+	args[0] = reflect.ValueOf(ctx)
+	for i, arg := range rawArgs {
+		if arg == nil {
+			// Interceptors might have replaced arguments with untyped nil values
+			args[i+1] = reflect.Zero(w.fn.Type().In(i + 1))
+			continue
+		}
+
+		args[i+1] = reflect.ValueOf(arg)
+	}
+
+	// Call workflow function
+	r := w.fn.Call(args)
+
+	var result interface{}
+	if len(r) > 1 {
+		result = r[0].Interface()
+	}
+
+	errResult := r[len(r)-1]
+	if errResult.IsNil() {
+		return result, nil
+	}
+
+	errInterface, ok := errResult.Interface().(error)
+	if !ok {
+		return nil, fmt.Errorf("workflow error result does not satisfy error interface (%T): %v", errResult, errResult)
+	}
+
+	return result, errInterface
+}
+
 func (w *workflow) Continue(ctx sync.Context) error {
 	return w.s.Execute(ctx)
 }
@@ -0,0 +1,18 @@
+package workflowstate
+
+import (
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/sync"
+)
+
+// Converter returns the converter for payloads created by the workflow. While replaying, payloads
+// are only compared with the history and then discarded, so they are not offloaded again.
+func Converter(ctx sync.Context) converter.Converter {
+	c := sync.Converter(ctx)
+
+	if wfState, ok := ctx.Value(workflowCtxKey).(*WfState); ok && wfState.Replaying() {
+		return converter.WithoutOffloading(c)
+	}
+
+	return c
+}
@@ -0,0 +1,43 @@
+package workflowstate
+
+import "github.com/cschleiden/go-workflows/internal/log"
+
+// replayLogger only writes messages when the workflow is not replaying, so that messages are
+// written exactly once even though workflow code is executed again for every replay.
+type replayLogger struct {
+	state  *WfState
+	logger log.Logger
+}
+
+var _ log.Logger = (*replayLogger)(nil)
+
+func (r *replayLogger) Debug(msg string, fields ...interface{}) {
+	if !r.state.Replaying() {
+		r.logger.Debug(msg, fields...)
+	}
+}
+
+func (r *replayLogger) Info(msg string, fields ...interface{}) {
+	if !r.state.Replaying() {
+		r.logger.Info(msg, fields...)
+	}
+}
+
+func (r *replayLogger) Warning(msg string, fields ...interface{}) {
+	if !r.state.Replaying() {
+		r.logger.Warning(msg, fields...)
+	}
+}
+
+func (r *replayLogger) Error(msg string, fields ...interface{}) {
+	if !r.state.Replaying() {
+		r.logger.Error(msg, fields...)
+	}
+}
+
+func (r *replayLogger) With(fields ...interface{}) log.Logger {
+	return &replayLogger{
+		state:  r.state,
+		logger: r.logger.With(fields...),
+	}
+}
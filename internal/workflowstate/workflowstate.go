@@ -6,6 +6,7 @@ import (
 	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/internal/command"
 	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/log"
 	"github.com/cschleiden/go-workflows/internal/sync"
 )
 
@@ -20,13 +21,14 @@ type WfState struct {
 	pendingFutures  map[int]sync.Future
 	signalChannels  map[string]sync.Channel
 	replaying       bool
+	logger          log.Logger
 
 	clock clock.Clock
 	time  time.Time
 }
 
-func NewWorkflowState(instance core.WorkflowInstance, clock clock.Clock) *WfState {
-	return &WfState{
+func NewWorkflowState(instance core.WorkflowInstance, logger log.Logger, clock clock.Clock) *WfState {
+	s := &WfState{
 		instance:        instance,
 		commands:        []*command.Command{},
 		scheduleEventID: 1,
@@ -34,6 +36,16 @@ func NewWorkflowState(instance core.WorkflowInstance, clock clock.Clock) *WfStat
 		signalChannels:  make(map[string]sync.Channel),
 		clock:           clock,
 	}
+
+	s.logger = &replayLogger{
+		state: s,
+		logger: logger.With(
+			log.InstanceIDKey, instance.GetInstanceID(),
+			log.ExecutionIDKey, instance.GetExecutionID(),
+		),
+	}
+
+	return s
 }
 
 func WorkflowState(ctx sync.Context) *WfState {
@@ -121,6 +133,12 @@ func (wf *WfState) Replaying() bool {
 	return wf.replaying
 }
 
+// Logger returns a logger for the workflow instance that discards messages while the
+// workflow history is being replayed.
+func (wf *WfState) Logger() log.Logger {
+	return wf.logger
+}
+
 func (wf *WfState) SetTime(t time.Time) {
 	wf.time = t
 }
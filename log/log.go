@@ -0,0 +1,40 @@
+package log
+
+import (
+	"log"
+
+	internal "github.com/cschleiden/go-workflows/internal/log"
+)
+
+// Logger writes structured log messages. fields are alternating key/value pairs.
+type Logger = internal.Logger
+
+// Keys of the fields added to log messages
+const (
+	InstanceIDKey      = internal.InstanceIDKey
+	ExecutionIDKey     = internal.ExecutionIDKey
+	WorkflowNameKey    = internal.WorkflowNameKey
+	EventIDKey         = internal.EventIDKey
+	EventTypeKey       = internal.EventTypeKey
+	ScheduleEventIDKey = internal.ScheduleEventIDKey
+	ActivityNameKey    = internal.ActivityNameKey
+	ActivityIDKey      = internal.ActivityIDKey
+	SignalNameKey      = internal.SignalNameKey
+	ErrorKey           = internal.ErrorKey
+)
+
+// NewDefaultLogger returns a logger writing to the standard library's default logger. Debug
+// messages are discarded.
+func NewDefaultLogger() Logger {
+	return internal.NewDefaultLogger()
+}
+
+// NewDebugLogger returns a logger writing all messages, including debug messages, to l
+func NewDebugLogger(l *log.Logger) Logger {
+	return internal.NewDebugLogger(l)
+}
+
+// NewNoopLogger returns a logger that discards all messages
+func NewNoopLogger() Logger {
+	return internal.NewNoopLogger()
+}
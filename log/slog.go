@@ -0,0 +1,39 @@
+//go:build go1.21
+
+package log
+
+import (
+	"context"
+	"log/slog"
+)
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+var _ Logger = (*slogLogger)(nil)
+
+// NewSlogLogger returns a logger writing to the given log/slog logger
+func NewSlogLogger(l *slog.Logger) Logger {
+	return &slogLogger{l}
+}
+
+func (sl *slogLogger) Debug(msg string, fields ...interface{}) {
+	sl.l.Log(context.Background(), slog.LevelDebug, msg, fields...)
+}
+
+func (sl *slogLogger) Info(msg string, fields ...interface{}) {
+	sl.l.Log(context.Background(), slog.LevelInfo, msg, fields...)
+}
+
+func (sl *slogLogger) Warning(msg string, fields ...interface{}) {
+	sl.l.Log(context.Background(), slog.LevelWarn, msg, fields...)
+}
+
+func (sl *slogLogger) Error(msg string, fields ...interface{}) {
+	sl.l.Log(context.Background(), slog.LevelError, msg, fields...)
+}
+
+func (sl *slogLogger) With(fields ...interface{}) Logger {
+	return &slogLogger{sl.l.With(fields...)}
+}
@@ -0,0 +1,33 @@
+package metrics
+
+// Tags are key/value pairs attached to a recorded metric
+type Tags map[string]string
+
+// Client records metrics for workers and backends. Implementations have to be safe for
+// concurrent use.
+type Client interface {
+	// Counter increments the counter with the given name by value
+	Counter(name string, tags Tags, value int64)
+
+	// Gauge sets the gauge with the given name to value
+	Gauge(name string, tags Tags, value int64)
+
+	// Distribution records a single observation, for example a latency in seconds, for
+	// the given name
+	Distribution(name string, tags Tags, value float64)
+}
+
+type noopClient struct{}
+
+var _ Client = (*noopClient)(nil)
+
+// NewNoopMetricsClient returns a client that discards all metrics
+func NewNoopMetricsClient() Client {
+	return &noopClient{}
+}
+
+func (*noopClient) Counter(name string, tags Tags, value int64) {}
+
+func (*noopClient) Gauge(name string, tags Tags, value int64) {}
+
+func (*noopClient) Distribution(name string, tags Tags, value float64) {}
@@ -0,0 +1,220 @@
+package prometheus
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type Option func(*client)
+
+// WithBuckets sets the buckets used for distributions. Defaults to prometheus.DefBuckets.
+func WithBuckets(buckets []float64) Option {
+	return func(c *client) {
+		c.buckets = buckets
+	}
+}
+
+// WithLogger sets the logger used to report collectors that could not be registered
+func WithLogger(logger log.Logger) Option {
+	return func(c *client) {
+		c.logger = logger
+	}
+}
+
+type client struct {
+	reg     prometheus.Registerer
+	buckets []float64
+	logger  log.Logger
+
+	mu         sync.Mutex
+	counters   map[string]*counter
+	gauges     map[string]*gauge
+	histograms map[string]*histogram
+
+	// dropped holds the names of metrics whose values could not be recorded
+	dropped map[string]bool
+}
+
+type counter struct {
+	labelNames []string
+	vec        *prometheus.CounterVec
+}
+
+type gauge struct {
+	labelNames []string
+	vec        *prometheus.GaugeVec
+}
+
+type histogram struct {
+	labelNames []string
+	vec        *prometheus.HistogramVec
+}
+
+var _ metrics.Client = (*client)(nil)
+
+// NewPrometheusClient returns a metrics client that registers collectors with the given
+// registerer. Collectors are created on first use, their label names are taken from the tags
+// of the first recorded value. Dots in metric names are replaced with underscores.
+func NewPrometheusClient(reg prometheus.Registerer, opts ...Option) metrics.Client {
+	c := &client{
+		reg:        reg,
+		buckets:    prometheus.DefBuckets,
+		logger:     log.NewDefaultLogger(),
+		counters:   map[string]*counter{},
+		gauges:     map[string]*gauge{},
+		histograms: map[string]*histogram{},
+		dropped:    map[string]bool{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func (c *client) Counter(name string, tags metrics.Tags, value int64) {
+	c.mu.Lock()
+	m, ok := c.counters[name]
+	if !ok {
+		labelNames := getLabelNames(tags)
+		vec := prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: metricName(name),
+			Help: name,
+		}, labelNames)
+
+		if existing, ok := c.register(name, vec).(*prometheus.CounterVec); ok {
+			vec = existing
+		}
+
+		m = &counter{labelNames, vec}
+		c.counters[name] = m
+	}
+	c.mu.Unlock()
+
+	metric, err := m.vec.GetMetricWith(labels(m.labelNames, tags))
+	if err != nil {
+		c.drop(name, err)
+		return
+	}
+
+	metric.Add(float64(value))
+}
+
+func (c *client) Gauge(name string, tags metrics.Tags, value int64) {
+	c.mu.Lock()
+	m, ok := c.gauges[name]
+	if !ok {
+		labelNames := getLabelNames(tags)
+		vec := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: metricName(name),
+			Help: name,
+		}, labelNames)
+
+		if existing, ok := c.register(name, vec).(*prometheus.GaugeVec); ok {
+			vec = existing
+		}
+
+		m = &gauge{labelNames, vec}
+		c.gauges[name] = m
+	}
+	c.mu.Unlock()
+
+	metric, err := m.vec.GetMetricWith(labels(m.labelNames, tags))
+	if err != nil {
+		c.drop(name, err)
+		return
+	}
+
+	metric.Set(float64(value))
+}
+
+func (c *client) Distribution(name string, tags metrics.Tags, value float64) {
+	c.mu.Lock()
+	m, ok := c.histograms[name]
+	if !ok {
+		labelNames := getLabelNames(tags)
+		vec := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    metricName(name),
+			Help:    name,
+			Buckets: c.buckets,
+		}, labelNames)
+
+		if existing, ok := c.register(name, vec).(*prometheus.HistogramVec); ok {
+			vec = existing
+		}
+
+		m = &histogram{labelNames, vec}
+		c.histograms[name] = m
+	}
+	c.mu.Unlock()
+
+	metric, err := m.vec.GetMetricWith(labels(m.labelNames, tags))
+	if err != nil {
+		c.drop(name, err)
+		return
+	}
+
+	metric.Observe(value)
+}
+
+// register registers the collector and returns the collector that was already registered
+// under the same name, e.g., by another client, if any. If the collector can't be registered,
+// for example because a collector with the same name but different labels exists, the error is
+// logged and values recorded for the metric are not exported.
+func (c *client) register(name string, collector prometheus.Collector) prometheus.Collector {
+	if err := c.reg.Register(collector); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector
+		}
+
+		c.logger.Error("could not register metric", "metric", name, "error", err)
+	}
+
+	return collector
+}
+
+// drop reports a value that could not be recorded, e.g., because the metric was registered by
+// another client with different label names. Only the first error of every metric is logged.
+func (c *client) drop(name string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dropped[name] {
+		return
+	}
+
+	c.dropped[name] = true
+	c.logger.Error("could not record metric", "metric", name, "error", err)
+}
+
+// labels maps the given tags to the label names of a collector. Tags without a matching label
+// are dropped, missing tags are recorded as empty values.
+func labels(labelNames []string, tags metrics.Tags) prometheus.Labels {
+	l := make(prometheus.Labels, len(labelNames))
+	for _, name := range labelNames {
+		l[name] = tags[name]
+	}
+
+	return l
+}
+
+func getLabelNames(tags metrics.Tags) []string {
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func metricName(name string) string {
+	return strings.ReplaceAll(name, ".", "_")
+}
@@ -0,0 +1,114 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PrometheusClient(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	c := NewPrometheusClient(reg)
+
+	c.Counter("workflows.tasks_total", metrics.Tags{"type": "workflow"}, 1)
+	c.Counter("workflows.tasks_total", metrics.Tags{"type": "workflow"}, 2)
+	c.Counter("workflows.tasks_total", metrics.Tags{"type": "activity", "unknown": "dropped"}, 1)
+	c.Gauge("workflows.cache.size", nil, 3)
+	c.Distribution("workflows.task_duration_seconds", metrics.Tags{"type": "workflow"}, 0.2)
+
+	count, err := testutil.GatherAndCount(reg)
+	require.NoError(t, err)
+	require.Equal(t, 4, count)
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	values := map[string]float64{}
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			switch {
+			case m.GetCounter() != nil:
+				values[mf.GetName()+"/"+m.GetLabel()[0].GetValue()] = m.GetCounter().GetValue()
+			case m.GetGauge() != nil:
+				values[mf.GetName()] = m.GetGauge().GetValue()
+			case m.GetHistogram() != nil:
+				values[mf.GetName()] = float64(m.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+
+	require.Equal(t, map[string]float64{
+		"workflows_tasks_total/workflow":  3,
+		"workflows_tasks_total/activity":  1,
+		"workflows_cache_size":            3,
+		"workflows_task_duration_seconds": 1,
+	}, values)
+}
+
+func Test_PrometheusClient_ReusesRegisteredCollectors(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	NewPrometheusClient(reg).Counter("workflows.tasks_total", nil, 1)
+	NewPrometheusClient(reg).Counter("workflows.tasks_total", nil, 1)
+
+	count, err := testutil.GatherAndCount(reg)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+}
+
+func Test_PrometheusClient_LogsRegistrationErrors(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	// A collector with the same name, but different labels
+	require.NoError(t, reg.Register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "workflows_tasks_total",
+		Help: "other",
+	}, []string{"other"})))
+
+	logger := &recordingLogger{Logger: log.NewDefaultLogger()}
+	NewPrometheusClient(reg, WithLogger(logger)).Counter("workflows.tasks_total", metrics.Tags{"type": "workflow"}, 1)
+
+	require.Equal(t, []string{"could not register metric"}, logger.errors)
+}
+
+// conflictingRegisterer reports an existing collector for every registration
+type conflictingRegisterer struct {
+	prometheus.Registerer
+
+	existing prometheus.Collector
+}
+
+func (r *conflictingRegisterer) Register(c prometheus.Collector) error {
+	return prometheus.AlreadyRegisteredError{ExistingCollector: r.existing, NewCollector: c}
+}
+
+func Test_PrometheusClient_DropsValuesWithMismatchingLabels(t *testing.T) {
+	existing := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "workflows_tasks_total",
+		Help: "other",
+	}, []string{"other"})
+
+	logger := &recordingLogger{Logger: log.NewDefaultLogger()}
+	c := NewPrometheusClient(&conflictingRegisterer{existing: existing}, WithLogger(logger))
+
+	require.NotPanics(t, func() {
+		c.Counter("workflows.tasks_total", metrics.Tags{"type": "workflow"}, 1)
+		c.Counter("workflows.tasks_total", metrics.Tags{"type": "activity"}, 1)
+	})
+
+	require.Equal(t, []string{"could not record metric"}, logger.errors)
+}
+
+type recordingLogger struct {
+	log.Logger
+
+	errors []string
+}
+
+func (l *recordingLogger) Error(msg string, fields ...interface{}) {
+	l.errors = append(l.errors, msg)
+}
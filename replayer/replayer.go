@@ -0,0 +1,198 @@
+// Package replayer replays recorded workflow histories against the current workflow code, to
+// find changes that are not deterministic before they are deployed.
+package replayer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/converter"
+	"github.com/cschleiden/go-workflows/export"
+	internalconverter "github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/log"
+	internal "github.com/cschleiden/go-workflows/internal/workflow"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+// NonDeterminismError describes where a workflow diverged from its recorded history
+type NonDeterminismError = internal.NonDeterminismError
+
+type Replayer interface {
+	RegisterWorkflow(w workflow.Workflow) error
+
+	// Replay replays the given history of a workflow instance
+	Replay(ctx context.Context, instance workflow.Instance, events []history.Event) *Result
+
+	// ReplayHistory replays an exported history
+	ReplayHistory(ctx context.Context, h *export.History) *Result
+
+	// ReplayAll replays all given histories and returns a result for each of them
+	ReplayAll(ctx context.Context, histories []*export.History) []*Result
+}
+
+// Diff describes a difference between a recorded history and the current workflow code
+type Diff struct {
+	// Index is the position of the recorded event in the history. It's equal to the length of
+	// the history if the workflow generated a command that's not part of the history.
+	Index int
+
+	EventType history.EventType
+
+	ScheduleEventID int
+
+	// Field is the part of the event that differs, e.g. "command", "name", "inputs", or "delay"
+	Field string
+
+	// Expected is the recorded value
+	Expected string
+
+	// Actual is the value generated by the current workflow code
+	Actual string
+}
+
+func (d Diff) String() string {
+	return fmt.Sprintf("event %d %v (schedule event id %d): %v differs, recorded %v, got %v", d.Index, d.EventType, d.ScheduleEventID, d.Field, d.Expected, d.Actual)
+}
+
+// Result is the outcome of replaying a single history
+type Result struct {
+	InstanceID  string
+	ExecutionID string
+
+	// Diff is the first difference found between the history and the workflow code. Replaying
+	// stops at the first difference, later events are not compared.
+	Diff *Diff
+
+	// Error is set if the history could not be replayed for other reasons, for example if the
+	// workflow is not registered
+	Error error
+}
+
+// Err returns an error describing the difference, or nil if the history could be replayed
+func (r *Result) Err() error {
+	if r.Error != nil {
+		return fmt.Errorf("replaying %v/%v failed: %w", r.InstanceID, r.ExecutionID, r.Error)
+	}
+
+	if r.Diff == nil {
+		return nil
+	}
+
+	return fmt.Errorf("replaying %v/%v diverged from history: %v", r.InstanceID, r.ExecutionID, r.Diff)
+}
+
+type replayer struct {
+	registry  *internal.Registry
+	converter internalconverter.Converter
+}
+
+type Option func(*replayer)
+
+// WithConverter sets the converter used to decode workflow inputs and encode commands. It has
+// to match the converter used by the workers that recorded the histories.
+func WithConverter(c converter.Converter) Option {
+	return func(r *replayer) {
+		r.converter = internalconverter.FromDataConverter(c)
+	}
+}
+
+func New(opts ...Option) Replayer {
+	r := &replayer{
+		registry:  internal.NewRegistry(),
+		converter: internalconverter.DefaultConverter,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+func (r *replayer) RegisterWorkflow(w workflow.Workflow) error {
+	return r.registry.RegisterWorkflow(w)
+}
+
+func (r *replayer) Replay(ctx context.Context, instance workflow.Instance, events []history.Event) *Result {
+	result := &Result{
+		InstanceID:  instance.GetInstanceID(),
+		ExecutionID: instance.GetExecutionID(),
+	}
+
+	err := internal.Replay(ctx, r.registry, instance, events,
+		internal.WithLogger(log.NewNoopLogger()), internal.WithConverter(r.converter))
+	if err != nil {
+		var nonDeterminismErr *NonDeterminismError
+		if errors.As(err, &nonDeterminismErr) {
+			result.Diff = &Diff{
+				Index:           nonDeterminismErr.Index,
+				EventType:       nonDeterminismErr.EventType,
+				ScheduleEventID: nonDeterminismErr.ScheduleEventID,
+				Field:           nonDeterminismErr.Field,
+				Expected:        fmt.Sprint(nonDeterminismErr.Expected),
+				Actual:          fmt.Sprint(nonDeterminismErr.Actual),
+			}
+		} else {
+			result.Error = err
+		}
+	}
+
+	return result
+}
+
+func (r *replayer) ReplayHistory(ctx context.Context, h *export.History) *Result {
+	return r.Replay(ctx, core.NewWorkflowInstance(h.InstanceID, h.ExecutionID), h.Events)
+}
+
+func (r *replayer) ReplayAll(ctx context.Context, histories []*export.History) []*Result {
+	results := make([]*Result, 0, len(histories))
+	for _, h := range histories {
+		results = append(results, r.ReplayHistory(ctx, h))
+	}
+
+	return results
+}
+
+// LoadFiles reads all exported histories matching the given glob patterns
+func LoadFiles(patterns ...string) ([]*export.History, error) {
+	histories := make([]*export.History, 0)
+
+	for _, pattern := range patterns {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, path := range paths {
+			h, err := export.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", path, err)
+			}
+
+			histories = append(histories, h)
+		}
+	}
+
+	return histories, nil
+}
+
+// LoadFromBackend reads the histories of the given workflow instances from b
+func LoadFromBackend(ctx context.Context, b backend.Backend, instances ...workflow.Instance) ([]*export.History, error) {
+	histories := make([]*export.History, 0, len(instances))
+
+	for _, instance := range instances {
+		h, err := export.Export(ctx, b, instance.GetInstanceID(), instance.GetExecutionID())
+		if err != nil {
+			return nil, err
+		}
+
+		histories = append(histories, h)
+	}
+
+	return histories, nil
+}
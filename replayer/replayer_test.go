@@ -0,0 +1,187 @@
+package replayer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/memory"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/export"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Replayer_ReplayHistory(t *testing.T) {
+	h := recordHistory(t)
+
+	r := New()
+	require.NoError(t, r.RegisterWorkflow(workflowWithActivity))
+
+	result := r.ReplayHistory(context.Background(), h)
+	require.NoError(t, result.Err())
+	require.Nil(t, result.Diff)
+}
+
+func Test_Replayer_ReportsDiffs(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(a *history.ActivityScheduledAttributes)
+		field    string
+		expected string
+		actual   string
+	}{
+		{
+			name:     "activity name",
+			change:   func(a *history.ActivityScheduledAttributes) { a.Name = "triple" },
+			field:    "name",
+			expected: "triple",
+			actual:   "double",
+		},
+		{
+			name:     "argument count",
+			change:   func(a *history.ActivityScheduledAttributes) { a.Inputs = append(a.Inputs, a.Inputs[0]) },
+			field:    "inputs",
+			expected: "2",
+			actual:   "1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := recordHistory(t)
+
+			// Simulate a change to the workflow by changing the recorded activity
+			index := -1
+			for i, e := range h.Events {
+				if e.Type == history.EventType_ActivityScheduled {
+					tt.change(e.Attributes.(*history.ActivityScheduledAttributes))
+					index = i
+				}
+			}
+			require.NotEqual(t, -1, index)
+
+			r := New()
+			require.NoError(t, r.RegisterWorkflow(workflowWithActivity))
+
+			result := r.ReplayHistory(context.Background(), h)
+			require.Error(t, result.Err())
+			require.NoError(t, result.Error)
+			require.Equal(t, &Diff{
+				Index:           index,
+				EventType:       history.EventType_ActivityScheduled,
+				ScheduleEventID: h.Events[index].ScheduleEventID,
+				Field:           tt.field,
+				Expected:        tt.expected,
+				Actual:          tt.actual,
+			}, result.Diff)
+		})
+	}
+}
+
+func Test_Replayer_ReportsUnexpectedCommands(t *testing.T) {
+	h := recordHistory(t)
+
+	// Drop everything after the first workflow task, and the activity scheduled in it
+	events := []history.Event{}
+	for _, e := range h.Events {
+		if e.Type != history.EventType_ActivityScheduled {
+			events = append(events, e)
+		}
+
+		if e.Type == history.EventType_WorkflowTaskFinished {
+			break
+		}
+	}
+	h.Events = events
+
+	r := New()
+	require.NoError(t, r.RegisterWorkflow(workflowWithActivity))
+
+	result := r.ReplayHistory(context.Background(), h)
+	require.NotNil(t, result.Diff)
+	require.Equal(t, len(events), result.Diff.Index)
+	require.Equal(t, history.EventType_ActivityScheduled, result.Diff.EventType)
+	require.Equal(t, "ScheduleActivityTask double with 1 inputs", result.Diff.Actual)
+}
+
+func Test_Replayer_UnregisteredWorkflow(t *testing.T) {
+	h := recordHistory(t)
+
+	result := New().ReplayHistory(context.Background(), h)
+	require.Error(t, result.Error)
+	require.Nil(t, result.Diff)
+}
+
+func Test_LoadFiles(t *testing.T) {
+	h := recordHistory(t)
+
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "history.json"))
+	require.NoError(t, err)
+	require.NoError(t, h.Write(f))
+	require.NoError(t, f.Close())
+
+	histories, err := LoadFiles(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	require.Len(t, histories, 1)
+
+	r := New()
+	require.NoError(t, r.RegisterWorkflow(workflowWithActivity))
+
+	for _, result := range r.ReplayAll(context.Background(), histories) {
+		require.NoError(t, result.Err())
+	}
+}
+
+func recordHistory(t *testing.T) *export.History {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := memory.NewMemoryBackend()
+
+	w := worker.New(b, nil)
+	require.NoError(t, w.RegisterWorkflow(workflowWithActivity))
+	require.NoError(t, w.RegisterActivity(double))
+	require.NoError(t, w.Start(ctx))
+
+	c := client.New(b)
+
+	instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+	}, workflowWithActivity, 21)
+	require.NoError(t, err)
+
+	var h *export.History
+	require.Eventually(t, func() bool {
+		histories, err := LoadFromBackend(ctx, b, instance)
+		require.NoError(t, err)
+		h = histories[0]
+
+		return len(h.Events) > 0 && h.Events[len(h.Events)-1].Type == history.EventType_WorkflowTaskFinished &&
+			h.Events[len(h.Events)-2].Type == history.EventType_WorkflowExecutionFinished
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, w.Stop())
+
+	return h
+}
+
+func workflowWithActivity(ctx workflow.Context, a int) (int, error) {
+	var r int
+	if err := workflow.ExecuteActivity(ctx, workflow.DefaultActivityOptions, double, a).Get(ctx, &r); err != nil {
+		return 0, err
+	}
+
+	return r, nil
+}
+
+func double(ctx context.Context, a int) (int, error) {
+	return a * 2, nil
+}
@@ -1,12 +1,20 @@
 package testing
 
 import (
+	"github.com/cschleiden/go-workflows/converter"
 	internal "github.com/cschleiden/go-workflows/internal/tester"
 	"github.com/cschleiden/go-workflows/workflow"
 )
 
 type WorkflowTester = internal.WorkflowTester
 
-func NewWorkflowTester(wf workflow.Workflow) WorkflowTester {
-	return internal.NewWorkflowTester(wf)
+type WorkflowTesterOption = internal.WorkflowTesterOption
+
+// WithConverter sets the converter used for workflow, activity, and signal payloads
+func WithConverter(c converter.Converter) WorkflowTesterOption {
+	return internal.WithConverter(c)
+}
+
+func NewWorkflowTester(wf workflow.Workflow, opts ...WorkflowTesterOption) WorkflowTester {
+	return internal.NewWorkflowTester(wf, opts...)
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/activity"
 	internal "github.com/cschleiden/go-workflows/internal/worker"
 	workflowinternal "github.com/cschleiden/go-workflows/internal/workflow"
 	"github.com/cschleiden/go-workflows/workflow"
@@ -44,6 +45,7 @@ type worker struct {
 
 	workflowWorker internal.WorkflowWorker
 	activityWorker internal.ActivityWorker
+	scheduleWorker internal.ScheduleWorker
 
 	workflows  map[string]interface{}
 	activities map[string]interface{}
@@ -51,6 +53,11 @@ type worker struct {
 
 type Options = internal.Options
 
+// ActivityInterceptor allows to add cross-cutting behavior to activity executions
+type ActivityInterceptor = activity.Interceptor
+
+type ExecuteActivityFunc = activity.ExecuteActivityFunc
+
 var DefaultWorkerOptions = internal.DefaultOptions
 
 func New(backend backend.Backend, options *Options) Worker {
@@ -68,6 +75,7 @@ func New(backend backend.Backend, options *Options) Worker {
 
 		workflowWorker: internal.NewWorkflowWorker(backend, registry, options),
 		activityWorker: internal.NewActivityWorker(backend, registry, clock.New(), options),
+		scheduleWorker: internal.NewScheduleWorker(backend, options),
 
 		registry: registry,
 
@@ -79,6 +87,7 @@ func New(backend backend.Backend, options *Options) Worker {
 func (w *worker) Start(ctx context.Context) error {
 	w.workflowWorker.Start(ctx)
 	w.activityWorker.Start(ctx)
+	w.scheduleWorker.Start(ctx)
 
 	return nil
 }
@@ -92,6 +101,10 @@ func (w *worker) Stop() error {
 		return err
 	}
 
+	if err := w.scheduleWorker.Stop(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -3,7 +3,6 @@ package workflow
 import (
 	a "github.com/cschleiden/go-workflows/internal/args"
 	"github.com/cschleiden/go-workflows/internal/command"
-	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/cschleiden/go-workflows/internal/workflowstate"
@@ -14,6 +13,9 @@ type Activity interface{}
 
 type ActivityOptions struct {
 	RetryOptions RetryOptions
+
+	// Priority of the activity task. Tasks with a higher priority are picked up first, default is 0.
+	Priority int
 }
 
 var DefaultActivityOptions = ActivityOptions{
@@ -22,6 +24,10 @@ var DefaultActivityOptions = ActivityOptions{
 
 // ExecuteActivity schedules the given activity to be executed
 func ExecuteActivity(ctx sync.Context, options ActivityOptions, activity Activity, args ...interface{}) sync.Future {
+	return interceptActivity(Interceptors(ctx), executeActivityWithRetries)(ctx, options, activity, args)
+}
+
+func executeActivityWithRetries(ctx sync.Context, options ActivityOptions, activity Activity, args []interface{}) sync.Future {
 	return WithRetries(ctx, options.RetryOptions, func(ctx sync.Context) sync.Future {
 		return executeActivity(ctx, options, activity, args...)
 	})
@@ -30,7 +36,7 @@ func ExecuteActivity(ctx sync.Context, options ActivityOptions, activity Activit
 func executeActivity(ctx sync.Context, options ActivityOptions, activity Activity, args ...interface{}) sync.Future {
 	f := sync.NewFuture()
 
-	inputs, err := a.ArgsToInputs(converter.DefaultConverter, args...)
+	inputs, err := a.ArgsToInputs(workflowstate.Converter(ctx), args...)
 	if err != nil {
 		f.Set(nil, errors.Wrap(err, "failed to convert activity input"))
 		return f
@@ -40,7 +46,7 @@ func executeActivity(ctx sync.Context, options ActivityOptions, activity Activit
 	scheduleEventID := wfState.GetNextScheduleEventID()
 
 	name := fn.Name(activity)
-	cmd := command.NewScheduleActivityTaskCommand(scheduleEventID, name, inputs)
+	cmd := command.NewScheduleActivityTaskCommand(scheduleEventID, name, inputs, options.Priority)
 	wfState.AddCommand(&cmd)
 	wfState.TrackFuture(scheduleEventID, f)
 
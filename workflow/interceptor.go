@@ -0,0 +1,126 @@
+package workflow
+
+import (
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/sync"
+)
+
+type ExecuteWorkflowFunc func(ctx Context, args []interface{}) (interface{}, error)
+
+type ExecuteActivityFunc func(ctx Context, options ActivityOptions, activity Activity, args []interface{}) Future
+
+type CreateSubWorkflowInstanceFunc func(ctx Context, options SubWorkflowOptions, workflow Workflow, args []interface{}) Future
+
+type ScheduleTimerFunc func(ctx Context, delay time.Duration) Future
+
+// Interceptor allows to add cross-cutting behavior to workflow executions and the commands
+// they issue. Interceptors are invoked in the order they are registered, each one has to call
+// next to continue the chain.
+//
+// Interceptors are executed as part of the workflow, so they have to follow the same rules
+// as workflow code and be deterministic. Embed NoopInterceptor to only override some methods.
+type Interceptor interface {
+	// ExecuteWorkflow is called when the workflow function is invoked
+	ExecuteWorkflow(ctx Context, name string, args []interface{}, next ExecuteWorkflowFunc) (interface{}, error)
+
+	// ExecuteActivity is called when the workflow schedules an activity
+	ExecuteActivity(ctx Context, options ActivityOptions, activity Activity, args []interface{}, next ExecuteActivityFunc) Future
+
+	// CreateSubWorkflowInstance is called when the workflow creates a sub-workflow instance
+	CreateSubWorkflowInstance(ctx Context, options SubWorkflowOptions, workflow Workflow, args []interface{}, next CreateSubWorkflowInstanceFunc) Future
+
+	// ScheduleTimer is called when the workflow schedules a timer
+	ScheduleTimer(ctx Context, delay time.Duration, next ScheduleTimerFunc) Future
+}
+
+// NoopInterceptor passes all calls on to the next interceptor in the chain
+type NoopInterceptor struct{}
+
+var _ Interceptor = (*NoopInterceptor)(nil)
+
+func (NoopInterceptor) ExecuteWorkflow(ctx Context, name string, args []interface{}, next ExecuteWorkflowFunc) (interface{}, error) {
+	return next(ctx, args)
+}
+
+func (NoopInterceptor) ExecuteActivity(ctx Context, options ActivityOptions, activity Activity, args []interface{}, next ExecuteActivityFunc) Future {
+	return next(ctx, options, activity, args)
+}
+
+func (NoopInterceptor) CreateSubWorkflowInstance(ctx Context, options SubWorkflowOptions, workflow Workflow, args []interface{}, next CreateSubWorkflowInstanceFunc) Future {
+	return next(ctx, options, workflow, args)
+}
+
+func (NoopInterceptor) ScheduleTimer(ctx Context, delay time.Duration, next ScheduleTimerFunc) Future {
+	return next(ctx, delay)
+}
+
+type interceptorsKey struct{}
+
+// WithInterceptors returns a copy of ctx with the given interceptors appended to the ones
+// already registered. The worker uses this to register the interceptors from its options
+// for every workflow execution.
+func WithInterceptors(ctx Context, interceptors ...Interceptor) Context {
+	existing := Interceptors(ctx)
+
+	ic := make([]Interceptor, 0, len(existing)+len(interceptors))
+	ic = append(ic, existing...)
+	ic = append(ic, interceptors...)
+
+	return sync.WithValue(ctx, interceptorsKey{}, ic)
+}
+
+// Interceptors returns the interceptors registered for the given context
+func Interceptors(ctx Context) []Interceptor {
+	if ic, ok := ctx.Value(interceptorsKey{}).([]Interceptor); ok {
+		return ic
+	}
+
+	return nil
+}
+
+// InterceptWorkflow invokes the ExecuteWorkflow interceptors registered for ctx before calling
+// fn. It is used by the workflow executor when starting a workflow.
+func InterceptWorkflow(ctx Context, name string, args []interface{}, fn ExecuteWorkflowFunc) (interface{}, error) {
+	return interceptWorkflow(Interceptors(ctx), name, fn)(ctx, args)
+}
+
+func interceptWorkflow(interceptors []Interceptor, name string, fn ExecuteWorkflowFunc) ExecuteWorkflowFunc {
+	if len(interceptors) == 0 {
+		return fn
+	}
+
+	return func(ctx Context, args []interface{}) (interface{}, error) {
+		return interceptors[0].ExecuteWorkflow(ctx, name, args, interceptWorkflow(interceptors[1:], name, fn))
+	}
+}
+
+func interceptActivity(interceptors []Interceptor, fn ExecuteActivityFunc) ExecuteActivityFunc {
+	if len(interceptors) == 0 {
+		return fn
+	}
+
+	return func(ctx Context, options ActivityOptions, activity Activity, args []interface{}) Future {
+		return interceptors[0].ExecuteActivity(ctx, options, activity, args, interceptActivity(interceptors[1:], fn))
+	}
+}
+
+func interceptSubWorkflow(interceptors []Interceptor, fn CreateSubWorkflowInstanceFunc) CreateSubWorkflowInstanceFunc {
+	if len(interceptors) == 0 {
+		return fn
+	}
+
+	return func(ctx Context, options SubWorkflowOptions, workflow Workflow, args []interface{}) Future {
+		return interceptors[0].CreateSubWorkflowInstance(ctx, options, workflow, args, interceptSubWorkflow(interceptors[1:], fn))
+	}
+}
+
+func interceptTimer(interceptors []Interceptor, fn ScheduleTimerFunc) ScheduleTimerFunc {
+	if len(interceptors) == 0 {
+		return fn
+	}
+
+	return func(ctx Context, delay time.Duration) Future {
+		return interceptors[0].ScheduleTimer(ctx, delay, interceptTimer(interceptors[1:], fn))
+	}
+}
@@ -0,0 +1,13 @@
+package workflow
+
+import (
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+	"github.com/cschleiden/go-workflows/log"
+)
+
+// Logger returns a logger for the current workflow instance. Messages are only written when
+// the workflow is not replaying its history, so every message is written once.
+func Logger(ctx Context) log.Logger {
+	wfState := workflowstate.WorkflowState(ctx)
+	return wfState.Logger()
+}
@@ -2,7 +2,6 @@ package workflow
 
 import (
 	"github.com/cschleiden/go-workflows/internal/command"
-	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/cschleiden/go-workflows/internal/workflowstate"
 )
@@ -26,7 +25,7 @@ func SideEffect(ctx sync.Context, f func(ctx sync.Context) interface{}) sync.Fut
 	r := f(ctx)
 
 	// Create command to add it to the history
-	payload, err := converter.DefaultConverter.To(r)
+	payload, err := sync.Converter(ctx).To(r)
 	if err != nil {
 		future.Set(nil, err)
 	}
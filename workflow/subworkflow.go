@@ -3,7 +3,6 @@ package workflow
 import (
 	a "github.com/cschleiden/go-workflows/internal/args"
 	"github.com/cschleiden/go-workflows/internal/command"
-	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/cschleiden/go-workflows/internal/workflowstate"
@@ -14,6 +13,9 @@ type SubWorkflowOptions struct {
 	InstanceID string
 
 	RetryOptions RetryOptions
+
+	// Priority of the sub-workflow's tasks. Tasks with a higher priority are picked up first, default is 0.
+	Priority int
 }
 
 var DefaultSubWorkflowOptions = SubWorkflowOptions{
@@ -21,6 +23,10 @@ var DefaultSubWorkflowOptions = SubWorkflowOptions{
 }
 
 func CreateSubWorkflowInstance(ctx sync.Context, options SubWorkflowOptions, workflow Workflow, args ...interface{}) sync.Future {
+	return interceptSubWorkflow(Interceptors(ctx), createSubWorkflowInstanceWithRetries)(ctx, options, workflow, args)
+}
+
+func createSubWorkflowInstanceWithRetries(ctx sync.Context, options SubWorkflowOptions, workflow Workflow, args []interface{}) sync.Future {
 	return WithRetries(ctx, options.RetryOptions, func(ctx sync.Context) sync.Future {
 		return createSubWorkflowInstance(ctx, options, workflow, args...)
 	})
@@ -29,7 +35,7 @@ func CreateSubWorkflowInstance(ctx sync.Context, options SubWorkflowOptions, wor
 func createSubWorkflowInstance(ctx sync.Context, options SubWorkflowOptions, workflow Workflow, args ...interface{}) sync.Future {
 	f := sync.NewFuture()
 
-	inputs, err := a.ArgsToInputs(converter.DefaultConverter, args...)
+	inputs, err := a.ArgsToInputs(workflowstate.Converter(ctx), args...)
 	if err != nil {
 		f.Set(nil, errors.Wrap(err, "failed to convert workflow input"))
 		return f
@@ -40,7 +46,7 @@ func createSubWorkflowInstance(ctx sync.Context, options SubWorkflowOptions, wor
 	scheduleEventID := wfState.GetNextScheduleEventID()
 
 	name := fn.Name(workflow)
-	cmd := command.NewScheduleSubWorkflowCommand(scheduleEventID, options.InstanceID, name, inputs)
+	cmd := command.NewScheduleSubWorkflowCommand(scheduleEventID, options.InstanceID, name, inputs, options.Priority)
 	wfState.AddCommand(&cmd)
 	wfState.TrackFuture(scheduleEventID, f)
 
@@ -9,11 +9,15 @@ import (
 )
 
 func ScheduleTimer(ctx sync.Context, delay time.Duration) sync.Future {
+	return interceptTimer(Interceptors(ctx), scheduleTimer)(ctx, delay)
+}
+
+func scheduleTimer(ctx sync.Context, delay time.Duration) sync.Future {
 	wfState := workflowstate.WorkflowState(ctx)
 
 	scheduleEventID := wfState.GetNextScheduleEventID()
 
-	timerCmd := command.NewScheduleTimerCommand(scheduleEventID, Now(ctx).Add(delay))
+	timerCmd := command.NewScheduleTimerCommand(scheduleEventID, Now(ctx).Add(delay), delay)
 	wfState.AddCommand(&timerCmd)
 
 	t := sync.NewFuture()